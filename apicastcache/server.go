@@ -0,0 +1,314 @@
+// Package apicastcache serves the proxy config endpoints of the 3scale Account Management API that APIcast
+// polls, from a cache refreshed periodically, so large APIcast fleets do not hit Porta directly and keep
+// getting their configuration while Porta is down
+package apicastcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/3scale/3scale-porta-go-client/client"
+)
+
+const (
+	defaultRefreshInterval = time.Minute
+	maxPerPage             = client.PROXYCONFIGS_PER_PAGE
+)
+
+var (
+	accountProxyConfigsPath = regexp.MustCompile(`^/admin/api/account/proxy_configs/(sandbox|production)\.json$`)
+	latestProxyConfigPath   = regexp.MustCompile(`^/admin/api/services/(\d+)/proxy/configs/(sandbox|production)/latest\.json$`)
+)
+
+// Source reads the latest proxy configs of the account, i.e. *client.ThreeScaleClient
+type Source interface {
+	ListAccountProxyConfigs(env string, version, host *string) (*client.ProxyConfigList, error)
+}
+
+// Options holds the environments a Server caches and how it refreshes them
+type Options struct {
+	// Environments cached, "sandbox" and "production" when empty
+	Environments []string
+	// RefreshInterval between refreshes of Run, one minute when zero
+	RefreshInterval time.Duration
+	// MaxStale is how long proxy configs are served after the last successful refresh. Zero serves them forever
+	MaxStale time.Duration
+	// AccessToken, when set, must be given by clients as access_token parameter, or basic auth user or password
+	AccessToken string
+	// OnError is invoked with the error of every failed refresh. Run keeps refreshing
+	OnError func(env string, err error)
+}
+
+// Server serves the cached proxy configs:
+//
+//	GET /admin/api/account/proxy_configs/{env}.json, filtered by host and paginated
+//	GET /admin/api/services/{id}/proxy/configs/{env}/latest.json
+//
+// Proxy configs are served as read from the source, fields unknown to client.ProxyConfig included. Responses
+// served while the last refresh failed carry a "Warning: 110" header
+type Server struct {
+	source Source
+	opts   Options
+	now    func() time.Time
+
+	mu        sync.RWMutex
+	snapshots map[string]*snapshot
+}
+
+type snapshot struct {
+	configs     []cachedProxyConfig
+	refreshedAt time.Time
+	err         error
+}
+
+// cachedProxyConfig holds a proxy config and its JSON, as served
+type cachedProxyConfig struct {
+	config client.ProxyConfig
+	raw    json.RawMessage
+}
+
+type proxyConfigElement struct {
+	ProxyConfig json.RawMessage `json:"proxy_config"`
+}
+
+type proxyConfigList struct {
+	ProxyConfigs []proxyConfigElement `json:"proxy_configs"`
+}
+
+// NewServer returns a server caching the proxy configs read from the source. Nothing is served until a refresh
+// succeeds, see Refresh and Run
+func NewServer(source Source, opts Options) *Server {
+	if len(opts.Environments) == 0 {
+		opts.Environments = []string{"sandbox", "production"}
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = defaultRefreshInterval
+	}
+	return &Server{source: source, opts: opts, now: time.Now, snapshots: map[string]*snapshot{}}
+}
+
+// Refresh reads the proxy configs of every environment. The proxy configs of an environment that fails to
+// refresh are kept and served as stale. It returns the first error
+func (s *Server) Refresh() error {
+	var first error
+	for _, env := range s.opts.Environments {
+		if err := s.refresh(env); err != nil {
+			if s.opts.OnError != nil {
+				s.opts.OnError(env, err)
+			}
+			if first == nil {
+				first = fmt.Errorf("%s proxy configs: %w", env, err)
+			}
+		}
+	}
+	return first
+}
+
+func (s *Server) refresh(env string) error {
+	list, err := s.source.ListAccountProxyConfigs(env, nil, nil)
+	var configs []cachedProxyConfig
+	if err == nil {
+		configs, err = cacheProxyConfigs(list)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.snapshots[env]
+	if !ok {
+		current = &snapshot{}
+		s.snapshots[env] = current
+	}
+	if err != nil {
+		current.err = err
+		return err
+	}
+
+	*current = snapshot{configs: configs, refreshedAt: s.now()}
+	return nil
+}
+
+// cacheProxyConfigs pairs the proxy configs with their JSON as read from Porta, encoded when it was not kept
+func cacheProxyConfigs(list *client.ProxyConfigList) ([]cachedProxyConfig, error) {
+	configs := make([]cachedProxyConfig, 0, len(list.ProxyConfigs))
+	for _, element := range list.ProxyConfigs {
+		raw := element.ProxyConfig.Raw
+		if len(raw) == 0 {
+			var err error
+			if raw, err = json.Marshal(element.ProxyConfig); err != nil {
+				return nil, fmt.Errorf("product %d: %w", element.ProxyConfig.Content.ID, err)
+			}
+		}
+		configs = append(configs, cachedProxyConfig{config: element.ProxyConfig, raw: raw})
+	}
+	return configs, nil
+}
+
+// Run refreshes the proxy configs right away and then every opts.RefreshInterval until the context is done
+func (s *Server) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		// errors already went to OnError
+		_ = s.Refresh()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ServeHTTP serves the proxy config endpoints
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, errorBody("method not allowed"))
+		return
+	}
+	if !s.authorized(req) {
+		writeJSON(w, http.StatusForbidden, errorBody("Access denied"))
+		return
+	}
+
+	if match := accountProxyConfigsPath.FindStringSubmatch(req.URL.Path); match != nil {
+		s.serveAccountProxyConfigs(w, req, match[1])
+		return
+	}
+	if match := latestProxyConfigPath.FindStringSubmatch(req.URL.Path); match != nil {
+		id, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, errorBody("Not found"))
+			return
+		}
+		s.serveLatestProxyConfig(w, id, match[2])
+		return
+	}
+	writeJSON(w, http.StatusNotFound, errorBody("Not found"))
+}
+
+func (s *Server) authorized(req *http.Request) bool {
+	if s.opts.AccessToken == "" {
+		return true
+	}
+	if req.URL.Query().Get("access_token") == s.opts.AccessToken {
+		return true
+	}
+	// the token is either the user or the password, as sent by APIcast
+	user, password, ok := req.BasicAuth()
+	return ok && (user == s.opts.AccessToken || password == s.opts.AccessToken)
+}
+
+// configs returns the cached proxy configs of the environment and whether they are stale
+func (s *Server) configs(env string) ([]cachedProxyConfig, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	current, ok := s.snapshots[env]
+	if !ok || current.refreshedAt.IsZero() {
+		return nil, false, errors.New("proxy configs not loaded yet")
+	}
+	if s.opts.MaxStale > 0 && s.now().Sub(current.refreshedAt) > s.opts.MaxStale {
+		return nil, true, errors.New("proxy configs are too old")
+	}
+	return current.configs, current.err != nil, nil
+}
+
+func (s *Server) serveAccountProxyConfigs(w http.ResponseWriter, req *http.Request, env string) {
+	configs, stale, err := s.configs(env)
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorBody(err.Error()))
+		return
+	}
+
+	values := req.URL.Query()
+	if version := values.Get("version"); version != "" && version != "latest" {
+		writeJSON(w, http.StatusUnprocessableEntity, errorBody("only the latest version is cached"))
+		return
+	}
+
+	matching := []proxyConfigElement{}
+	host := values.Get("host")
+	for _, cached := range configs {
+		if host == "" || servesHost(cached.config, host) {
+			matching = append(matching, proxyConfigElement{ProxyConfig: cached.raw})
+		}
+	}
+
+	page, perPage := pagination(values.Get("page"), values.Get("per_page"))
+	from := (page - 1) * perPage
+	if from > len(matching) {
+		from = len(matching)
+	}
+	to := from + perPage
+	if to > len(matching) {
+		to = len(matching)
+	}
+
+	markStale(w, stale)
+	writeJSON(w, http.StatusOK, proxyConfigList{ProxyConfigs: matching[from:to]})
+}
+
+func (s *Server) serveLatestProxyConfig(w http.ResponseWriter, productID int64, env string) {
+	configs, stale, err := s.configs(env)
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorBody(err.Error()))
+		return
+	}
+
+	for _, cached := range configs {
+		// the content of a proxy config is its product
+		if cached.config.Content.ID == productID {
+			markStale(w, stale)
+			writeJSON(w, http.StatusOK, proxyConfigElement{ProxyConfig: cached.raw})
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, errorBody("Not found"))
+}
+
+func servesHost(config client.ProxyConfig, host string) bool {
+	for _, served := range config.Content.Proxy.Hosts {
+		if served == host {
+			return true
+		}
+	}
+	return false
+}
+
+// pagination returns the page and page size requested, following the defaults and limits of Porta
+func pagination(pageParam, perPageParam string) (int, int) {
+	page, err := strconv.Atoi(pageParam)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(perPageParam)
+	if err != nil || perPage < 1 || perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+	return page, perPage
+}
+
+func markStale(w http.ResponseWriter, stale bool) {
+	if stale {
+		w.Header().Set("Warning", `110 - "Response is Stale"`)
+	}
+}
+
+func errorBody(message string) map[string]string {
+	return map[string]string{"error": message}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
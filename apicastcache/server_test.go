@@ -0,0 +1,314 @@
+package apicastcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/3scale/3scale-porta-go-client/client"
+)
+
+type fakeSource struct {
+	mu      sync.Mutex
+	configs map[string][]client.ProxyConfig
+	err     error
+}
+
+func (f *fakeSource) ListAccountProxyConfigs(env string, version, host *string) (*client.ProxyConfigList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	list := &client.ProxyConfigList{ProxyConfigs: []client.ProxyConfigElement{}}
+	for _, config := range f.configs[env] {
+		list.ProxyConfigs = append(list.ProxyConfigs, client.ProxyConfigElement{ProxyConfig: config})
+	}
+	return list, nil
+}
+
+func (f *fakeSource) fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func testProxyConfig(productID int64, env string, version int, hosts ...string) client.ProxyConfig {
+	return client.ProxyConfig{
+		ID:          int(productID)*100 + version,
+		Version:     version,
+		Environment: env,
+		Content: client.Content{
+			ID:         productID,
+			SystemName: "product_" + strconv.FormatInt(productID, 10),
+			Proxy:      client.ContentProxy{ServiceID: productID, Hosts: hosts},
+		},
+	}
+}
+
+func newTestSource() *fakeSource {
+	return &fakeSource{configs: map[string][]client.ProxyConfig{
+		"sandbox": {
+			testProxyConfig(1, "sandbox", 3, "pets-staging.example.com"),
+			testProxyConfig(2, "sandbox", 1, "cats-staging.example.com"),
+		},
+		"production": {
+			testProxyConfig(1, "production", 2, "pets.example.com"),
+		},
+	}}
+}
+
+func equals(t *testing.T, exp, act interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(exp, act) {
+		t.Fatalf("\n\texp: %#v\n\n\tgot: %#v", exp, act)
+	}
+}
+
+func newTestClient(t *testing.T, url, token string) *client.ThreeScaleClient {
+	t.Helper()
+	adminPortal, err := client.NewAdminPortalFromStr(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.NewThreeScale(adminPortal, token, nil)
+}
+
+func TestServer(t *testing.T) {
+	source := newTestSource()
+	server := NewServer(source, Options{AccessToken: "secret"})
+	if err := server.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	c := newTestClient(t, httpServer.URL, "secret")
+
+	list, err := c.ListAccountProxyConfigs("sandbox", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 2, len(list.ProxyConfigs))
+
+	host := "cats-staging.example.com"
+	list, err = c.ListAccountProxyConfigs("sandbox", nil, &host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 1, len(list.ProxyConfigs))
+	equals(t, int64(2), list.ProxyConfigs[0].ProxyConfig.Content.ID)
+
+	latest, err := c.GetLatestProxyConfig("1", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 2, latest.ProxyConfig.Version)
+
+	if _, err := c.GetLatestProxyConfig("2", "production"); !client.IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	// unauthorized clients
+	if _, err := newTestClient(t, httpServer.URL, "wrong").ListAccountProxyConfigs("sandbox", nil, nil); err == nil {
+		t.Fatal("expected error for wrong access token")
+	}
+	resp, err := http.Get(httpServer.URL + "/admin/api/account/proxy_configs/sandbox.json?access_token=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	equals(t, http.StatusOK, resp.StatusCode)
+
+	// APIcast sends the token as basic auth user
+	for _, credentials := range [][2]string{{"secret", ""}, {"", "secret"}, {"wrong", ""}} {
+		req, err := http.NewRequest(http.MethodGet, httpServer.URL+"/admin/api/account/proxy_configs/sandbox.json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth(credentials[0], credentials[1])
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		expected := http.StatusOK
+		if credentials[0] == "wrong" {
+			expected = http.StatusForbidden
+		}
+		equals(t, expected, resp.StatusCode)
+	}
+}
+
+func TestServerRawProxyConfigs(t *testing.T) {
+	config := client.ProxyConfig{}
+	if err := json.Unmarshal([]byte(`{"id": 101, "version": 1, "environment": "production", "content": {
+		"id": 1, "system_name": "pets",
+		"proxy": {"service_id": 1, "hosts": ["pets.example.com"], "jwt_claim_with_client_id": "azp",
+			"proxy_rules": [{"id": 7, "owner_id": 1, "owner_type": "Service"}]}}}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	source := &fakeSource{configs: map[string][]client.ProxyConfig{"production": {config}}}
+	server := NewServer(source, Options{Environments: []string{"production"}})
+	if err := server.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		"/admin/api/account/proxy_configs/production.json?host=pets.example.com",
+		"/admin/api/services/1/proxy/configs/production/latest.json",
+	} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		equals(t, http.StatusOK, recorder.Code)
+
+		body := recorder.Body.String()
+		for _, field := range []string{`"jwt_claim_with_client_id":"azp"`, `"owner_id":1`, `"owner_type":"Service"`} {
+			if !strings.Contains(body, field) {
+				t.Fatalf("%s: expected %s in %s", path, field, body)
+			}
+		}
+	}
+}
+
+func TestServerPagination(t *testing.T) {
+	source := &fakeSource{configs: map[string][]client.ProxyConfig{}}
+	for id := int64(1); id <= 5; id++ {
+		source.configs["production"] = append(source.configs["production"], testProxyConfig(id, "production", 1))
+	}
+	server := NewServer(source, Options{Environments: []string{"production"}})
+	if err := server.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		query    string
+		expected []int64
+	}{
+		{"", []int64{1, 2, 3, 4, 5}},
+		{"?page=2&per_page=2", []int64{3, 4}},
+		{"?page=3&per_page=2", []int64{5}},
+		{"?page=4&per_page=2", []int64{}},
+		{"?version=latest&per_page=1000", []int64{1, 2, 3, 4, 5}},
+	} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/api/account/proxy_configs/production.json"+tc.query, nil))
+		equals(t, http.StatusOK, recorder.Code)
+
+		list := client.ProxyConfigList{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		ids := []int64{}
+		for _, element := range list.ProxyConfigs {
+			ids = append(ids, element.ProxyConfig.Content.ID)
+		}
+		equals(t, tc.expected, ids)
+	}
+
+	for _, tc := range []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodGet, "/admin/api/account/proxy_configs/production.json?version=3", http.StatusUnprocessableEntity},
+		{http.MethodGet, "/admin/api/account/proxy_configs/sandbox.json", http.StatusServiceUnavailable},
+		{http.MethodGet, "/admin/api/services.json", http.StatusNotFound},
+		{http.MethodPost, "/admin/api/account/proxy_configs/production.json", http.StatusMethodNotAllowed},
+	} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, nil))
+		equals(t, tc.code, recorder.Code)
+	}
+}
+
+func TestServerStale(t *testing.T) {
+	source := newTestSource()
+	server := NewServer(source, Options{MaxStale: time.Hour})
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	server.now = func() time.Time { return now }
+
+	serve := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/api/services/1/proxy/configs/sandbox/latest.json", nil))
+		return recorder
+	}
+
+	// nothing loaded yet
+	equals(t, http.StatusServiceUnavailable, serve().Code)
+
+	if err := server.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	recorder := serve()
+	equals(t, http.StatusOK, recorder.Code)
+	equals(t, "", recorder.Header().Get("Warning"))
+
+	// porta is down, the cached configs are served as stale
+	source.fail(errors.New("porta is down"))
+	if err := server.Refresh(); err == nil {
+		t.Fatal("expected refresh error")
+	}
+	now = now.Add(30 * time.Minute)
+	recorder = serve()
+	equals(t, http.StatusOK, recorder.Code)
+	equals(t, `110 - "Response is Stale"`, recorder.Header().Get("Warning"))
+
+	// too old
+	now = now.Add(time.Hour)
+	equals(t, http.StatusServiceUnavailable, serve().Code)
+
+	// back up
+	source.fail(nil)
+	if err := server.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	recorder = serve()
+	equals(t, http.StatusOK, recorder.Code)
+	equals(t, "", recorder.Header().Get("Warning"))
+}
+
+func TestServerRun(t *testing.T) {
+	source := newTestSource()
+	source.fail(errors.New("porta is down"))
+
+	errs := make(chan string, 100)
+	server := NewServer(source, Options{
+		Environments:    []string{"sandbox"},
+		RefreshInterval: time.Millisecond,
+		OnError:         func(env string, err error) { errs <- env },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.Run(ctx)
+		close(done)
+	}()
+
+	equals(t, "sandbox", <-errs)
+	source.fail(nil)
+
+	deadline := time.After(5 * time.Second)
+	for {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/api/account/proxy_configs/sandbox.json", nil))
+		if recorder.Code == http.StatusOK {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("proxy configs never refreshed")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// spec file extensions read by SyncActiveDocs
+var activeDocFileExtensions = map[string]bool{".json": true, ".yaml": true, ".yml": true}
+
+// characters not allowed in system names
+var activeDocSystemNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+type activeDocFile struct {
+	systemName  string
+	name        string
+	description string
+	body        string
+}
+
+// SyncActiveDocs makes the activedocs match the spec files (*.json, *.yaml, *.yml) of the directory.
+// The system name of each activedoc is the file name without extension, characters not allowed replaced
+// by underscores; its name and description come from the info of the document.
+// Bodies are validated and normalized, see NormalizeActiveDocBody, and compared with the normalized
+// remote body so unchanged activedocs are not uploaded again.
+// opts.Delete needs opts.ServiceID, or opts.WholeTenant to delete activedocs of any product
+func (c *ThreeScaleClient) SyncActiveDocs(dir string, opts ActiveDocSyncOptions) (*ActiveDocSyncResult, error) {
+	if opts.Delete && opts.ServiceID == 0 && !opts.WholeTenant {
+		return nil, errors.New("deleting activedocs needs a ServiceID, or WholeTenant to delete them across the tenant")
+	}
+
+	files, err := readActiveDocFiles(dir, opts.Normalize)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.ListActiveDocs()
+	if err != nil {
+		return nil, fmt.Errorf("activedocs: %w", err)
+	}
+	remote := map[string]ActiveDocItem{}
+	for _, doc := range list.ActiveDocs {
+		// activedocs without system name can not match any spec file
+		if systemName := stringValue(doc.Element.SystemName); systemName != "" {
+			remote[systemName] = doc.Element
+		}
+	}
+
+	result := &ActiveDocSyncResult{
+		Created:   []string{},
+		Updated:   []string{},
+		Deleted:   []string{},
+		Unchanged: []string{},
+		IDs:       map[string]int64{},
+	}
+
+	local := map[string]bool{}
+	for _, file := range files {
+		file := file
+		local[file.systemName] = true
+
+		desired := ActiveDocItem{
+			SystemName:  &file.systemName,
+			Name:        &file.name,
+			Description: &file.description,
+			Published:   &opts.Published,
+			Body:        &file.body,
+		}
+		if serviceID := opts.serviceID(file.systemName); serviceID != 0 {
+			desired.ServiceID = &serviceID
+		}
+
+		current, ok := remote[file.systemName]
+		if !ok {
+			created, err := c.CreateActiveDoc(&ActiveDoc{Element: desired})
+			if err != nil {
+				return result, fmt.Errorf("create activedoc %s: %w", file.systemName, err)
+			}
+			result.Created = append(result.Created, file.systemName)
+			result.IDs[file.systemName] = *created.Element.ID
+			continue
+		}
+		result.IDs[file.systemName] = *current.ID
+
+		update, changed := activeDocUpdate(current, desired, opts.Normalize)
+		if !changed {
+			result.Unchanged = append(result.Unchanged, file.systemName)
+			continue
+		}
+		if _, err := c.UpdateActiveDoc(&ActiveDoc{Element: update}); err != nil {
+			return result, fmt.Errorf("update activedoc %s: %w", file.systemName, err)
+		}
+		result.Updated = append(result.Updated, file.systemName)
+	}
+
+	if !opts.Delete {
+		return result, nil
+	}
+
+	for _, systemName := range sortedActiveDocNames(remote) {
+		current := remote[systemName]
+		if local[systemName] || (opts.ServiceID != 0 && (current.ServiceID == nil || *current.ServiceID != opts.ServiceID)) {
+			continue
+		}
+		if err := c.DeleteActiveDoc(*current.ID); err != nil {
+			return result, fmt.Errorf("delete activedoc %s: %w", systemName, err)
+		}
+		result.Deleted = append(result.Deleted, systemName)
+	}
+
+	return result, nil
+}
+
+func (opts ActiveDocSyncOptions) serviceID(systemName string) int64 {
+	if id, ok := opts.ServiceIDs[systemName]; ok {
+		return id
+	}
+	return opts.ServiceID
+}
+
+// activeDocUpdate returns the activedoc holding the fields to update, and whether there is any
+func activeDocUpdate(current, desired ActiveDocItem, opts ActiveDocNormalizeOptions) (ActiveDocItem, bool) {
+	update := ActiveDocItem{ID: current.ID}
+	changed := false
+
+	if stringValue(current.Name) != stringValue(desired.Name) {
+		update.Name, changed = desired.Name, true
+	}
+	if stringValue(current.Description) != stringValue(desired.Description) {
+		update.Description, changed = desired.Description, true
+	}
+	if boolValue(current.Published) != boolValue(desired.Published) {
+		update.Published, changed = desired.Published, true
+	}
+	if desired.ServiceID != nil && (current.ServiceID == nil || *current.ServiceID != *desired.ServiceID) {
+		update.ServiceID, changed = desired.ServiceID, true
+	}
+
+	// remote bodies not uploaded by SyncActiveDocs may not be normalized yet
+	currentBody, err := NormalizeActiveDocBody([]byte(stringValue(current.Body)), opts)
+	if err != nil || currentBody != *desired.Body {
+		update.Body, changed = desired.Body, true
+	}
+
+	return update, changed
+}
+
+func readActiveDocFiles(dir string, opts ActiveDocNormalizeOptions) ([]activeDocFile, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []activeDocFile{}
+	paths := map[string]string{}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !activeDocFileExtensions[ext] {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+
+		systemName := activeDocSystemNameInvalid.ReplaceAllString(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())), "_")
+		if systemName == "" {
+			return nil, fmt.Errorf("%s: empty system name", path)
+		}
+		if previous, ok := paths[systemName]; ok {
+			return nil, fmt.Errorf("%s and %s have the same system name %s", previous, path, systemName)
+		}
+		paths[systemName] = path
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		body, err := NormalizeActiveDocBody(content, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		info := struct {
+			Info struct {
+				Title       string `json:"title"`
+				Description string `json:"description"`
+			} `json:"info"`
+		}{}
+		if err := json.Unmarshal([]byte(body), &info); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		files = append(files, activeDocFile{
+			systemName:  systemName,
+			name:        info.Info.Title,
+			description: info.Info.Description,
+			body:        body,
+		})
+	}
+
+	return files, nil
+}
+
+func sortedActiveDocNames(docs map[string]ActiveDocItem) []string {
+	names := make([]string, 0, len(docs))
+	for name := range docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
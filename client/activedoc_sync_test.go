@@ -0,0 +1,214 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeActiveDocFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// activeDocsPorta lists the given activedocs. Created and updated activedocs echo the request,
+// created ones get IDs from 100 on
+func activeDocsPorta(t *testing.T, docs ...ActiveDocItem) *mockPorta {
+	porta := newMockPorta(t)
+	list := ActiveDocList{ActiveDocs: []ActiveDoc{}}
+	for _, doc := range docs {
+		list.ActiveDocs = append(list.ActiveDocs, ActiveDoc{Element: doc})
+		serveActiveDoc(porta, *doc.ID)
+	}
+	porta.reply(http.MethodGet, "/admin/api/active_docs.json", http.StatusOK, list)
+
+	nextID := int64(100)
+	porta.handle(http.MethodPost, "/admin/api/active_docs.json", func(req *http.Request) *http.Response {
+		doc := activeDocRequest(t, req)
+		id := nextID
+		doc.ID, nextID = &id, nextID+1
+		serveActiveDoc(porta, id)
+		return helperJSONResponse(t, http.StatusCreated, ActiveDoc{Element: doc})
+	})
+	return porta
+}
+
+func serveActiveDoc(porta *mockPorta, id int64) {
+	path := fmt.Sprintf("/admin/api/active_docs/%d.json", id)
+	porta.handle(http.MethodPut, path, func(req *http.Request) *http.Response {
+		doc := activeDocRequest(porta.t, req)
+		doc.ID = &id
+		return helperJSONResponse(porta.t, http.StatusOK, ActiveDoc{Element: doc})
+	})
+	porta.reply(http.MethodDelete, path, http.StatusOK, nil)
+}
+
+func activeDocRequest(t *testing.T, req *http.Request) ActiveDocItem {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return activeDocBody(t, body)
+}
+
+func activeDocBody(t *testing.T, body []byte) ActiveDocItem {
+	doc := ActiveDocItem{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// createdActiveDocs returns the activedocs created by the served requests, as listed afterwards
+func createdActiveDocs(t *testing.T, porta *mockPorta, ids map[string]int64) []ActiveDocItem {
+	docs := []ActiveDocItem{}
+	for _, req := range porta.calls(http.MethodPost, "/admin/api/active_docs.json") {
+		doc := activeDocBody(t, req.Body)
+		id := ids[*doc.SystemName]
+		doc.ID = &id
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestSyncActiveDocs(t *testing.T) {
+	porta := activeDocsPorta(t)
+	c := porta.client()
+
+	dir, err := ioutil.TempDir("", "activedocs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeActiveDocFiles(t, dir, map[string]string{
+		"pets.yaml":       testActiveDocYAML,
+		"pet store.json":  strings.Replace(testActiveDocYAML, "title: Pets", "title: Pet Store", 1),
+		"cats.yml":        strings.Replace(testActiveDocYAML, "title: Pets", "title: Cats", 1),
+		"README.md":       "not a spec",
+		"ignored.json.md": "not a spec",
+	})
+
+	opts := ActiveDocSyncOptions{ServiceID: 10, ServiceIDs: map[string]int64{"cats": 60}, Published: true, Delete: true}
+	result, err := c.SyncActiveDocs(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"cats", "pet_store", "pets"}, result.Created)
+	created := createdActiveDocs(t, porta, result.IDs)
+	equals(t, 3, len(created))
+	docs := map[string]ActiveDocItem{}
+	for _, doc := range created {
+		docs[*doc.SystemName] = doc
+	}
+	equals(t, "Pets", *docs["pets"].Name)
+	equals(t, int64(10), *docs["pets"].ServiceID)
+	equals(t, true, *docs["pets"].Published)
+	equals(t, int64(60), *docs["cats"].ServiceID)
+
+	// nothing uploaded when nothing changed
+	porta = activeDocsPorta(t, created...)
+	c = porta.client()
+	result, err = c.SyncActiveDocs(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"cats", "pet_store", "pets"}, result.Unchanged)
+	equals(t, 1, len(porta.served()))
+
+	// reformatting the spec is not a change, editing it is
+	if err := os.Remove(filepath.Join(dir, "pets.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	writeActiveDocFiles(t, dir, map[string]string{
+		"pets.json": *docs["pets"].Body,
+		"cats.yml":  strings.Replace(testActiveDocYAML, "title: Pets", "title: Cats and dogs", 1),
+	})
+	if err := os.Remove(filepath.Join(dir, "pet store.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = c.SyncActiveDocs(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"cats"}, result.Updated)
+	equals(t, []string{"pets"}, result.Unchanged)
+	equals(t, []string{"pet_store"}, result.Deleted)
+	cats, petStore := result.IDs["cats"], *docs["pet_store"].ID
+	equals(t, []string{
+		fmt.Sprintf("PUT /admin/api/active_docs/%d.json", cats),
+		fmt.Sprintf("DELETE /admin/api/active_docs/%d.json", petStore),
+	}, porta.writes())
+	updated := activeDocBody(t, porta.served(http.MethodPut)[0].Body)
+	equals(t, "Cats and dogs", *updated.Name)
+}
+
+func TestSyncActiveDocsErrors(t *testing.T) {
+	porta := activeDocsPorta(t)
+	c := porta.client()
+
+	dir, err := ioutil.TempDir("", "activedocs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeActiveDocFiles(t, dir, map[string]string{"pets.json": `{"openapi": "3.0.0"}`})
+	if _, err := c.SyncActiveDocs(dir, ActiveDocSyncOptions{}); err == nil || !strings.Contains(err.Error(), "pets.json") {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	writeActiveDocFiles(t, dir, map[string]string{"pets.json": testActiveDocYAML, "pets.yaml": testActiveDocYAML})
+	if _, err := c.SyncActiveDocs(dir, ActiveDocSyncOptions{}); err == nil || !strings.Contains(err.Error(), "same system name") {
+		t.Fatalf("expected duplicated system name error, got %v", err)
+	}
+
+	os.Remove(filepath.Join(dir, "pets.yaml"))
+	writeActiveDocFiles(t, dir, map[string]string{".json": testActiveDocYAML})
+	if _, err := c.SyncActiveDocs(dir, ActiveDocSyncOptions{}); err == nil || !strings.Contains(err.Error(), "empty system name") {
+		t.Fatalf("expected empty system name error, got %v", err)
+	}
+
+	if _, err := c.SyncActiveDocs(dir, ActiveDocSyncOptions{Delete: true}); err == nil || !strings.Contains(err.Error(), "needs a ServiceID") {
+		t.Fatalf("expected delete without product error, got %v", err)
+	}
+	equals(t, []string{}, porta.writes())
+}
+
+func TestSyncActiveDocsWholeTenant(t *testing.T) {
+	dir, err := ioutil.TempDir("", "activedocs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeActiveDocFiles(t, dir, map[string]string{"pets.yaml": testActiveDocYAML, "cats.yaml": testActiveDocYAML})
+	porta := activeDocsPorta(t)
+	result, err := porta.client().SyncActiveDocs(dir, ActiveDocSyncOptions{ServiceIDs: map[string]int64{"pets": 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only pets is bound to a product
+	porta = activeDocsPorta(t, createdActiveDocs(t, porta, result.IDs)...)
+	os.Remove(filepath.Join(dir, "pets.yaml"))
+	os.Remove(filepath.Join(dir, "cats.yaml"))
+	writeActiveDocFiles(t, dir, map[string]string{"dogs.yaml": testActiveDocYAML})
+	result, err = porta.client().SyncActiveDocs(dir, ActiveDocSyncOptions{Delete: true, WholeTenant: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"dogs"}, result.Created)
+	equals(t, []string{"cats", "pets"}, result.Deleted)
+	equals(t, 2, len(porta.served(http.MethodDelete)))
+}
@@ -0,0 +1,621 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var openAPIPathParam = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// ValidateActiveDocBody checks the activedoc body, Swagger 2.0 or OpenAPI 3.x in JSON or YAML.
+// Problems are returned all at once as an *ActiveDocValidationErr
+func ValidateActiveDocBody(body []byte) error {
+	doc, err := parseActiveDocBody(body)
+	if err != nil {
+		return err
+	}
+	return validateActiveDoc(doc)
+}
+
+// NormalizeActiveDocBody converts the activedoc body, Swagger 2.0 or OpenAPI 3.x in JSON or YAML, to JSON
+// after validating it, and applies the rewrites of opts.
+// The output is stable: keys are sorted, so equal documents give equal bodies
+func NormalizeActiveDocBody(body []byte, opts ActiveDocNormalizeOptions) (string, error) {
+	doc, err := parseActiveDocBody(body)
+	if err != nil {
+		return "", err
+	}
+	if err := validateActiveDoc(doc); err != nil {
+		return "", err
+	}
+
+	_, oas3 := doc["openapi"]
+	if opts.ServerURL != "" {
+		if err := setActiveDocServerURL(doc, oas3, opts.ServerURL); err != nil {
+			return "", err
+		}
+	}
+	if opts.UserKey != "" {
+		if err := injectActiveDocUserKey(doc, oas3, opts.UserKey, opts.UserKeyLocation); err != nil {
+			return "", err
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// NormalizeActiveDoc normalizes the body of the activedoc in place, see NormalizeActiveDocBody.
+// The body no longer needs skip_swagger_validations once normalized
+func NormalizeActiveDoc(activeDoc *ActiveDoc, opts ActiveDocNormalizeOptions) error {
+	if activeDoc == nil || activeDoc.Element.Body == nil {
+		return fmt.Errorf("activedoc body is required")
+	}
+
+	body, err := NormalizeActiveDocBody([]byte(*activeDoc.Element.Body), opts)
+	if err != nil {
+		return err
+	}
+	activeDoc.Element.Body = &body
+	return nil
+}
+
+// ProductActiveDocOptions returns the rewrites matching the product proxy: its public endpoint for the
+// environment ("sandbox" or "production") and, for products authenticated with a user key, its parameter
+func (c *ThreeScaleClient) ProductActiveDocOptions(productID int64, env string) (*ActiveDocNormalizeOptions, error) {
+	product, err := c.Product(productID)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := c.ProductProxy(productID)
+	if err != nil {
+		return nil, fmt.Errorf("product %d: proxy: %w", productID, err)
+	}
+
+	opts := &ActiveDocNormalizeOptions{}
+	switch env {
+	case "sandbox":
+		opts.ServerURL = proxy.Element.SandboxEndpoint
+	case "production":
+		opts.ServerURL = proxy.Element.Endpoint
+	default:
+		return nil, fmt.Errorf("unknown environment %q", env)
+	}
+
+	if product.Element.BackendVersion == BackendVersionUserKey && proxy.Element.CredentialsLocation != "authorization" {
+		opts.UserKey = proxy.Element.AuthUserKey
+		if opts.UserKey == "" {
+			opts.UserKey = "user_key"
+		}
+		opts.UserKeyLocation = proxy.Element.CredentialsLocation
+	}
+
+	return opts, nil
+}
+
+// parseActiveDocBody decodes JSON or YAML documents into JSON compatible values
+func parseActiveDocBody(body []byte) (map[string]interface{}, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, &ActiveDocValidationErr{Errors: []ValidationError{{Message: "document is empty"}}}
+	}
+
+	var value interface{}
+	if trimmed[0] == '{' {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return nil, &ActiveDocValidationErr{Errors: []ValidationError{{Message: "invalid JSON: " + err.Error()}}}
+		}
+	} else {
+		if err := yaml.Unmarshal(trimmed, &value); err != nil {
+			return nil, &ActiveDocValidationErr{Errors: []ValidationError{{Message: "invalid YAML: " + err.Error()}}}
+		}
+		value = yamlToJSONValue(value)
+		if doc, ok := value.(map[string]interface{}); ok {
+			// unquoted versions, i.e. swagger: 2.0, are decoded as numbers
+			for _, key := range []string{"swagger", "openapi"} {
+				if version, ok := yamlVersionString(doc[key]); ok {
+					doc[key] = version
+				}
+			}
+		}
+	}
+
+	doc, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, &ActiveDocValidationErr{Errors: []ValidationError{{Message: "document must be an object"}}}
+	}
+	return doc, nil
+}
+
+// yamlVersionString formats a version decoded as a number back to the string it was written as,
+// keeping the minor version of whole numbers: 2.0 is "2.0", 3.1 is "3.1"
+func yamlVersionString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v) + ".0", true
+	case float64:
+		if v == math.Trunc(v) {
+			return strconv.FormatFloat(v, 'f', 1, 64), true
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// yamlToJSONValue converts the maps decoded by yaml, which accept any key, to maps with string keys
+func yamlToJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = yamlToJSONValue(item)
+		}
+		return m
+	case []interface{}:
+		for idx := range v {
+			v[idx] = yamlToJSONValue(v[idx])
+		}
+		return v
+	}
+	return value
+}
+
+// jsonPointer builds a JSON pointer from its reference tokens
+func jsonPointer(tokens ...string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString("/")
+		sb.WriteString(strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1))
+	}
+	return sb.String()
+}
+
+// resolveJSONPointer returns the value the pointer references in doc
+func resolveJSONPointer(doc interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return doc, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+
+	value := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+		switch v := value.(type) {
+		case map[string]interface{}:
+			item, ok := v[token]
+			if !ok {
+				return nil, false
+			}
+			value = item
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			value = v[idx]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+type activeDocValidator struct {
+	doc    map[string]interface{}
+	oas3   bool
+	errors []ValidationError
+	// pointer of the first operation with each operationId
+	operationIDs map[string]string
+}
+
+func validateActiveDoc(doc map[string]interface{}) error {
+	v := &activeDocValidator{doc: doc, operationIDs: map[string]string{}}
+
+	swagger, hasSwagger := doc["swagger"]
+	openapi, hasOpenAPI := doc["openapi"]
+	switch {
+	case hasSwagger && hasOpenAPI:
+		v.add("", "swagger and openapi are mutually exclusive")
+	case hasSwagger:
+		if swagger != "2.0" {
+			v.add("/swagger", "must be \"2.0\"")
+		}
+	case hasOpenAPI:
+		if version, ok := openapi.(string); !ok || !strings.HasPrefix(version, "3.") {
+			v.add("/openapi", "must be a 3.x version string")
+		}
+		v.oas3 = true
+	default:
+		v.add("", "swagger or openapi version is required")
+	}
+
+	v.validateInfo()
+	if v.oas3 {
+		v.validateServers()
+	} else {
+		v.validateHost()
+	}
+	v.validatePaths()
+	v.validateSecurity(jsonPointer("security"), doc["security"])
+	v.validateRefs("", doc)
+
+	if len(v.errors) > 0 {
+		return &ActiveDocValidationErr{Errors: v.errors}
+	}
+	return nil
+}
+
+func (v *activeDocValidator) add(pointer, format string, args ...interface{}) {
+	v.errors = append(v.errors, ValidationError{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+// object returns the object at the pointer, reporting it when it is not an object
+func (v *activeDocValidator) object(pointer string, value interface{}) (map[string]interface{}, bool) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		v.add(pointer, "must be an object")
+	}
+	return m, ok
+}
+
+func (v *activeDocValidator) requiredString(pointer string, parent map[string]interface{}, key string) (string, bool) {
+	value, ok := parent[key]
+	if !ok {
+		v.add(pointer, "%s is required", key)
+		return "", false
+	}
+	s, ok := value.(string)
+	if !ok || s == "" {
+		v.add(pointer+jsonPointer(key), "must be a non empty string")
+		return "", false
+	}
+	return s, true
+}
+
+func (v *activeDocValidator) validateInfo() {
+	value, ok := v.doc["info"]
+	if !ok {
+		v.add("", "info is required")
+		return
+	}
+	info, ok := v.object("/info", value)
+	if !ok {
+		return
+	}
+	v.requiredString("/info", info, "title")
+	v.requiredString("/info", info, "version")
+}
+
+func (v *activeDocValidator) validateServers() {
+	value, ok := v.doc["servers"]
+	if !ok {
+		return
+	}
+	servers, ok := value.([]interface{})
+	if !ok {
+		v.add("/servers", "must be an array")
+		return
+	}
+	for idx, item := range servers {
+		pointer := jsonPointer("servers", fmt.Sprint(idx))
+		if server, ok := v.object(pointer, item); ok {
+			v.requiredString(pointer, server, "url")
+		}
+	}
+}
+
+func (v *activeDocValidator) validateHost() {
+	host, ok := v.doc["host"].(string)
+	if !ok {
+		return
+	}
+	if strings.Contains(host, "://") || strings.Contains(host, "/") {
+		v.add("/host", "must hold the host and optional port only, without scheme nor path")
+	}
+}
+
+func (v *activeDocValidator) validatePaths() {
+	value, ok := v.doc["paths"]
+	if !ok {
+		v.add("", "paths is required")
+		return
+	}
+	paths, ok := v.object("/paths", value)
+	if !ok {
+		return
+	}
+
+	for _, path := range sortedStringKeys(paths) {
+		if strings.HasPrefix(path, "x-") {
+			continue
+		}
+		pointer := jsonPointer("paths", path)
+		if !strings.HasPrefix(path, "/") {
+			v.add(pointer, "path must start with /")
+		}
+		item, ok := v.object(pointer, paths[path])
+		if !ok {
+			continue
+		}
+
+		pathParams := v.validateParameters(pointer+jsonPointer("parameters"), item["parameters"])
+		for _, method := range openAPIHTTPMethods {
+			if value, ok := item[method]; ok {
+				v.validateOperation(pointer+jsonPointer(method), path, value, pathParams)
+			}
+		}
+	}
+}
+
+func (v *activeDocValidator) validateOperation(pointer, path string, value interface{}, pathParams map[string]bool) {
+	operation, ok := v.object(pointer, value)
+	if !ok {
+		return
+	}
+
+	if id, ok := operation["operationId"]; ok {
+		if s, ok := id.(string); !ok {
+			v.add(pointer+jsonPointer("operationId"), "must be a string")
+		} else if first, ok := v.operationIDs[s]; ok {
+			v.add(pointer+jsonPointer("operationId"), "%q is already the operationId of #%s", s, first)
+		} else {
+			v.operationIDs[s] = pointer
+		}
+	}
+
+	if responses, ok := operation["responses"]; !ok {
+		v.add(pointer, "responses is required")
+	} else if m, ok := v.object(pointer+jsonPointer("responses"), responses); ok && len(m) == 0 {
+		v.add(pointer+jsonPointer("responses"), "must declare at least one response")
+	}
+
+	declared := v.validateParameters(pointer+jsonPointer("parameters"), operation["parameters"])
+	for name := range pathParams {
+		declared[name] = true
+	}
+	for _, match := range openAPIPathParam.FindAllStringSubmatch(path, -1) {
+		if !declared[match[1]] {
+			v.add(pointer, "path parameter %s is not declared", match[1])
+		}
+	}
+
+	v.validateSecurity(pointer+jsonPointer("security"), operation["security"])
+}
+
+// validateParameters checks a list of parameters and returns the names of the path parameters
+func (v *activeDocValidator) validateParameters(pointer string, value interface{}) map[string]bool {
+	pathParams := map[string]bool{}
+	if value == nil {
+		return pathParams
+	}
+	params, ok := value.([]interface{})
+	if !ok {
+		v.add(pointer, "must be an array")
+		return pathParams
+	}
+
+	locations := map[string]bool{"query": true, "header": true, "path": true, "formData": true, "body": true}
+	if v.oas3 {
+		locations = map[string]bool{"query": true, "header": true, "path": true, "cookie": true}
+	}
+
+	for idx, item := range params {
+		paramPointer := pointer + jsonPointer(fmt.Sprint(idx))
+		param, ok := v.object(paramPointer, item)
+		if !ok {
+			continue
+		}
+		if ref, ok := param["$ref"].(string); ok {
+			// broken references are reported by validateRefs, references to other documents are not followed
+			if !strings.HasPrefix(ref, "#") {
+				continue
+			}
+			resolved, _ := resolveJSONPointer(v.doc, ref[1:])
+			if param, ok = resolved.(map[string]interface{}); !ok {
+				continue
+			}
+		}
+
+		name, _ := v.requiredString(paramPointer, param, "name")
+		in, ok := v.requiredString(paramPointer, param, "in")
+		if !ok {
+			continue
+		}
+		if !locations[in] {
+			v.add(paramPointer+jsonPointer("in"), "unsupported location %q", in)
+			continue
+		}
+
+		if in == "path" {
+			if required, _ := param["required"].(bool); !required {
+				v.add(paramPointer, "path parameters must be required")
+			}
+			pathParams[name] = true
+		}
+
+		_, hasSchema := param["schema"]
+		_, hasContent := param["content"]
+		_, hasType := param["type"]
+		switch {
+		case v.oas3 && !hasSchema && !hasContent:
+			v.add(paramPointer, "schema or content is required")
+		case !v.oas3 && in == "body" && !hasSchema:
+			v.add(paramPointer, "schema is required")
+		case !v.oas3 && in != "body" && !hasType:
+			v.add(paramPointer, "type is required")
+		}
+	}
+	return pathParams
+}
+
+func (v *activeDocValidator) validateSecurity(pointer string, value interface{}) {
+	if value == nil {
+		return
+	}
+	requirements, ok := value.([]interface{})
+	if !ok {
+		v.add(pointer, "must be an array")
+		return
+	}
+
+	schemesPointer := "/securityDefinitions"
+	if v.oas3 {
+		schemesPointer = "/components/securitySchemes"
+	}
+	schemes, _ := resolveJSONPointer(v.doc, schemesPointer)
+	defined, _ := schemes.(map[string]interface{})
+
+	for idx, item := range requirements {
+		requirement, ok := v.object(pointer+jsonPointer(fmt.Sprint(idx)), item)
+		if !ok {
+			continue
+		}
+		for _, name := range sortedStringKeys(requirement) {
+			if _, ok := defined[name]; !ok {
+				v.add(pointer+jsonPointer(fmt.Sprint(idx), name), "security scheme is not defined in #%s", schemesPointer)
+			}
+		}
+	}
+}
+
+// validateRefs checks that local references resolve. References to other documents are not followed
+func (v *activeDocValidator) validateRefs(pointer string, value interface{}) {
+	switch val := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedStringKeys(val) {
+			if key == "$ref" {
+				ref, ok := val[key].(string)
+				if !ok {
+					v.add(pointer+jsonPointer(key), "must be a string")
+				} else if strings.HasPrefix(ref, "#") {
+					if _, found := resolveJSONPointer(v.doc, ref[1:]); !found {
+						v.add(pointer+jsonPointer(key), "reference %s not found", ref)
+					}
+				}
+				continue
+			}
+			v.validateRefs(pointer+jsonPointer(key), val[key])
+		}
+	case []interface{}:
+		for idx, item := range val {
+			v.validateRefs(pointer+jsonPointer(fmt.Sprint(idx)), item)
+		}
+	}
+}
+
+// setActiveDocServerURL points the document to the given URL. OpenAPI 3 documents keep the path
+// of their first server, as it is the base path of the operations
+func setActiveDocServerURL(doc map[string]interface{}, oas3 bool, serverURL string) error {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid server URL %q", serverURL)
+	}
+
+	if !oas3 {
+		doc["host"] = u.Host
+		doc["schemes"] = []interface{}{u.Scheme}
+		return nil
+	}
+
+	basePath := ""
+	if servers, ok := doc["servers"].([]interface{}); ok && len(servers) > 0 {
+		if server, ok := servers[0].(map[string]interface{}); ok {
+			raw, _ := json.Marshal(server)
+			current := openAPIServer{}
+			if json.Unmarshal(raw, &current) == nil {
+				if cu, err := url.Parse(current.expandURL()); err == nil {
+					basePath = strings.TrimSuffix(cu.Path, "/")
+				}
+			}
+		}
+	}
+	doc["servers"] = []interface{}{
+		map[string]interface{}{"url": strings.TrimSuffix(u.Scheme+"://"+u.Host+u.Path, "/") + basePath},
+	}
+	return nil
+}
+
+// injectActiveDocUserKey adds the user key parameter to every operation declaring no parameter with the
+// same name and location. x-data-threescale-name lets the developer portal fill it with the user keys
+// of the signed in developer
+func injectActiveDocUserKey(doc map[string]interface{}, oas3 bool, name, location string) error {
+	in := "query"
+	switch location {
+	case "", "query":
+	case "headers":
+		in = "header"
+	default:
+		return fmt.Errorf("unsupported user key location %q", location)
+	}
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	for _, path := range sortedStringKeys(paths) {
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		pathParams, _ := item["parameters"].([]interface{})
+
+		for _, method := range openAPIHTTPMethods {
+			operation, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			params, _ := operation["parameters"].([]interface{})
+			if hasActiveDocParam(pathParams, name, in) || hasActiveDocParam(params, name, in) {
+				continue
+			}
+
+			param := map[string]interface{}{
+				"name":                   name,
+				"in":                     in,
+				"description":            "Your API access key",
+				"required":               true,
+				"x-data-threescale-name": "user_keys",
+			}
+			if oas3 {
+				param["schema"] = map[string]interface{}{"type": "string"}
+			} else {
+				param["type"] = "string"
+			}
+			operation["parameters"] = append(params, param)
+		}
+	}
+	return nil
+}
+
+func hasActiveDocParam(params []interface{}, name, in string) bool {
+	for _, item := range params {
+		if param, ok := item.(map[string]interface{}); ok && param["name"] == name && param["in"] == in {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedStringKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
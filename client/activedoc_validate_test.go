@@ -0,0 +1,215 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const testActiveDocYAML = `
+openapi: 3.0.2
+info:
+  title: Pets
+  version: "1.0"
+servers:
+  - url: https://pets.example.com/v1
+paths:
+  /pets/{petId}:
+    parameters:
+      - $ref: '#/components/parameters/petId'
+    get:
+      operationId: showPet
+      description: Shows <b>one</b> pet
+      responses:
+        "200":
+          description: A pet
+components:
+  parameters:
+    petId:
+      name: petId
+      in: path
+      required: true
+      schema:
+        type: integer
+`
+
+func TestNormalizeActiveDocBodyYAML(t *testing.T) {
+	body, err := NormalizeActiveDocBody([]byte(testActiveDocYAML), ActiveDocNormalizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(body, `{"components":`) {
+		t.Fatalf("expected sorted JSON, got %s", body)
+	}
+	if !strings.Contains(body, `"description":"Shows <b>one</b> pet"`) {
+		t.Fatalf("expected unescaped description, got %s", body)
+	}
+
+	// JSON input gives the same output
+	again, err := NormalizeActiveDocBody([]byte(body), ActiveDocNormalizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, body, again)
+}
+
+func TestNormalizeActiveDocBodyRewrites(t *testing.T) {
+	body, err := NormalizeActiveDocBody([]byte(testActiveDocYAML), ActiveDocNormalizeOptions{
+		UserKey:         "X-User-Key",
+		UserKeyLocation: "headers",
+		ServerURL:       "https://pets-staging.example.com:443",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		t.Fatal(err)
+	}
+	server, _ := resolveJSONPointer(doc, "/servers/0/url")
+	equals(t, "https://pets-staging.example.com:443/v1", server)
+	params, _ := resolveJSONPointer(doc, "/paths/~1pets~1{petId}/get/parameters")
+	equals(t, 1, len(params.([]interface{})))
+	param := params.([]interface{})[0].(map[string]interface{})
+	equals(t, "X-User-Key", param["name"])
+	equals(t, "header", param["in"])
+	equals(t, "user_keys", param["x-data-threescale-name"])
+
+	// OpenAPI 2
+	body, err = NormalizeActiveDocBody([]byte(`{"swagger": "2.0", "info": {"title": "Pets", "version": "1"}, "host": "pets.example.com",
+		"paths": {"/pets": {"get": {"responses": {"200": {"description": "ok"}}, "parameters": [{"name": "user_key", "in": "query", "type": "string"}]}}}}`),
+		ActiveDocNormalizeOptions{UserKey: "user_key", ServerURL: "http://localhost:8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, `{"host":"localhost:8080","info":{"title":"Pets","version":"1"},"paths":{"/pets":{"get":{"parameters":[{"in":"query","name":"user_key","type":"string"}],`+
+		`"responses":{"200":{"description":"ok"}}}}},"schemes":["http"],"swagger":"2.0"}`, body)
+}
+
+func TestValidateActiveDocBody(t *testing.T) {
+	err := ValidateActiveDocBody([]byte(`
+swagger: "2.0"
+info:
+  title: Pets
+  version: 1.0
+host: https://pets.example.com
+paths:
+  /pets/{petId}:
+    get:
+      operationId: getPet
+      parameters:
+        - name: id
+          in: path
+          type: string
+    put:
+      operationId: getPet
+      responses: {}
+      security:
+        - oauth: []
+  pets:
+    $ref: '#/definitions/Missing'
+`))
+	var validationErr *ActiveDocValidationErr
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	equals(t, []ValidationError{
+		{"/info/version", "must be a non empty string"},
+		{"/host", "must hold the host and optional port only, without scheme nor path"},
+		{"/paths/~1pets~1{petId}/get", "responses is required"},
+		{"/paths/~1pets~1{petId}/get/parameters/0", "path parameters must be required"},
+		{"/paths/~1pets~1{petId}/get", "path parameter petId is not declared"},
+		{"/paths/~1pets~1{petId}/put/operationId", `"getPet" is already the operationId of #/paths/~1pets~1{petId}/get`},
+		{"/paths/~1pets~1{petId}/put/responses", "must declare at least one response"},
+		{"/paths/~1pets~1{petId}/put", "path parameter petId is not declared"},
+		{"/paths/~1pets~1{petId}/put/security/0/oauth", "security scheme is not defined in #/securityDefinitions"},
+		{"/paths/pets", "path must start with /"},
+		{"/paths/pets/$ref", "reference #/definitions/Missing not found"},
+	}, validationErr.Errors)
+
+	for _, body := range []string{``, `[]`, `{"info": `, "key: [unclosed"} {
+		if err := ValidateActiveDocBody([]byte(body)); err == nil {
+			t.Fatalf("expected error for %q", body)
+		}
+	}
+
+	if err := ValidateActiveDocBody([]byte(testActiveDocYAML)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProductActiveDocOptions(t *testing.T) {
+	porta := petsPorta(t, true)
+	porta.reply(http.MethodGet, "/admin/api/services/10/proxy.json", http.StatusOK, ProxyJSON{Element: ProxyItem{
+		ServiceID:           10,
+		Endpoint:            "https://pets.example.com:443",
+		SandboxEndpoint:     "https://pets-staging.example.com:443",
+		CredentialsLocation: "query",
+	}})
+	c := porta.client()
+
+	// the pets product authenticates with user keys
+	opts, err := c.ProductActiveDocOptions(10, "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, &ActiveDocNormalizeOptions{UserKey: "user_key", UserKeyLocation: "query", ServerURL: "https://pets-staging.example.com:443"}, opts)
+
+	porta.reply(http.MethodGet, "/admin/api/services/10.json", http.StatusOK, Product{Element: ProductItem{
+		ID: 10, Name: "Pets API", SystemName: "pets", BackendVersion: BackendVersionOIDC,
+	}})
+	opts, err = c.ProductActiveDocOptions(10, "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, &ActiveDocNormalizeOptions{ServerURL: "https://pets.example.com:443"}, opts)
+
+	if _, err := c.ProductActiveDocOptions(10, "staging"); err == nil {
+		t.Fatal("expected error for unknown environment")
+	}
+}
+
+func TestNormalizeActiveDoc(t *testing.T) {
+	body := testActiveDocYAML
+	doc := &ActiveDoc{Element: ActiveDocItem{Body: &body}}
+	if err := NormalizeActiveDoc(doc, ActiveDocNormalizeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid([]byte(*doc.Element.Body)) {
+		t.Fatalf("expected JSON body, got %s", *doc.Element.Body)
+	}
+	if err := NormalizeActiveDoc(&ActiveDoc{}, ActiveDocNormalizeOptions{}); err == nil {
+		t.Fatal("expected error for missing body")
+	}
+}
+
+func TestNormalizeActiveDocBodyUnquotedVersion(t *testing.T) {
+	for _, tc := range []struct {
+		name, header, expected string
+	}{
+		{"swagger", "swagger: 2.0\nhost: pets.example.com\n", `"swagger":"2.0"`},
+		{"openapi", "openapi: 3.0\n", `"openapi":"3.0"`},
+		{"openapi minor", "openapi: 3.1\n", `"openapi":"3.1"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			body := tc.header + "info:\n  title: Pets\n  version: \"1.0\"\npaths: {}\n"
+			if err := ValidateActiveDocBody([]byte(body)); err != nil {
+				t.Fatal(err)
+			}
+			normalized, err := NormalizeActiveDocBody([]byte(body), ActiveDocNormalizeOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(normalized, tc.expected) {
+				t.Fatalf("expected %s, got %s", tc.expected, normalized)
+			}
+		})
+	}
+
+	if err := ValidateActiveDocBody([]byte("swagger: 3.0\ninfo:\n  title: Pets\n  version: \"1\"\npaths: {}\n")); err == nil {
+		t.Fatal("expected error for swagger 3.0")
+	}
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// APIcastConfig assembles the latest proxy configs of the account for the environment into the configuration
+// APIcast loads from a file (THREESCALE_CONFIG_FILE), keeping those matching the product and host filters.
+// Services are sorted by ID and written as read from Porta. The OpenID Connect issuers of the services are
+// discovered the way APIcast does when it loads its configuration from Porta
+func (c *ThreeScaleClient) APIcastConfig(opts APIcastConfigOptions) (*APIcastConfig, error) {
+	env := opts.Environment
+	if env == "" {
+		env = "production"
+	}
+
+	var host *string
+	if len(opts.Hosts) == 1 {
+		host = &opts.Hosts[0]
+	}
+	list, err := c.ListAccountProxyConfigs(env, nil, host)
+	if err != nil {
+		return nil, fmt.Errorf("%s proxy configs: %w", env, err)
+	}
+
+	proxyConfigs := []ProxyConfig{}
+	for _, element := range list.ProxyConfigs {
+		if opts.matches(element.ProxyConfig.Content) {
+			proxyConfigs = append(proxyConfigs, element.ProxyConfig)
+		}
+	}
+	sort.Slice(proxyConfigs, func(i, j int) bool { return proxyConfigs[i].Content.ID < proxyConfigs[j].Content.ID })
+
+	config := &APIcastConfig{Services: []json.RawMessage{}}
+	oidc := []APIcastOIDC{}
+	discovered := false
+	for _, proxyConfig := range proxyConfigs {
+		content, err := proxyConfig.rawContent()
+		if err != nil {
+			return nil, fmt.Errorf("product %d: %w", proxyConfig.Content.ID, err)
+		}
+		config.Services = append(config.Services, content)
+
+		issuer, _ := proxyConfig.Content.Proxy.OidcIssuerEndpoint.(string)
+		if issuer == "" {
+			oidc = append(oidc, APIcastOIDC{})
+			continue
+		}
+		discovery, err := c.discoverOIDC(issuer)
+		if err != nil {
+			return nil, fmt.Errorf("product %d: openid connect discovery: %w", proxyConfig.Content.ID, err)
+		}
+		oidc = append(oidc, *discovery)
+		discovered = true
+	}
+	if discovered {
+		config.OIDC = oidc
+	}
+
+	return config, nil
+}
+
+// discoverOIDC reads the openid configuration of the issuer and its JSON web keys. The credentials of the
+// issuer endpoint are not sent
+func (c *ThreeScaleClient) discoverOIDC(issuerEndpoint string) (*APIcastOIDC, error) {
+	issuerURL, err := url.Parse(issuerEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	issuerURL.User = nil
+	issuerURL.Path = strings.TrimSuffix(issuerURL.Path, "/") + "/.well-known/openid-configuration"
+
+	config := map[string]interface{}{}
+	if err := c.getDiscoveryJSON(issuerURL.String(), &config); err != nil {
+		return nil, err
+	}
+	discovery := &APIcastOIDC{Config: config, Keys: map[string]map[string]interface{}{}}
+	discovery.Issuer, _ = config["issuer"].(string)
+
+	jwksURI, _ := config["jwks_uri"].(string)
+	if jwksURI == "" {
+		return discovery, nil
+	}
+	jwks := struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}{}
+	if err := c.getDiscoveryJSON(jwksURI, &jwks); err != nil {
+		return nil, err
+	}
+	for _, key := range jwks.Keys {
+		kid, _ := key["kid"].(string)
+		if kid == "" {
+			continue
+		}
+		if pemKey, err := jwkPEM(key); err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		} else if pemKey != "" {
+			key["pem"] = pemKey
+		}
+		discovery.Keys[kid] = key
+	}
+	return discovery, nil
+}
+
+func (c *ThreeScaleClient) getDiscoveryJSON(rawURL string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// jwkPEM returns the PEM encoded public key of an RSA JSON web key, or an empty string for other keys
+func jwkPEM(key map[string]interface{}) (string, error) {
+	if key["kty"] != "RSA" {
+		return "", nil
+	}
+	n, _ := key["n"].(string)
+	e, _ := key["e"].(string)
+	modulus, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return "", fmt.Errorf("modulus: %w", err)
+	}
+	exponent, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return "", fmt.Errorf("exponent: %w", err)
+	}
+
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: int(new(big.Int).SetBytes(exponent).Int64())}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func (opts APIcastConfigOptions) matches(content Content) bool {
+	if len(opts.ProductIDs) > 0 || len(opts.ProductSystemNames) > 0 {
+		found := false
+		for _, id := range opts.ProductIDs {
+			found = found || id == content.ID
+		}
+		for _, systemName := range opts.ProductSystemNames {
+			found = found || systemName == content.SystemName
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(opts.Hosts) == 0 {
+		return true
+	}
+	for _, host := range opts.Hosts {
+		for _, served := range content.Proxy.Hosts {
+			if host == served {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Write writes the configuration as indented JSON
+func (c *APIcastConfig) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+// WriteFile writes the configuration to the file, ready to be used as THREESCALE_CONFIG_FILE
+func (c *APIcastConfig) WriteFile(path string) error {
+	raw, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(raw, '\n'), 0644)
+}
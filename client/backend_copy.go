@@ -0,0 +1,83 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CopyBackendApi recreates the backend api with the given ID under a new system name, in the dst tenant,
+// which may be the same tenant src points to as long as the system name differs. Metrics, methods under
+// the hits metric and mapping rules, with their positions, are copied as well. When the dst tenant already
+// has a backend api with that system name, it is updated to match the source.
+// The returned result maps every source ID to the ID of its copy
+func CopyBackendApi(src *ThreeScaleClient, backendapiID int64, dst *ThreeScaleClient, systemName string) (*BackendApiCopyResult, error) {
+	if src == nil || dst == nil {
+		return nil, errors.New("CopyBackendApi needs not nil clients")
+	}
+
+	if systemName == "" {
+		return nil, errors.New("backend system_name is required")
+	}
+
+	e := newProductExporter(src)
+	spec, err := e.exportBackend(backendapiID)
+	if err != nil {
+		return nil, fmt.Errorf("export backend api %d: %w", backendapiID, err)
+	}
+	srcSystemName := spec.SystemName
+	if src.sameTenant(dst) && systemName == srcSystemName {
+		return nil, fmt.Errorf("backend %s: copies within the same tenant need a new system name", srcSystemName)
+	}
+	spec.SystemName = systemName
+
+	dstIDs, err := importBackendApi(dst, spec)
+	if dstIDs == nil {
+		return nil, err
+	}
+
+	result := &BackendApiCopyResult{
+		BackendApiID:   dstIDs.BackendIDs[systemName],
+		MetricIDs:      mapIDs(e.state.result.BackendMetricIDs[srcSystemName], dstIDs.BackendMetricIDs[systemName], nil),
+		MappingRuleIDs: map[int64]int64{},
+	}
+	if err != nil {
+		return result, err
+	}
+
+	srcRules, err := backendMetricOwner(src, backendapiID).listMappingRules()
+	if err != nil {
+		return result, fmt.Errorf("mapping rules: %w", err)
+	}
+
+	dstRules, err := backendMetricOwner(dst, result.BackendApiID).listMappingRules()
+	if err != nil {
+		return result, fmt.Errorf("mapping rules: %w", err)
+	}
+
+	dstRuleIDs := map[string]int64{}
+	for _, rule := range dstRules.MappingRules {
+		dstRuleIDs[mappingRuleKey(rule.Element.HTTPMethod, rule.Element.Pattern)] = rule.Element.ID
+	}
+
+	for _, rule := range srcRules.MappingRules {
+		if id, ok := dstRuleIDs[mappingRuleKey(rule.Element.HTTPMethod, rule.Element.Pattern)]; ok {
+			result.MappingRuleIDs[rule.Element.ID] = id
+		}
+	}
+
+	return result, nil
+}
+
+// importBackendApi creates or updates a backend api with its metrics, methods and mapping rules.
+// Returns the IDs known so far, also on apply errors
+func importBackendApi(c *ThreeScaleClient, spec *BackendApiSpec) (*ProductImportResult, error) {
+	p := &productPlanner{
+		c:    c,
+		plan: &ChangePlan{Changes: []Change{}, state: newReconcileState(c)},
+	}
+	if err := p.planBackend(spec); err != nil {
+		return nil, fmt.Errorf("backend %s: %w", spec.SystemName, err)
+	}
+
+	return p.plan.state.result, p.plan.Apply()
+}
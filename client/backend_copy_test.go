@@ -0,0 +1,99 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCopyBackendApi(t *testing.T) {
+	staging := petsPorta(t, true)
+	// reversed rule positions check they are preserved rather than recomputed
+	staging.reply(http.MethodGet, "/admin/api/backend_apis/20/mapping_rules.json", http.StatusOK, MappingRuleJSONList{MappingRules: []MappingRuleJSON{
+		{MappingRuleItem{ID: 24, MetricID: 23, Pattern: "/pets$", HTTPMethod: "GET", Delta: 1, Position: 2}},
+		{MappingRuleItem{ID: 25, MetricID: 22, Pattern: "/pets", HTTPMethod: "POST", Delta: 5, Position: 1}},
+	}})
+	production, dst := copyPorta(t, false)
+
+	result, err := CopyBackendApi(staging.client(), 20, dst, "pets_backend_v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equals(t, &BackendApiCopyResult{
+		BackendApiID:   120,
+		MetricIDs:      map[int64]int64{21: 121, 22: 122, 23: 123},
+		MappingRuleIDs: map[int64]int64{24: 124, 25: 125},
+	}, result)
+	equals(t, []string{}, staging.writes())
+
+	backend := production.calls(http.MethodPost, "/admin/api/backend_apis.json")[0]
+	equals(t, "pets_backend_v2", backend.Params.Get("system_name"))
+	equals(t, "https://pets.internal:443", backend.Params.Get("private_endpoint"))
+
+	// the method goes under the hits metric of the copy
+	equals(t, "list_pets", production.calls(http.MethodPost, "/admin/api/backend_apis/120/metrics/121/methods.json")[0].Params.Get("system_name"))
+
+	rules := map[string]mockRequest{}
+	for _, rule := range production.calls(http.MethodPost, "/admin/api/backend_apis/120/mapping_rules.json") {
+		rules[rule.Params.Get("pattern")] = rule
+	}
+	equals(t, 2, len(rules))
+	equals(t, "2", rules["/pets$"].Params.Get("position"))
+	equals(t, "123", rules["/pets$"].Params.Get("metric_id"))
+	equals(t, "1", rules["/pets"].Params.Get("position"))
+	equals(t, "122", rules["/pets"].Params.Get("metric_id"))
+}
+
+func TestCopyBackendApiSameTenant(t *testing.T) {
+	porta := petsPorta(t, true)
+	porta.load("pets_product_copy_fixture.json")
+	porta.load("pets_product_fixture.json", http.MethodGet)
+	c := porta.client()
+
+	result, err := CopyBackendApi(c, 20, c, "pets_backend_v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equals(t, int64(120), result.BackendApiID)
+	equals(t, "pets_backend_v2", porta.calls(http.MethodPost, "/admin/api/backend_apis.json")[0].Params.Get("system_name"))
+	equals(t, 2, len(porta.calls(http.MethodPost, "/admin/api/backend_apis/120/mapping_rules.json")))
+	for _, write := range porta.writes() {
+		if write == "POST /admin/api/backend_apis/20/mapping_rules.json" {
+			t.Fatalf("unexpected change of the source backend: %s", write)
+		}
+	}
+
+	// copying again converges on the existing copy
+	porta.reset()
+	porta.reply(http.MethodGet, "/admin/api/backend_apis.json", http.StatusOK, BackendApiList{Backends: []BackendApi{
+		{BackendApiItem{ID: 20, Name: "Pets Backend", SystemName: "pets_backend", PrivateEndpoint: "https://pets.internal:443"}},
+		{BackendApiItem{ID: 120, Name: "Pets Backend", SystemName: "pets_backend_v2", PrivateEndpoint: "https://pets.internal:443"}},
+	}})
+	again, err := CopyBackendApi(c, 20, c, "pets_backend_v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, result, again)
+	equals(t, []string{}, porta.writes())
+}
+
+func TestCopyBackendApiSameTenantSameSystemName(t *testing.T) {
+	porta := petsPorta(t, true)
+	c := porta.client()
+
+	_, err := CopyBackendApi(c, 20, c, "pets_backend")
+	if err == nil || err.Error() != "backend pets_backend: copies within the same tenant need a new system name" {
+		t.Fatalf("expected same system name error, got %v", err)
+	}
+	equals(t, []string{}, porta.writes())
+}
+
+func TestCopyBackendApiRequiresSystemName(t *testing.T) {
+	porta := newMockPorta(t)
+	c := porta.client()
+	if _, err := CopyBackendApi(c, 1, c, ""); err == nil {
+		t.Fatal("expected error")
+	}
+	equals(t, []mockRequest{}, porta.served())
+}
@@ -13,6 +13,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -78,6 +79,55 @@ func (p Params) AddParam(key string, value string) {
 	p[key] = value
 }
 
+// jsonParams flattens the scalar fields of a json serializable object into Params,
+// using the same formatting the Params based endpoints expect
+func jsonParams(obj interface{}) Params {
+	params := NewParams()
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return params
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return params
+	}
+
+	for k, v := range fields {
+		switch value := v.(type) {
+		case string:
+			params[k] = value
+		case bool:
+			params[k] = strconv.FormatBool(value)
+		case float64:
+			params[k] = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	}
+	return params
+}
+
+// changedParams returns the desired params whose value differs from the current one.
+// Numbers are compared by value, so "10" and "10.0" are the same
+func changedParams(desired, current Params) Params {
+	params := NewParams()
+	for k, v := range desired {
+		if current[k] != v && !equalNumbers(current[k], v) {
+			params[k] = v
+		}
+	}
+	return params
+}
+
+func equalNumbers(a, b string) bool {
+	x, err := strconv.ParseFloat(a, 64)
+	if err != nil {
+		return false
+	}
+	y, err := strconv.ParseFloat(b, 64)
+	return err == nil && x == y
+}
+
 // SetCredentials allow the user to set the client credentials
 func (c *ThreeScaleClient) SetCredentials(credential string) {
 	c.credential = credential
@@ -0,0 +1,221 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Ensure operations look a resource up by its natural key, create it when missing and otherwise
+// update only the params whose value differs from the current one.
+// Besides the resource they report whether anything was created or updated.
+
+// EnsureProduct ensures the product with the given system name exists with the given params.
+// The product name defaults to the system name on creation
+func (c *ThreeScaleClient) EnsureProduct(systemName string, params Params) (*Product, bool, error) {
+	product, err := c.ProductBySystemName(systemName)
+	if IsNotFound(err) {
+		createParams := ensureCreateParams(params, "system_name", systemName)
+		name := createParams["name"]
+		if name == "" {
+			name = systemName
+		}
+		delete(createParams, "name")
+		product, err = c.CreateProduct(name, createParams)
+		return product, err == nil, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	changed := ensureUpdateParams(params, jsonParams(product.Element), "system_name")
+	if len(changed) == 0 {
+		return product, false, nil
+	}
+	product, err = c.UpdateProduct(product.Element.ID, changed)
+	return product, err == nil, err
+}
+
+// EnsureBackendApi ensures the backend api with the given system name exists with the given params.
+// The backend api name defaults to the system name on creation
+func (c *ThreeScaleClient) EnsureBackendApi(systemName string, params Params) (*BackendApi, bool, error) {
+	backend, err := c.BackendApiBySystemName(systemName)
+	if IsNotFound(err) {
+		createParams := ensureCreateParams(params, "system_name", systemName)
+		if createParams["name"] == "" {
+			createParams["name"] = systemName
+		}
+		backend, err = c.CreateBackendApi(createParams)
+		return backend, err == nil, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	changed := ensureUpdateParams(params, jsonParams(backend.Element), "system_name")
+	if len(changed) == 0 {
+		return backend, false, nil
+	}
+	backend, err = c.UpdateBackendApi(backend.Element.ID, changed)
+	return backend, err == nil, err
+}
+
+// EnsureProductMetric ensures the product metric with the given system name exists with the given params.
+// The metric friendly name defaults to the system name on creation
+func (c *ThreeScaleClient) EnsureProductMetric(productID int64, systemName string, params Params) (*MetricJSON, bool, error) {
+	return productMetricOwner(c, productID).ensureMetric(systemName, params)
+}
+
+// EnsureBackendApiMetric ensures the backend api metric with the given system name exists with the given params.
+// The system name is given without the ".<backendapiID>" suffix
+func (c *ThreeScaleClient) EnsureBackendApiMetric(backendapiID int64, systemName string, params Params) (*MetricJSON, bool, error) {
+	return backendMetricOwner(c, backendapiID).ensureMetric(systemName, params)
+}
+
+func (o metricOwner) ensureMetric(systemName string, params Params) (*MetricJSON, bool, error) {
+	metric, err := o.metricBySystemName(systemName)
+	if IsNotFound(err) {
+		createParams := ensureCreateParams(params, "system_name", systemName)
+		if createParams["friendly_name"] == "" {
+			createParams["friendly_name"] = systemName
+		}
+		metric, err = o.createMetric(createParams)
+		return metric, err == nil, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	changed := ensureUpdateParams(params, jsonParams(metric.Element), "system_name")
+	if len(changed) == 0 {
+		return metric, false, nil
+	}
+	metric, err = o.updateMetric(metric.Element.ID, changed)
+	return metric, err == nil, err
+}
+
+// EnsureProductMappingRule ensures the product mapping rule with the given HTTP method and pattern exists
+// with the given params (metric_id, delta, position, last)
+func (c *ThreeScaleClient) EnsureProductMappingRule(productID int64, httpMethod, pattern string, params Params) (*MappingRuleJSON, bool, error) {
+	return productMetricOwner(c, productID).ensureMappingRule(httpMethod, pattern, params)
+}
+
+// EnsureBackendApiMappingRule ensures the backend api mapping rule with the given HTTP method and pattern
+// exists with the given params (metric_id, delta, position, last)
+func (c *ThreeScaleClient) EnsureBackendApiMappingRule(backendapiID int64, httpMethod, pattern string, params Params) (*MappingRuleJSON, bool, error) {
+	return backendMetricOwner(c, backendapiID).ensureMappingRule(httpMethod, pattern, params)
+}
+
+func (o metricOwner) ensureMappingRule(httpMethod, pattern string, params Params) (*MappingRuleJSON, bool, error) {
+	list, err := o.listMappingRules()
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: mapping rules: %w", o, err)
+	}
+	return o.ensureListedMappingRule(list, httpMethod, pattern, params)
+}
+
+// ensureListedMappingRule looks the mapping rule up in the given list of current rules, which is kept up to date
+// with the created or updated rule, so several rules can be ensured out of a single list request
+func (o metricOwner) ensureListedMappingRule(list *MappingRuleJSONList, httpMethod, pattern string, params Params) (*MappingRuleJSON, bool, error) {
+	for idx := range list.MappingRules {
+		rule := &list.MappingRules[idx]
+		if rule.Element.HTTPMethod != httpMethod || rule.Element.Pattern != pattern {
+			continue
+		}
+
+		changed := ensureUpdateParams(params, jsonParams(rule.Element), "http_method", "pattern")
+		if len(changed) == 0 {
+			return rule, false, nil
+		}
+		updated, err := o.updateMappingRule(rule.Element.ID, changed)
+		if err != nil {
+			return nil, false, err
+		}
+		*rule = *updated
+		return updated, true, nil
+	}
+
+	createParams := ensureCreateParams(params, "http_method", httpMethod)
+	createParams["pattern"] = pattern
+	if createParams["delta"] == "" {
+		createParams["delta"] = "1"
+	}
+	rule, err := o.createMappingRule(createParams)
+	if err != nil {
+		return nil, false, err
+	}
+	list.MappingRules = append(list.MappingRules, *rule)
+	return rule, true, nil
+}
+
+// EnsureApplicationPlan ensures the application plan of the product with the given system name exists with
+// the given params. The plan name defaults to the system name on creation.
+// A state_event param ("publish" or "hide") only counts as a change when the plan is not in that state yet
+func (c *ThreeScaleClient) EnsureApplicationPlan(productID int64, systemName string, params Params) (*ApplicationPlan, bool, error) {
+	plan, err := c.ApplicationPlanBySystemName(productID, systemName)
+	if IsNotFound(err) {
+		createParams := ensureCreateParams(params, "system_name", systemName)
+		if createParams["name"] == "" {
+			createParams["name"] = systemName
+		}
+		plan, err = c.CreateApplicationPlan(productID, createParams)
+		return plan, err == nil, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	current := jsonParams(plan.Element)
+	current["state_event"] = map[string]string{"published": "publish", "hidden": "hide"}[plan.Element.State]
+
+	changed := ensureUpdateParams(params, current, "system_name")
+	if len(changed) == 0 {
+		return plan, false, nil
+	}
+	plan, err = c.UpdateApplicationPlan(productID, plan.Element.ID, changed)
+	return plan, err == nil, err
+}
+
+// EnsureApplicationPlanLimit ensures the application plan has a limit for the given metric and period
+// with the given value
+func (c *ThreeScaleClient) EnsureApplicationPlanLimit(planID, metricID int64, period string, value int) (*ApplicationPlanLimit, bool, error) {
+	list, err := c.ListApplicationPlansLimits(planID)
+	if err != nil {
+		return nil, false, fmt.Errorf("application plan %d: limits: %w", planID, err)
+	}
+
+	params := Params{"period": period, "value": strconv.Itoa(value)}
+	for idx := range list.Limits {
+		limit := &list.Limits[idx]
+		if limit.Element.MetricID != metricID || limit.Element.Period != period {
+			continue
+		}
+
+		if limit.Element.Value == value {
+			return limit, false, nil
+		}
+		limit, err = c.UpdateApplicationPlanLimit(planID, metricID, limit.Element.ID, Params{"value": params["value"]})
+		return limit, err == nil, err
+	}
+
+	limit, err := c.CreateApplicationPlanLimit(planID, metricID, params)
+	return limit, err == nil, err
+}
+
+// ensureCreateParams copies the params and sets the natural key
+func ensureCreateParams(params Params, key, value string) Params {
+	createParams := NewParams()
+	for k, v := range params {
+		createParams[k] = v
+	}
+	createParams[key] = value
+	return createParams
+}
+
+// ensureUpdateParams returns the changed params, leaving out the natural key
+func ensureUpdateParams(params, current Params, keys ...string) Params {
+	changed := changedParams(params, current)
+	for _, key := range keys {
+		delete(changed, key)
+	}
+	return changed
+}
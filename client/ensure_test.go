@@ -0,0 +1,264 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestEnsureProduct(t *testing.T) {
+	porta := petsPorta(t, false)
+	c := porta.client()
+
+	product, changed, err := c.EnsureProduct("pets", Params{"description": "all about pets"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, int64(10), product.Element.ID)
+	created := porta.calls(http.MethodPost, "/admin/api/services.json")[0].Params
+	equals(t, "pets", created.Get("system_name"))
+	equals(t, "pets", created.Get("name"))
+	equals(t, "all about pets", created.Get("description"))
+
+	// the product is listed from now on
+	porta.load("pets_product_fixture.json", http.MethodGet)
+	porta.reset()
+	_, changed, err = c.EnsureProduct("pets", Params{"description": "all about pets"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, false, changed)
+	equals(t, []string{}, porta.writes())
+
+	_, changed, err = c.EnsureProduct("pets", Params{"description": "new description", "name": "Pets API"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, []string{"PUT /admin/api/services/10.json"}, porta.writes())
+	updated := porta.calls(http.MethodPut, "/admin/api/services/10.json")[0].Params
+	equals(t, "new description", updated.Get("description"))
+	equals(t, "", updated.Get("name"))
+}
+
+func TestEnsureBackendApi(t *testing.T) {
+	porta := petsPorta(t, false)
+	porta.reply(http.MethodPut, "/admin/api/backend_apis/20.json", http.StatusOK, BackendApi{
+		Element: BackendApiItem{ID: 20, Name: "Pets Backend", SystemName: "pets_backend", PrivateEndpoint: "https://cats.internal:443"},
+	})
+	c := porta.client()
+
+	params := Params{"name": "Pets Backend", "private_endpoint": "https://pets.internal:443"}
+	_, changed, err := c.EnsureBackendApi("pets_backend", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, "pets_backend", porta.calls(http.MethodPost, "/admin/api/backend_apis.json")[0].Params.Get("system_name"))
+
+	porta.load("pets_product_fixture.json", http.MethodGet)
+	porta.reset()
+	_, changed, err = c.EnsureBackendApi("pets_backend", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, false, changed)
+	equals(t, []string{}, porta.writes())
+
+	params["private_endpoint"] = "https://cats.internal:443"
+	_, changed, err = c.EnsureBackendApi("pets_backend", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, []string{"PUT /admin/api/backend_apis/20.json"}, porta.writes())
+	equals(t, "https://cats.internal:443", porta.served(http.MethodPut)[0].Params.Get("private_endpoint"))
+}
+
+func TestEnsureMetrics(t *testing.T) {
+	porta := petsPorta(t, true)
+	porta.reply(http.MethodPut, "/admin/api/backend_apis/20/metrics/22.json", http.StatusOK, MetricJSON{
+		Element: MetricItem{ID: 22, Name: "Storage", SystemName: "storage.20", Unit: "GB"},
+	})
+	porta.reply(http.MethodPost, "/admin/api/services/10/metrics.json", http.StatusCreated, MetricJSON{
+		Element: MetricItem{ID: 16, Name: "bytes", SystemName: "bytes", Unit: "byte"},
+	})
+	c := porta.client()
+
+	// existing backend metric, known without the suffix
+	metric, changed, err := c.EnsureBackendApiMetric(20, "storage", Params{"unit": "MB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, false, changed)
+	equals(t, int64(22), metric.Element.ID)
+	equals(t, []string{}, porta.writes())
+
+	_, changed, err = c.EnsureBackendApiMetric(20, "storage", Params{"unit": "GB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, []string{"PUT /admin/api/backend_apis/20/metrics/22.json"}, porta.writes())
+	equals(t, "GB", porta.served(http.MethodPut)[0].Params.Get("unit"))
+
+	metric, changed, err = c.EnsureProductMetric(10, "bytes", Params{"unit": "byte"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, "bytes", metric.Element.Name)
+	equals(t, "bytes", porta.calls(http.MethodPost, "/admin/api/services/10/metrics.json")[0].Params.Get("friendly_name"))
+}
+
+func TestEnsureMappingRules(t *testing.T) {
+	porta := petsPorta(t, true)
+	rules := "/admin/api/services/10/proxy/mapping_rules.json"
+	bytesRule := MappingRuleJSON{Element: MappingRuleItem{ID: 14, MetricID: 16, Pattern: "/bytes", HTTPMethod: "GET", Delta: 2, Position: 2}}
+	porta.handle(http.MethodPost, rules, func(req *http.Request) *http.Response {
+		if err := req.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if req.PostForm.Get("metric_id") != "16" {
+			return helperJSONResponse(t, http.StatusUnprocessableEntity, `{"errors":{"metric_id":["can't be blank"]}}`)
+		}
+		return helperJSONResponse(t, http.StatusCreated, bytesRule)
+	})
+	c := porta.client()
+
+	// remote errors are returned
+	rule, _, err := c.EnsureProductMappingRule(10, "GET", "/bytes", Params{"metric_id": "0"})
+	if err == nil {
+		t.Fatalf("expected error for unknown metric; got %v", rule)
+	}
+
+	params := Params{"metric_id": "16", "delta": "2"}
+	rule, changed, err := c.EnsureProductMappingRule(10, "GET", "/bytes", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, 2, rule.Element.Delta)
+	created := porta.calls(http.MethodPost, rules)[1].Params
+	equals(t, "GET", created.Get("http_method"))
+	equals(t, "/bytes", created.Get("pattern"))
+
+	// the rule is listed from now on
+	porta.reply(http.MethodGet, rules, http.StatusOK, MappingRuleJSONList{MappingRules: []MappingRuleJSON{
+		{Element: MappingRuleItem{ID: 13, MetricID: 12, Pattern: "/adopt", HTTPMethod: "POST", Delta: 1, Position: 1, Last: true}},
+		bytesRule,
+	}})
+	porta.reply(http.MethodPut, "/admin/api/services/10/proxy/mapping_rules/14.json", http.StatusOK, bytesRule)
+	porta.reset()
+
+	_, changed, err = c.EnsureProductMappingRule(10, "GET", "/bytes", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, false, changed)
+	equals(t, []string{}, porta.writes())
+
+	params["last"] = "true"
+	_, changed, err = c.EnsureProductMappingRule(10, "GET", "/bytes", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, []string{"PUT /admin/api/services/10/proxy/mapping_rules/14.json"}, porta.writes())
+	equals(t, "true", porta.served(http.MethodPut)[0].Params.Get("last"))
+	equals(t, "", porta.served(http.MethodPut)[0].Params.Get("delta"))
+
+	// same pattern, different method
+	porta.reset()
+	_, changed, err = c.EnsureBackendApiMappingRule(20, "DELETE", "/pets$", Params{"metric_id": "23"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, []string{"POST /admin/api/backend_apis/20/mapping_rules.json"}, porta.writes())
+	created = porta.served(http.MethodPost)[0].Params
+	equals(t, "DELETE", created.Get("http_method"))
+	equals(t, "1", created.Get("delta"))
+}
+
+func TestEnsureApplicationPlan(t *testing.T) {
+	porta := petsPorta(t, true)
+	porta.reply(http.MethodPut, "/admin/api/services/10/application_plans/40.json", http.StatusOK, ApplicationPlan{
+		Element: ApplicationPlanItem{ID: 40, Name: "Basic", SystemName: "basic", State: "hidden"},
+	})
+	porta.reply(http.MethodPost, "/admin/api/services/10/application_plans.json", http.StatusCreated, ApplicationPlan{
+		Element: ApplicationPlanItem{ID: 44, Name: "Premium", SystemName: "premium", State: "hidden"},
+	})
+	c := porta.client()
+
+	// basic is published with a cost of 10
+	plan, changed, err := c.EnsureApplicationPlan(10, "basic", Params{"state_event": "publish", "cost_per_month": "10.00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, false, changed)
+	equals(t, int64(40), plan.Element.ID)
+	equals(t, []string{}, porta.writes())
+
+	_, changed, err = c.EnsureApplicationPlan(10, "basic", Params{"state_event": "hide"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, "hide", porta.calls(http.MethodPut, "/admin/api/services/10/application_plans/40.json")[0].Params.Get("state_event"))
+
+	plan, changed, err = c.EnsureApplicationPlan(10, "premium", Params{"name": "Premium"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, "Premium", plan.Element.Name)
+	equals(t, "premium", porta.calls(http.MethodPost, "/admin/api/services/10/application_plans.json")[0].Params.Get("system_name"))
+}
+
+func TestEnsureApplicationPlanLimit(t *testing.T) {
+	porta := newMockPorta(t)
+	limits := "/admin/api/application_plans/44/limits.json"
+	dayLimit := ApplicationPlanLimit{Element: ApplicationPlanLimitItem{ID: 45, Period: "day", Value: 5, MetricID: 12}}
+	porta.reply(http.MethodGet, limits, http.StatusOK, ApplicationPlanLimitList{Limits: []ApplicationPlanLimit{}})
+	porta.reply(http.MethodPost, "/admin/api/application_plans/44/metrics/12/limits.json", http.StatusCreated, dayLimit)
+	porta.reply(http.MethodPut, "/admin/api/application_plans/44/metrics/12/limits/45.json", http.StatusOK, dayLimit)
+	c := porta.client()
+
+	_, changed, err := c.EnsureApplicationPlanLimit(44, 12, "day", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	created := porta.served(http.MethodPost)[0].Params
+	equals(t, "day", created.Get("period"))
+	equals(t, "5", created.Get("value"))
+
+	// the limit is listed from now on
+	porta.reply(http.MethodGet, limits, http.StatusOK, ApplicationPlanLimitList{Limits: []ApplicationPlanLimit{dayLimit}})
+	porta.reset()
+
+	_, changed, err = c.EnsureApplicationPlanLimit(44, 12, "day", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, false, changed)
+	equals(t, []string{}, porta.writes())
+
+	_, changed, err = c.EnsureApplicationPlanLimit(44, 12, "day", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, []string{"PUT /admin/api/application_plans/44/metrics/12/limits/45.json"}, porta.writes())
+	equals(t, "10", porta.served(http.MethodPut)[0].Params.Get("value"))
+
+	// another period is another limit
+	porta.reset()
+	_, changed, err = c.EnsureApplicationPlanLimit(44, 12, "month", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, changed)
+	equals(t, []string{"POST /admin/api/application_plans/44/metrics/12/limits.json"}, porta.writes())
+}
@@ -1,8 +1,10 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 type ApiErr struct {
@@ -18,11 +20,69 @@ func (e ApiErr) Code() int {
 	return e.code
 }
 
+// SystemNameNotFoundErr is returned when a resource looked up by system name does not exist
+type SystemNameNotFoundErr struct {
+	Kind       string
+	SystemName string
+}
+
+func (e *SystemNameNotFoundErr) Error() string {
+	return fmt.Sprintf("%s with system_name %q not found", e.Kind, e.SystemName)
+}
+
+func (e *SystemNameNotFoundErr) Code() int {
+	return http.StatusNotFound
+}
+
+// ActiveDocValidationErr is returned when an activedoc body is not a valid Swagger 2.0 or OpenAPI 3.x document
+type ActiveDocValidationErr struct {
+	Errors []ValidationError
+}
+
+func (e *ActiveDocValidationErr) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("#%s: %s", err.Pointer, err.Message))
+	}
+	return fmt.Sprintf("invalid activedoc: %s", strings.Join(msgs, "; "))
+}
+
+// PolicyValidationErr is returned when policy configurations do not match the JSON schemas of their policies
+type PolicyValidationErr struct {
+	Errors []ValidationError
+}
+
+func (e *PolicyValidationErr) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("#%s: %s", err.Pointer, err.Message))
+	}
+	return fmt.Sprintf("invalid policy chain: %s", strings.Join(msgs, "; "))
+}
+
+// PolicyManifestValidationErr is returned when an apicast-policy.json manifest is not valid
+type PolicyManifestValidationErr struct {
+	Path   string
+	Errors []ValidationError
+}
+
+func (e *PolicyManifestValidationErr) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("#%s: %s", err.Pointer, err.Message))
+	}
+	if e.Path == "" {
+		return fmt.Sprintf("invalid policy manifest: %s", strings.Join(msgs, "; "))
+	}
+	return fmt.Sprintf("invalid policy manifest %s: %s", e.Path, strings.Join(msgs, "; "))
+}
+
 // codeForError returns the HTTP status for a particular error.
+// Wrapped errors are unwrapped until an error carrying a status is found.
 func codeForError(err error) int {
-	switch t := err.(type) {
-	case ApiErr:
-		return t.Code()
+	var coder interface{ Code() int }
+	if errors.As(err, &coder) {
+		return coder.Code()
 	}
 	// Unknown
 	return -1
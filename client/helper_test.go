@@ -1,11 +1,15 @@
 package client
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -27,3 +31,169 @@ func fetchBasicAuthHeader(req *http.Request) (string, error) {
 
 	return auth[1], nil
 }
+
+// mockPorta serves each request with the handler registered for its "METHOD path",
+// or a not found response, and records the requests served
+type mockPorta struct {
+	t        *testing.T
+	mu       sync.Mutex
+	handlers map[string]RoundTripFunc
+	requests []mockRequest
+}
+
+// mockRequest holds a served request. Params holds the query and form values
+type mockRequest struct {
+	Method string
+	Path   string
+	Params url.Values
+	Body   []byte
+}
+
+func (r mockRequest) String() string {
+	return r.Method + " " + r.Path
+}
+
+func newMockPorta(t *testing.T) *mockPorta {
+	return &mockPorta{t: t, handlers: map[string]RoundTripFunc{}}
+}
+
+func (m *mockPorta) client() *ThreeScaleClient {
+	return NewThreeScale(NewTestAdminPortal(m.t), "someAccessToken", NewTestClient(m.serve))
+}
+
+func (m *mockPorta) handle(method, path string, handler RoundTripFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[method+" "+path] = handler
+}
+
+// reply serves obj encoded as JSON
+func (m *mockPorta) reply(method, path string, code int, obj interface{}) {
+	m.handle(method, path, func(req *http.Request) *http.Response {
+		return helperJSONResponse(m.t, code, obj)
+	})
+}
+
+// load serves the responses of a testdata file holding a JSON object keyed by "METHOD path",
+// only those of the given methods when any is given.
+// POST requests are answered with 201 and the rest with 200
+func (m *mockPorta) load(name string, methods ...string) {
+	responses := map[string]json.RawMessage{}
+	if err := json.Unmarshal(helperLoadBytes(m.t, name), &responses); err != nil {
+		m.t.Fatal(err)
+	}
+	for key, body := range responses {
+		parts := strings.SplitN(key, " ", 2)
+		if !matchMethod(methods, parts[0]) {
+			continue
+		}
+		code := http.StatusOK
+		if parts[0] == http.MethodPost {
+			code = http.StatusCreated
+		}
+		m.reply(parts[0], parts[1], code, body)
+	}
+}
+
+func (m *mockPorta) serve(req *http.Request) *http.Response {
+	served := mockRequest{Method: req.Method, Path: req.URL.Path, Params: req.URL.Query()}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			m.t.Fatal(err)
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		served.Body = body
+		if req.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+			form, err := url.ParseQuery(string(body))
+			if err != nil {
+				m.t.Fatal(err)
+			}
+			for k, v := range form {
+				served.Params[k] = v
+			}
+		}
+	}
+
+	m.mu.Lock()
+	m.requests = append(m.requests, served)
+	handler, ok := m.handlers[served.String()]
+	m.mu.Unlock()
+
+	if !ok {
+		return helperJSONResponse(m.t, http.StatusNotFound, map[string]string{"status": "Not found"})
+	}
+	return handler(req)
+}
+
+// served returns the requests served with any of the given methods, all of them when none is given
+func (m *mockPorta) served(methods ...string) []mockRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := []mockRequest{}
+	for _, req := range m.requests {
+		if matchMethod(methods, req.Method) {
+			list = append(list, req)
+		}
+	}
+	return list
+}
+
+// calls returns the requests served for "METHOD path"
+func (m *mockPorta) calls(method, path string) []mockRequest {
+	list := []mockRequest{}
+	for _, req := range m.served(method) {
+		if req.Path == path {
+			list = append(list, req)
+		}
+	}
+	return list
+}
+
+// writes returns "METHOD path" of the requests changing state
+func (m *mockPorta) writes() []string {
+	list := []string{}
+	for _, req := range m.served(http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete) {
+		list = append(list, req.String())
+	}
+	return list
+}
+
+func (m *mockPorta) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = nil
+}
+
+// matchMethod returns true when method is in methods, or methods is empty
+func matchMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return len(methods) == 0
+}
+
+// helperJSONResponse encodes obj as the response body, unless it is already encoded
+func helperJSONResponse(t *testing.T, code int, obj interface{}) *http.Response {
+	var body []byte
+	switch typed := obj.(type) {
+	case nil:
+	case json.RawMessage:
+		body = typed
+	case string:
+		body = []byte(typed)
+	default:
+		var err error
+		body, err = json.Marshal(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &http.Response{
+		StatusCode: code,
+		Body:       ioutil.NopCloser(bytes.NewBuffer(body)),
+		Header:     make(http.Header),
+	}
+}
@@ -0,0 +1,384 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonSchemaValidator validates JSON values against the JSON Schema (draft 7) keywords used by APIcast
+// policy manifests. Formats are not checked and references must be local to the schema
+type jsonSchemaValidator struct {
+	root   interface{}
+	errors []ValidationError
+}
+
+// validateJSONSchema validates the value, decoded JSON or any value encoding to JSON, against the schema
+// and returns the problems found, with pointers prefixed by base
+func validateJSONSchema(schema json.RawMessage, value interface{}, base string) ([]ValidationError, error) {
+	var root interface{}
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	v := &jsonSchemaValidator{root: root}
+	v.validate(root, doc, base)
+	return v.errors, nil
+}
+
+func (v *jsonSchemaValidator) add(pointer, format string, args ...interface{}) {
+	v.errors = append(v.errors, ValidationError{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+// valid tells whether the value matches the schema, without reporting anything
+func (v *jsonSchemaValidator) valid(schema, value interface{}, pointer string) bool {
+	sub := &jsonSchemaValidator{root: v.root}
+	sub.validate(schema, value, pointer)
+	return len(sub.errors) == 0
+}
+
+func (v *jsonSchemaValidator) validate(schema, value interface{}, pointer string) {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			v.add(pointer, "is not allowed")
+		}
+		return
+	case map[string]interface{}:
+		if ref, ok := s["$ref"].(string); ok {
+			target, found := resolveJSONPointer(v.root, strings.TrimPrefix(ref, "#"))
+			if !strings.HasPrefix(ref, "#") || !found {
+				v.add(pointer, "schema reference %s cannot be resolved", ref)
+				return
+			}
+			v.validate(target, value, pointer)
+			return
+		}
+		v.validateObjectSchema(s, value, pointer)
+	}
+}
+
+func (v *jsonSchemaValidator) validateObjectSchema(s map[string]interface{}, value interface{}, pointer string) {
+	if types, ok := s["type"]; ok && !jsonSchemaTypeMatches(types, value) {
+		v.add(pointer, "must be of type %s", jsonSchemaTypeNames(types))
+		return
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, item := range enum {
+			found = found || jsonEqual(item, value)
+		}
+		if !found {
+			v.add(pointer, "must be one of %s", jsonString(enum))
+		}
+	}
+	if constant, ok := s["const"]; ok && !jsonEqual(constant, value) {
+		v.add(pointer, "must be %s", jsonString(constant))
+	}
+
+	switch val := value.(type) {
+	case string:
+		v.validateString(s, val, pointer)
+	case float64:
+		v.validateNumber(s, val, pointer)
+	case map[string]interface{}:
+		v.validateObject(s, val, pointer)
+	case []interface{}:
+		v.validateArray(s, val, pointer)
+	}
+
+	v.validateCombinators(s, value, pointer)
+}
+
+func (v *jsonSchemaValidator) validateCombinators(s map[string]interface{}, value interface{}, pointer string) {
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			v.validate(sub, value, pointer)
+		}
+	}
+
+	if any, ok := s["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range any {
+			if v.valid(sub, value, pointer) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.add(pointer, "must match at least one schema of anyOf")
+		}
+	}
+
+	if one, ok := s["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range one {
+			if v.valid(sub, value, pointer) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.add(pointer, "must match exactly one schema of oneOf, matches %d", matches)
+		}
+	}
+
+	if not, ok := s["not"]; ok && v.valid(not, value, pointer) {
+		v.add(pointer, "must not match the schema of not")
+	}
+
+	if cond, ok := s["if"]; ok {
+		if v.valid(cond, value, pointer) {
+			if then, ok := s["then"]; ok {
+				v.validate(then, value, pointer)
+			}
+		} else if otherwise, ok := s["else"]; ok {
+			v.validate(otherwise, value, pointer)
+		}
+	}
+}
+
+func (v *jsonSchemaValidator) validateString(s map[string]interface{}, value, pointer string) {
+	length := float64(utf8.RuneCountInString(value))
+	if min, ok := s["minLength"].(float64); ok && length < min {
+		v.add(pointer, "must be at least %v characters long", min)
+	}
+	if max, ok := s["maxLength"].(float64); ok && length > max {
+		v.add(pointer, "must be at most %v characters long", max)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		// patterns RE2 does not support are not checked
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+			v.add(pointer, "must match the pattern %s", pattern)
+		}
+	}
+}
+
+func (v *jsonSchemaValidator) validateNumber(s map[string]interface{}, value float64, pointer string) {
+	if min, ok := s["minimum"].(float64); ok {
+		if exclusive, _ := s["exclusiveMinimum"].(bool); exclusive && value <= min {
+			v.add(pointer, "must be greater than %v", min)
+		} else if value < min {
+			v.add(pointer, "must be greater than or equal to %v", min)
+		}
+	}
+	if max, ok := s["maximum"].(float64); ok {
+		if exclusive, _ := s["exclusiveMaximum"].(bool); exclusive && value >= max {
+			v.add(pointer, "must be less than %v", max)
+		} else if value > max {
+			v.add(pointer, "must be less than or equal to %v", max)
+		}
+	}
+	if min, ok := s["exclusiveMinimum"].(float64); ok && value <= min {
+		v.add(pointer, "must be greater than %v", min)
+	}
+	if max, ok := s["exclusiveMaximum"].(float64); ok && value >= max {
+		v.add(pointer, "must be less than %v", max)
+	}
+	if multiple, ok := s["multipleOf"].(float64); ok && multiple > 0 {
+		if quotient := value / multiple; math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.add(pointer, "must be a multiple of %v", multiple)
+		}
+	}
+}
+
+func (v *jsonSchemaValidator) validateObject(s map[string]interface{}, value map[string]interface{}, pointer string) {
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, item := range required {
+			if name, ok := item.(string); ok {
+				if _, ok := value[name]; !ok {
+					v.add(pointer+jsonPointer(name), "is required")
+				}
+			}
+		}
+	}
+
+	if min, ok := s["minProperties"].(float64); ok && float64(len(value)) < min {
+		v.add(pointer, "must have at least %v properties", min)
+	}
+	if max, ok := s["maxProperties"].(float64); ok && float64(len(value)) > max {
+		v.add(pointer, "must have at most %v properties", max)
+	}
+
+	properties, _ := s["properties"].(map[string]interface{})
+	patterns, _ := s["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := s["additionalProperties"]
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propPointer := pointer + jsonPointer(name)
+		if propertyNames, ok := s["propertyNames"]; ok && !v.valid(propertyNames, name, propPointer) {
+			v.add(propPointer, "property name is not valid")
+		}
+
+		matched := false
+		if sub, ok := properties[name]; ok {
+			matched = true
+			v.validate(sub, value[name], propPointer)
+		}
+		for _, pattern := range sortedStringKeys(patterns) {
+			if re, err := regexp.Compile(pattern); err == nil && re.MatchString(name) {
+				matched = true
+				v.validate(patterns[pattern], value[name], propPointer)
+			}
+		}
+		if matched || !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			v.add(propPointer, "is not allowed")
+		} else {
+			v.validate(additional, value[name], propPointer)
+		}
+	}
+
+	if dependencies, ok := s["dependencies"].(map[string]interface{}); ok {
+		for _, name := range sortedStringKeys(dependencies) {
+			if _, ok := value[name]; !ok {
+				continue
+			}
+			if required, ok := dependencies[name].([]interface{}); ok {
+				for _, item := range required {
+					if dep, ok := item.(string); ok {
+						if _, ok := value[dep]; !ok {
+							v.add(pointer+jsonPointer(dep), "is required by %s", name)
+						}
+					}
+				}
+				continue
+			}
+			v.validate(dependencies[name], value, pointer)
+		}
+	}
+}
+
+func (v *jsonSchemaValidator) validateArray(s map[string]interface{}, value []interface{}, pointer string) {
+	if min, ok := s["minItems"].(float64); ok && float64(len(value)) < min {
+		v.add(pointer, "must have at least %v items", min)
+	}
+	if max, ok := s["maxItems"].(float64); ok && float64(len(value)) > max {
+		v.add(pointer, "must have at most %v items", max)
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+		seen := map[string]bool{}
+		for idx, item := range value {
+			key := jsonString(item)
+			if seen[key] {
+				v.add(pointer+jsonPointer(fmt.Sprint(idx)), "is a duplicate, items must be unique")
+			}
+			seen[key] = true
+		}
+	}
+
+	switch items := s["items"].(type) {
+	case []interface{}:
+		for idx, item := range value {
+			itemPointer := pointer + jsonPointer(fmt.Sprint(idx))
+			if idx < len(items) {
+				v.validate(items[idx], item, itemPointer)
+			} else if additional, ok := s["additionalItems"]; ok {
+				v.validate(additional, item, itemPointer)
+			}
+		}
+	case nil:
+	default:
+		for idx, item := range value {
+			v.validate(items, item, pointer+jsonPointer(fmt.Sprint(idx)))
+		}
+	}
+
+	if contains, ok := s["contains"]; ok {
+		found := false
+		for idx, item := range value {
+			if v.valid(contains, item, pointer+jsonPointer(fmt.Sprint(idx))) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.add(pointer, "must contain an item matching the schema of contains")
+		}
+	}
+}
+
+func jsonSchemaTypeMatches(types, value interface{}) bool {
+	switch t := types.(type) {
+	case string:
+		return jsonSchemaTypeIs(t, value)
+	case []interface{}:
+		for _, item := range t {
+			if name, ok := item.(string); ok && jsonSchemaTypeIs(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func jsonSchemaTypeIs(name string, value interface{}) bool {
+	switch name {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+func jsonSchemaTypeNames(types interface{}) string {
+	if list, ok := types.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, item := range list {
+			names = append(names, fmt.Sprint(item))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(types)
+}
+
+func jsonString(value interface{}) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(raw)
+}
+
+func jsonEqual(a, b interface{}) bool {
+	return jsonString(a) == jsonString(b)
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"definitions": {
+			"rule": {
+				"type": "object",
+				"properties": {
+					"url": {"type": "string", "pattern": "^https?://"},
+					"weight": {"type": "integer", "minimum": 1, "maximum": 10}
+				},
+				"required": ["url"],
+				"additionalProperties": false
+			}
+		},
+		"properties": {
+			"name": {"type": "string", "minLength": 2},
+			"mode": {"enum": ["strict", "lax"]},
+			"rules": {"type": "array", "items": {"$ref": "#/definitions/rule"}, "maxItems": 2},
+			"limit": {"oneOf": [{"type": "integer"}, {"type": "string", "enum": ["unlimited"]}]},
+			"user": {"type": "string"},
+			"password": {"type": "string"}
+		},
+		"dependencies": {"user": ["password"]},
+		"required": ["name"]
+	}`)
+
+	errs, err := validateJSONSchema(schema, map[string]interface{}{
+		"name":  "pets",
+		"mode":  "strict",
+		"rules": []interface{}{map[string]interface{}{"url": "https://pets.example.com", "weight": 2}},
+		"limit": "unlimited",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []ValidationError(nil), errs)
+
+	errs, err = validateJSONSchema(schema, map[string]interface{}{
+		"mode": "loose",
+		"rules": []interface{}{
+			map[string]interface{}{"url": "ftp://pets.example.com", "weight": 2.5},
+			map[string]interface{}{"weight": 20, "path": "/"},
+			map[string]interface{}{"url": "http://pets.example.com"},
+		},
+		"limit": 1.5,
+		"user":  "admin",
+	}, "/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []ValidationError{
+		{Pointer: "/config/name", Message: "is required"},
+		{Pointer: "/config/limit", Message: "must match exactly one schema of oneOf, matches 0"},
+		{Pointer: "/config/mode", Message: `must be one of ["strict","lax"]`},
+		{Pointer: "/config/rules", Message: "must have at most 2 items"},
+		{Pointer: "/config/rules/0/url", Message: "must match the pattern ^https?://"},
+		{Pointer: "/config/rules/0/weight", Message: "must be of type integer"},
+		{Pointer: "/config/rules/1/url", Message: "is required"},
+		{Pointer: "/config/rules/1/path", Message: "is not allowed"},
+		{Pointer: "/config/rules/1/weight", Message: "must be less than or equal to 10"},
+		{Pointer: "/config/password", Message: "is required by user"},
+	}, errs)
+
+	if _, err := validateJSONSchema(json.RawMessage(`{`), nil, ""); err == nil {
+		t.Fatal("expected error for invalid schema")
+	}
+}
+
+func TestValidateJSONSchemaUnresolvableRef(t *testing.T) {
+	errs, err := validateJSONSchema(json.RawMessage(`{"$ref": "#/definitions/missing"}`), "value", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []ValidationError{{Pointer: "", Message: "schema reference #/definitions/missing cannot be resolved"}}, errs)
+}
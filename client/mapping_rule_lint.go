@@ -0,0 +1,264 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Mapping rule issue codes
+const (
+	MappingRuleDuplicate       = "duplicate"
+	MappingRuleShadowed        = "shadowed"
+	MappingRuleInvalidSyntax   = "invalid_syntax"
+	MappingRuleUnknownMetric   = "unknown_metric"
+	MappingRuleOverlappingPath = "overlapping_path"
+)
+
+// Mapping rule issue severities
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+var mappingRuleHTTPMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "OPTIONS": true,
+	"TRACE": true, "CONNECT": true,
+}
+
+// LintProductMappingRules reads the mapping rules of the product and of the backend apis it uses and lints them
+func (c *ThreeScaleClient) LintProductMappingRules(productID int64) (*MappingRuleLintReport, error) {
+	sets := []MappingRuleSet{}
+
+	set, err := mappingRuleSet(productMetricOwner(c, productID), "product", "")
+	if err != nil {
+		return nil, err
+	}
+	sets = append(sets, *set)
+
+	usages, err := c.ListBackendapiUsages(productID)
+	if err != nil {
+		return nil, fmt.Errorf("product %d: backend usages: %w", productID, err)
+	}
+	for _, usage := range usages {
+		backend, err := c.BackendApi(usage.Element.BackendAPIID)
+		if err != nil {
+			return nil, fmt.Errorf("backend api %d: %w", usage.Element.BackendAPIID, err)
+		}
+		set, err := mappingRuleSet(backendMetricOwner(c, backend.Element.ID), backend.Element.SystemName, usage.Element.Path)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, *set)
+	}
+
+	return LintMappingRules(sets), nil
+}
+
+func mappingRuleSet(owner metricOwner, name, pathPrefix string) (*MappingRuleSet, error) {
+	rules, err := owner.listMappingRules()
+	if err != nil {
+		return nil, fmt.Errorf("%s: mapping rules: %w", owner, err)
+	}
+
+	metrics, err := owner.listMetrics()
+	if err != nil {
+		return nil, fmt.Errorf("%s: metrics: %w", owner, err)
+	}
+
+	set := &MappingRuleSet{Name: name, PathPrefix: pathPrefix, Rules: mappingRuleItems(rules), MetricIDs: []int64{}}
+	for _, metric := range metrics.Metrics {
+		set.MetricIDs = append(set.MetricIDs, metric.Element.ID)
+	}
+	return set, nil
+}
+
+// LintMappingRules checks the mapping rule sets of a product, product rules first, and reports:
+//   - rules with an invalid HTTP method or pattern
+//   - rules with the same HTTP method and pattern as a previous rule of the same set
+//   - rules referencing metrics that do not exist
+//   - rules that never get evaluated, because an earlier rule flagged as last matches all their requests
+//   - backend usage paths overlapping with the path of another backend usage
+func LintMappingRules(sets []MappingRuleSet) *MappingRuleLintReport {
+	l := &mappingRuleLinter{report: &MappingRuleLintReport{Issues: []MappingRuleIssue{}}}
+
+	// rules flagged as last seen so far, in evaluation order
+	lastRules := []simulatedMappingRule{}
+	for _, set := range sets {
+		l.lintSet(set, &lastRules)
+	}
+	l.lintPaths(sets)
+
+	return l.report
+}
+
+type mappingRuleLinter struct {
+	report *MappingRuleLintReport
+}
+
+func (l *mappingRuleLinter) add(code, severity, set string, rule *MappingRuleItem, format string, args ...interface{}) {
+	issue := MappingRuleIssue{Code: code, Severity: severity, Set: set, Message: fmt.Sprintf(format, args...)}
+	if rule != nil {
+		issue.MappingRuleID, issue.HTTPMethod, issue.Pattern = rule.ID, rule.HTTPMethod, rule.Pattern
+	}
+	l.report.Issues = append(l.report.Issues, issue)
+}
+
+func (l *mappingRuleLinter) lintSet(set MappingRuleSet, lastRules *[]simulatedMappingRule) {
+	var metricIDs map[int64]bool
+	if set.MetricIDs != nil {
+		metricIDs = map[int64]bool{}
+		for _, id := range set.MetricIDs {
+			metricIDs[id] = true
+		}
+	}
+
+	rules := make([]MappingRuleItem, len(set.Rules))
+	copy(rules, set.Rules)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Position < rules[j].Position
+	})
+
+	seen := map[string]MappingRuleItem{}
+	for idx := range rules {
+		rule := &rules[idx]
+
+		if !mappingRuleHTTPMethods[strings.ToUpper(rule.HTTPMethod)] {
+			l.add(MappingRuleInvalidSyntax, LintError, set.Name, rule, "invalid HTTP method %q", rule.HTTPMethod)
+		}
+
+		pattern := rule.Pattern
+		if set.PathPrefix != "" {
+			pattern = strings.TrimSuffix(set.PathPrefix, "/") + pattern
+		}
+		path, query, err := compileMappingRulePattern(pattern)
+		if err != nil {
+			l.add(MappingRuleInvalidSyntax, LintError, set.Name, rule, "%v", err)
+			continue
+		}
+
+		key := mappingRuleKey(rule.HTTPMethod, rule.Pattern)
+		if previous, ok := seen[key]; ok {
+			l.add(MappingRuleDuplicate, LintError, set.Name, rule, "same HTTP method and pattern as mapping rule %d", previous.ID)
+		} else {
+			seen[key] = *rule
+		}
+
+		if metricIDs != nil && !metricIDs[rule.MetricID] {
+			l.add(MappingRuleUnknownMetric, LintError, set.Name, rule, "metric %d does not exist", rule.MetricID)
+		}
+
+		samples := mappingRuleSamples(pattern)
+		for _, last := range *lastRules {
+			if !strings.EqualFold(last.rule.HTTPMethod, rule.HTTPMethod) || !last.matchesSamples(samples) {
+				continue
+			}
+			l.add(MappingRuleShadowed, LintWarning, set.Name, rule, "never evaluated: mapping rule %d (%s %s) is flagged as last and matches first",
+				last.rule.ID, last.rule.HTTPMethod, last.rule.Pattern)
+			break
+		}
+
+		if rule.Last {
+			*lastRules = append(*lastRules, simulatedMappingRule{
+				rule:  MatchedMappingRule{MappingRuleItem: *rule, PathPrefix: set.PathPrefix},
+				path:  path,
+				query: query,
+			})
+		}
+	}
+}
+
+// lintPaths reports backend usages whose paths are equal, or nested so that requests under
+// the inner path match both backends
+func (l *mappingRuleLinter) lintPaths(sets []MappingRuleSet) {
+	for i := range sets {
+		if sets[i].PathPrefix == "" {
+			continue
+		}
+		for j := 0; j < i; j++ {
+			if sets[j].PathPrefix == "" {
+				continue
+			}
+			inner, outer := mappingRulePathContains(sets[j].PathPrefix, sets[i].PathPrefix), mappingRulePathContains(sets[i].PathPrefix, sets[j].PathPrefix)
+			switch {
+			case inner && outer:
+				l.add(MappingRuleOverlappingPath, LintError, sets[i].Name, nil, "backend path %s is also the path of %s",
+					sets[i].PathPrefix, sets[j].Name)
+			case inner || outer:
+				l.add(MappingRuleOverlappingPath, LintWarning, sets[i].Name, nil, "backend path %s overlaps with path %s of %s",
+					sets[i].PathPrefix, sets[j].PathPrefix, sets[j].Name)
+			}
+		}
+	}
+}
+
+// mappingRulePathContains tells whether every path under inner is also under outer
+func mappingRulePathContains(outer, inner string) bool {
+	outer = strings.TrimSuffix(outer, "/") + "/"
+	inner = strings.TrimSuffix(inner, "/") + "/"
+	return strings.HasPrefix(inner, outer)
+}
+
+// mappingRuleSamples returns requests the pattern matches. Placeholders take two different values and
+// paths not anchored with $ get extra characters and segments, so a rule matching every sample covers
+// the pattern: its literals cover the literals of the pattern and the placeholders of the pattern
+// are placeholders in the rule as well
+func mappingRuleSamples(pattern string) []*url.URL {
+	path, query := pattern, ""
+	if idx := strings.Index(pattern, "?"); idx >= 0 {
+		path, query = pattern[:idx], strings.Replace(pattern[idx:], "$", "", -1)
+	}
+	anchored := strings.HasSuffix(path, "$")
+	path = strings.Replace(path, "$", "", -1)
+
+	samples := []*url.URL{}
+	for _, value := range []string{"x", "y"} {
+		suffixes := []string{""}
+		if !anchored {
+			suffixes = append(suffixes, value, "/"+value)
+		}
+		for _, suffix := range suffixes {
+			sample := mappingRulePlaceholder.ReplaceAllLiteralString(path, value) + suffix +
+				mappingRulePlaceholder.ReplaceAllLiteralString(query, value)
+			u, err := url.Parse(sample)
+			if err != nil {
+				u = &url.URL{Path: sample}
+			}
+			samples = append(samples, u)
+		}
+	}
+	return samples
+}
+
+func (r simulatedMappingRule) matchesSamples(samples []*url.URL) bool {
+	for _, sample := range samples {
+		if !r.path.MatchString(sample.Path) || !r.matchesQuery(sample.Query()) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasErrors tells whether the report holds issues with error severity
+func (r *MappingRuleLintReport) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns one line per issue
+func (r *MappingRuleLintReport) String() string {
+	var sb strings.Builder
+	for _, issue := range r.Issues {
+		fmt.Fprintf(&sb, "%s: %s: %s", issue.Severity, issue.Code, issue.Set)
+		if issue.Pattern != "" {
+			fmt.Fprintf(&sb, " %s %s", issue.HTTPMethod, issue.Pattern)
+		}
+		fmt.Fprintf(&sb, ": %s\n", issue.Message)
+	}
+	return sb.String()
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestLintMappingRules(t *testing.T) {
+	report := LintMappingRules([]MappingRuleSet{
+		{
+			Name: "product",
+			Rules: []MappingRuleItem{
+				{ID: 1, MetricID: 10, HTTPMethod: "GET", Pattern: "/pets", Position: 1, Last: true},
+				{ID: 2, MetricID: 10, HTTPMethod: "GET", Pattern: "/pets/{id}", Position: 2},
+				{ID: 3, MetricID: 10, HTTPMethod: "POST", Pattern: "/pets", Position: 3},
+				{ID: 4, MetricID: 10, HTTPMethod: "POST", Pattern: "/pets", Position: 4},
+				{ID: 5, MetricID: 99, HTTPMethod: "GET", Pattern: "/cats", Position: 5},
+				{ID: 6, MetricID: 10, HTTPMethod: "FETCH", Pattern: "/cats(", Position: 6},
+			},
+			MetricIDs: []int64{10},
+		},
+		{
+			Name:       "pets_backend",
+			PathPrefix: "/",
+			Rules: []MappingRuleItem{
+				// shadowed by the product rule across sets
+				{ID: 7, MetricID: 20, HTTPMethod: "GET", Pattern: "/pets/toys$", Position: 1},
+				{ID: 8, MetricID: 20, HTTPMethod: "GET", Pattern: "/dogs", Position: 2},
+			},
+		},
+		{Name: "v1_backend", PathPrefix: "/v1"},
+		{Name: "v1_copy_backend", PathPrefix: "/v1/"},
+	})
+
+	type issue struct {
+		Code string
+		ID   int64
+	}
+	issues := []issue{}
+	for _, i := range report.Issues {
+		issues = append(issues, issue{i.Code, i.MappingRuleID})
+	}
+	equals(t, []issue{
+		{MappingRuleShadowed, 2},
+		{MappingRuleDuplicate, 4},
+		{MappingRuleUnknownMetric, 5},
+		{MappingRuleInvalidSyntax, 6},
+		{MappingRuleInvalidSyntax, 6},
+		{MappingRuleShadowed, 7},
+		{MappingRuleOverlappingPath, 0},
+		{MappingRuleOverlappingPath, 0},
+		{MappingRuleOverlappingPath, 0},
+	}, issues)
+
+	equals(t, true, report.HasErrors())
+	equals(t, LintWarning, report.Issues[6].Severity)
+	equals(t, LintError, report.Issues[8].Severity)
+	equals(t, "v1_copy_backend", report.Issues[8].Set)
+
+	// usable as CI output
+	raw, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &MappingRuleLintReport{}
+	if err := json.Unmarshal(raw, decoded); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, report, decoded)
+}
+
+func TestLintMappingRulesClean(t *testing.T) {
+	report := LintMappingRules([]MappingRuleSet{
+		{Name: "product", Rules: []MappingRuleItem{
+			{ID: 1, HTTPMethod: "GET", Pattern: "/pets$", Last: true},
+			{ID: 2, HTTPMethod: "GET", Pattern: "/pets/{id}"},
+		}},
+		{Name: "a", PathPrefix: "/a"},
+		{Name: "ab", PathPrefix: "/ab"},
+	})
+	equals(t, 0, len(report.Issues))
+	equals(t, false, report.HasErrors())
+	equals(t, "", report.String())
+}
+
+func TestLintMappingRulesShadowed(t *testing.T) {
+	tests := []struct {
+		last, rule string
+		shadowed   bool
+	}{
+		{"/a/{id}", "/a/x", true},
+		{"/a/x", "/a/{id}", false},
+		{"/a", "/a/{id}/b", true},
+		{"/a/", "/a", false},
+		{"/a$", "/a", false},
+		{"/a", "/a$", true},
+		{"/a/{id}$", "/a/{id}", false},
+		{"/a/{id}", "/a/{other}.json$", true},
+		{"/a/{id}.json", "/a/{other}", false},
+		{"/a?k={v}", "/a?k=x", true},
+		{"/a?k=x", "/a?k={v}", false},
+	}
+	for _, tt := range tests {
+		report := LintMappingRules([]MappingRuleSet{
+			{Name: "product", Rules: []MappingRuleItem{
+				{ID: 1, HTTPMethod: "GET", Pattern: tt.last, Position: 1, Last: true},
+				{ID: 2, HTTPMethod: "GET", Pattern: tt.rule, Position: 2},
+			}},
+		})
+		shadowed := len(report.Issues) == 1 && report.Issues[0].Code == MappingRuleShadowed
+		if shadowed != tt.shadowed {
+			t.Errorf("GET %s flagged as last, GET %s shadowed: expected %t; got %t", tt.last, tt.rule, tt.shadowed, shadowed)
+		}
+	}
+}
+
+func TestLintProductMappingRules(t *testing.T) {
+	porta := petsPorta(t, true)
+	c := porta.client()
+
+	report, err := c.LintProductMappingRules(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 0, len(report.Issues))
+
+	// the product rule is flagged as last and now matches the backend rule
+	porta.reply(http.MethodGet, "/admin/api/services/10/proxy/mapping_rules.json", http.StatusOK, MappingRuleJSONList{
+		MappingRules: []MappingRuleJSON{
+			{Element: MappingRuleItem{ID: 13, MetricID: 12, Pattern: "/v1", HTTPMethod: "GET", Delta: 1, Position: 1, Last: true}},
+		},
+	})
+
+	report, err = c.LintProductMappingRules(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 1, len(report.Issues))
+	equals(t, MappingRuleShadowed, report.Issues[0].Code)
+	equals(t, "pets_backend", report.Issues[0].Set)
+	equals(t, "warning: shadowed: pets_backend GET /pets$: never evaluated: mapping rule 13 (GET /v1) "+
+		"is flagged as last and matches first\n", report.String())
+}
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ReplaceProductMappingRules makes the mapping rules of the product match the given ones.
+// See replaceMappingRules
+func (c *ThreeScaleClient) ReplaceProductMappingRules(productID int64, rules []MappingRuleItem) (*MappingRulesReplaceResult, error) {
+	return productMetricOwner(c, productID).replaceMappingRules(rules)
+}
+
+// ReplaceBackendApiMappingRules makes the mapping rules of the backend api match the given ones.
+// See replaceMappingRules
+func (c *ThreeScaleClient) ReplaceBackendApiMappingRules(backendapiID int64, rules []MappingRuleItem) (*MappingRulesReplaceResult, error) {
+	return backendMetricOwner(c, backendapiID).replaceMappingRules(rules)
+}
+
+// ReorderProductMappingRules sets the position of the product mapping rules following the given order.
+// ids must hold every mapping rule of the product
+func (c *ThreeScaleClient) ReorderProductMappingRules(productID int64, ids []int64) (*MappingRulesReplaceResult, error) {
+	return productMetricOwner(c, productID).reorderMappingRules(ids)
+}
+
+// ReorderBackendApiMappingRules sets the position of the backend api mapping rules following the given order.
+// ids must hold every mapping rule of the backend api
+func (c *ThreeScaleClient) ReorderBackendApiMappingRules(backendapiID int64, ids []int64) (*MappingRulesReplaceResult, error) {
+	return backendMetricOwner(c, backendapiID).reorderMappingRules(ids)
+}
+
+// replaceMappingRules makes the current mapping rules match the given ones, in the given order.
+// IDs and positions of the given rules are ignored: a rule takes the place of the current rule with the same
+// HTTP method and pattern, which is updated when any other field differs. Current rules left without a
+// counterpart are deleted and given rules left without one are created.
+// When an operation fails, the ones already performed are reverted. Deleted rules come back with new IDs
+func (o metricOwner) replaceMappingRules(rules []MappingRuleItem) (*MappingRulesReplaceResult, error) {
+	list, err := o.listMappingRules()
+	if err != nil {
+		return nil, fmt.Errorf("%s: mapping rules: %w", o, err)
+	}
+	current := mappingRuleItems(list)
+	sortMappingRuleItems(current)
+
+	// current rules by key, in position order, so duplicated keys pair up in order
+	byKey := map[string][]MappingRuleItem{}
+	for _, rule := range current {
+		key := mappingRuleKey(rule.HTTPMethod, rule.Pattern)
+		byKey[key] = append(byKey[key], rule)
+	}
+
+	type pair struct {
+		desired MappingRuleItem
+		current *MappingRuleItem
+	}
+	pairs := []pair{}
+	for idx, rule := range rules {
+		rule.ID = 0
+		rule.Position = idx + 1
+		p := pair{desired: rule}
+		key := mappingRuleKey(rule.HTTPMethod, rule.Pattern)
+		if matches := byKey[key]; len(matches) > 0 {
+			p.current = &matches[0]
+			byKey[key] = matches[1:]
+		}
+		pairs = append(pairs, p)
+	}
+
+	unmatched := map[int64]bool{}
+	for _, matches := range byKey {
+		for _, rule := range matches {
+			unmatched[rule.ID] = true
+		}
+	}
+
+	tx := newMappingRuleTx(o)
+	// deleting first frees the positions the remaining rules move to
+	for _, rule := range current {
+		if !unmatched[rule.ID] {
+			continue
+		}
+		if err := tx.delete(rule); err != nil {
+			return tx.rollback(err)
+		}
+	}
+
+	for _, p := range pairs {
+		var err error
+		if p.current == nil {
+			err = tx.create(p.desired)
+		} else {
+			p.desired.ID = p.current.ID
+			err = tx.update(*p.current, p.desired)
+		}
+		if err != nil {
+			return tx.rollback(err)
+		}
+	}
+
+	return tx.result, nil
+}
+
+// reorderMappingRules sets the position of the mapping rules following the order of the given IDs
+func (o metricOwner) reorderMappingRules(ids []int64) (*MappingRulesReplaceResult, error) {
+	list, err := o.listMappingRules()
+	if err != nil {
+		return nil, fmt.Errorf("%s: mapping rules: %w", o, err)
+	}
+
+	current := map[int64]MappingRuleItem{}
+	for _, rule := range list.MappingRules {
+		current[rule.Element.ID] = rule.Element
+	}
+	if len(ids) != len(current) {
+		return nil, fmt.Errorf("%s: expected the %d mapping rule IDs; got %d", o, len(current), len(ids))
+	}
+
+	rules := make([]MappingRuleItem, 0, len(ids))
+	for _, id := range ids {
+		rule, ok := current[id]
+		if !ok {
+			return nil, fmt.Errorf("%s: mapping rule %d not found", o, id)
+		}
+		delete(current, id)
+		rules = append(rules, rule)
+	}
+
+	tx := newMappingRuleTx(o)
+	for idx, rule := range rules {
+		desired := rule
+		desired.Position = idx + 1
+		if err := tx.update(rule, desired); err != nil {
+			return tx.rollback(err)
+		}
+	}
+
+	return tx.result, nil
+}
+
+// mappingRuleTx performs mapping rule operations, keeping what is needed to revert them
+type mappingRuleTx struct {
+	owner  metricOwner
+	result *MappingRulesReplaceResult
+	undo   []func() error
+}
+
+func newMappingRuleTx(owner metricOwner) *mappingRuleTx {
+	return &mappingRuleTx{owner: owner, result: &MappingRulesReplaceResult{
+		Created: []MappingRuleItem{},
+		Updated: []MappingRuleItem{},
+		Deleted: []MappingRuleItem{},
+	}}
+}
+
+func (tx *mappingRuleTx) create(rule MappingRuleItem) error {
+	created, err := tx.owner.createMappingRule(mappingRuleItemParams(rule))
+	if err != nil {
+		return fmt.Errorf("create mapping rule %s %s: %w", rule.HTTPMethod, rule.Pattern, err)
+	}
+
+	tx.result.Created = append(tx.result.Created, created.Element)
+	tx.undo = append(tx.undo, func() error {
+		return tx.owner.deleteMappingRule(created.Element.ID)
+	})
+	return nil
+}
+
+func (tx *mappingRuleTx) update(current, desired MappingRuleItem) error {
+	params := changedParams(mappingRuleItemParams(desired), mappingRuleItemParams(current))
+	if len(params) == 0 {
+		return nil
+	}
+
+	updated, err := tx.owner.updateMappingRule(current.ID, params)
+	if err != nil {
+		return fmt.Errorf("update mapping rule %d: %w", current.ID, err)
+	}
+
+	tx.result.Updated = append(tx.result.Updated, updated.Element)
+	tx.undo = append(tx.undo, func() error {
+		_, err := tx.owner.updateMappingRule(current.ID, changedParams(mappingRuleItemParams(current), mappingRuleItemParams(desired)))
+		return err
+	})
+	return nil
+}
+
+func (tx *mappingRuleTx) delete(rule MappingRuleItem) error {
+	if err := tx.owner.deleteMappingRule(rule.ID); err != nil {
+		return fmt.Errorf("delete mapping rule %d: %w", rule.ID, err)
+	}
+
+	tx.result.Deleted = append(tx.result.Deleted, rule)
+	tx.undo = append(tx.undo, func() error {
+		_, err := tx.owner.createMappingRule(mappingRuleItemParams(rule))
+		return err
+	})
+	return nil
+}
+
+// rollback reverts the performed operations, last first, and returns the error that caused it
+func (tx *mappingRuleTx) rollback(cause error) (*MappingRulesReplaceResult, error) {
+	for idx := len(tx.undo) - 1; idx >= 0; idx-- {
+		if err := tx.undo[idx](); err != nil {
+			return tx.result, fmt.Errorf("%s: %w (rollback failed: %v)", tx.owner, cause, err)
+		}
+	}
+	tx.result.RolledBack = true
+	return tx.result, fmt.Errorf("%s: %w", tx.owner, cause)
+}
+
+func mappingRuleItemParams(rule MappingRuleItem) Params {
+	return Params{
+		"http_method": rule.HTTPMethod,
+		"pattern":     rule.Pattern,
+		"metric_id":   strconv.FormatInt(rule.MetricID, 10),
+		"delta":       strconv.Itoa(rule.Delta),
+		"last":        strconv.FormatBool(rule.Last),
+		"position":    strconv.Itoa(rule.Position),
+	}
+}
+
+func sortMappingRuleItems(rules []MappingRuleItem) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Position < rules[j].Position
+	})
+}
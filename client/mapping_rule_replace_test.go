@@ -0,0 +1,175 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// mappingRulesPorta serves the given mapping rules at path, the mapping rule list endpoint without
+// the .json extension. Created and updated rules echo the params and patterns not starting with / are rejected
+func mappingRulesPorta(t *testing.T, path string, rules ...MappingRuleItem) *mockPorta {
+	porta := newMockPorta(t)
+	list := MappingRuleJSONList{MappingRules: []MappingRuleJSON{}}
+	for _, rule := range rules {
+		list.MappingRules = append(list.MappingRules, MappingRuleJSON{Element: rule})
+		serveMappingRule(porta, path, rule.ID)
+	}
+	porta.reply(http.MethodGet, path+".json", http.StatusOK, list)
+
+	nextID := int64(100)
+	porta.handle(http.MethodPost, path+".json", func(req *http.Request) *http.Response {
+		rule := mappingRuleForm(t, req)
+		if !strings.HasPrefix(rule.Pattern, "/") {
+			return helperJSONResponse(t, http.StatusUnprocessableEntity, `{"errors":{"pattern":["is invalid"]}}`)
+		}
+		rule.ID, nextID = nextID, nextID+1
+		serveMappingRule(porta, path, rule.ID)
+		return helperJSONResponse(t, http.StatusCreated, MappingRuleJSON{Element: rule})
+	})
+	return porta
+}
+
+func serveMappingRule(porta *mockPorta, path string, id int64) {
+	rulePath := fmt.Sprintf("%s/%d.json", path, id)
+	porta.handle(http.MethodPut, rulePath, func(req *http.Request) *http.Response {
+		rule := mappingRuleForm(porta.t, req)
+		rule.ID = id
+		return helperJSONResponse(porta.t, http.StatusOK, MappingRuleJSON{Element: rule})
+	})
+	porta.reply(http.MethodDelete, rulePath, http.StatusOK, nil)
+}
+
+func mappingRuleForm(t *testing.T, req *http.Request) MappingRuleItem {
+	if err := req.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+	rule := MappingRuleItem{HTTPMethod: req.PostForm.Get("http_method"), Pattern: req.PostForm.Get("pattern")}
+	rule.MetricID, _ = strconv.ParseInt(req.PostForm.Get("metric_id"), 10, 64)
+	rule.Delta, _ = strconv.Atoi(req.PostForm.Get("delta"))
+	rule.Position, _ = strconv.Atoi(req.PostForm.Get("position"))
+	rule.Last = req.PostForm.Get("last") == "true"
+	return rule
+}
+
+func TestReplaceProductMappingRules(t *testing.T) {
+	path := "/admin/api/services/10/proxy/mapping_rules"
+	porta := mappingRulesPorta(t, path,
+		MappingRuleItem{ID: 1, MetricID: 11, HTTPMethod: "GET", Pattern: "/pets", Delta: 1, Position: 1},
+		MappingRuleItem{ID: 2, MetricID: 11, HTTPMethod: "GET", Pattern: "/cats", Delta: 1, Position: 2},
+		MappingRuleItem{ID: 3, MetricID: 11, HTTPMethod: "POST", Pattern: "/pets", Delta: 1, Position: 3},
+	)
+	c := porta.client()
+
+	desired := []MappingRuleItem{
+		{MetricID: 11, HTTPMethod: "POST", Pattern: "/pets", Delta: 5, Last: true},
+		{MetricID: 11, HTTPMethod: "GET", Pattern: "/pets", Delta: 1},
+		{MetricID: 11, HTTPMethod: "GET", Pattern: "/dogs", Delta: 1},
+	}
+	result, err := c.ReplaceProductMappingRules(10, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 1, len(result.Created))
+	equals(t, 2, len(result.Updated))
+	equals(t, 1, len(result.Deleted))
+	equals(t, "/cats", result.Deleted[0].Pattern)
+	equals(t, false, result.RolledBack)
+
+	// matched rules keep their IDs, only changed fields are sent
+	equals(t, []string{
+		"DELETE " + path + "/2.json",
+		"PUT " + path + "/3.json",
+		"PUT " + path + "/1.json",
+		"POST " + path + ".json",
+	}, porta.writes())
+	writes := porta.served(http.MethodPut, http.MethodPost)
+	equals(t, url.Values{"delta": {"5"}, "last": {"true"}, "position": {"1"}}, writes[0].Params)
+	equals(t, url.Values{"position": {"2"}}, writes[1].Params)
+	equals(t, "/dogs", writes[2].Params.Get("pattern"))
+	equals(t, "3", writes[2].Params.Get("position"))
+
+	// nothing to do the second time
+	porta.reply(http.MethodGet, path+".json", http.StatusOK, MappingRuleJSONList{MappingRules: []MappingRuleJSON{
+		{Element: MappingRuleItem{ID: 3, MetricID: 11, HTTPMethod: "POST", Pattern: "/pets", Delta: 5, Last: true, Position: 1}},
+		{Element: MappingRuleItem{ID: 1, MetricID: 11, HTTPMethod: "GET", Pattern: "/pets", Delta: 1, Position: 2}},
+		{Element: MappingRuleItem{ID: 100, MetricID: 11, HTTPMethod: "GET", Pattern: "/dogs", Delta: 1, Position: 3}},
+	}})
+	porta.reset()
+	result, err = c.ReplaceProductMappingRules(10, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 0, len(result.Created)+len(result.Updated)+len(result.Deleted))
+	equals(t, 1, len(porta.served()))
+}
+
+func TestReplaceMappingRulesRollback(t *testing.T) {
+	path := "/admin/api/backend_apis/20/mapping_rules"
+	porta := mappingRulesPorta(t, path,
+		MappingRuleItem{ID: 1, MetricID: 21, HTTPMethod: "GET", Pattern: "/pets", Delta: 1, Position: 1},
+		MappingRuleItem{ID: 2, MetricID: 21, HTTPMethod: "GET", Pattern: "/cats", Delta: 1, Position: 2},
+	)
+
+	// the second rule created fails
+	result, err := porta.client().ReplaceBackendApiMappingRules(20, []MappingRuleItem{
+		{MetricID: 21, HTTPMethod: "GET", Pattern: "/dogs", Delta: 1},
+		{MetricID: 21, HTTPMethod: "GET", Pattern: "/pets", Delta: 2},
+		{MetricID: 21, HTTPMethod: "GET", Pattern: "birds", Delta: 1},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "birds") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equals(t, true, result.RolledBack)
+
+	// operations are reverted last first
+	equals(t, []string{
+		"DELETE " + path + "/2.json",
+		"POST " + path + ".json",
+		"PUT " + path + "/1.json",
+		"POST " + path + ".json",
+		"PUT " + path + "/1.json",
+		"DELETE " + path + "/100.json",
+		"POST " + path + ".json",
+	}, porta.writes())
+	writes := porta.served(http.MethodPut, http.MethodPost)
+	equals(t, url.Values{"delta": {"1"}, "position": {"1"}}, writes[3].Params)
+	equals(t, "/cats", writes[4].Params.Get("pattern"))
+	equals(t, "2", writes[4].Params.Get("position"))
+}
+
+func TestReorderProductMappingRules(t *testing.T) {
+	path := "/admin/api/services/10/proxy/mapping_rules"
+	porta := mappingRulesPorta(t, path,
+		MappingRuleItem{ID: 1, MetricID: 11, HTTPMethod: "GET", Pattern: "/a", Delta: 1, Position: 1},
+		MappingRuleItem{ID: 2, MetricID: 11, HTTPMethod: "GET", Pattern: "/b", Delta: 1, Position: 2},
+		MappingRuleItem{ID: 3, MetricID: 11, HTTPMethod: "GET", Pattern: "/d", Delta: 1, Position: 3},
+	)
+	c := porta.client()
+
+	result, err := c.ReorderProductMappingRules(10, []int64{1, 3, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a keeps its position
+	equals(t, 2, len(result.Updated))
+	equals(t, []string{"PUT " + path + "/3.json", "PUT " + path + "/2.json"}, porta.writes())
+	writes := porta.served(http.MethodPut)
+	equals(t, url.Values{"position": {"2"}}, writes[0].Params)
+	equals(t, url.Values{"position": {"3"}}, writes[1].Params)
+
+	porta.reset()
+	if _, err := c.ReorderProductMappingRules(10, []int64{1, 2}); err == nil {
+		t.Fatal("expected error for missing mapping rule")
+	}
+	if _, err := c.ReorderProductMappingRules(10, []int64{1, 2, 4}); err == nil {
+		t.Fatal("expected error for unknown mapping rule")
+	}
+	equals(t, []string{}, porta.writes())
+}
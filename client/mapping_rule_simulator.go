@@ -0,0 +1,223 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// characters APIcast accepts in the value of a {placeholder}
+const mappingRulePlaceholderValue = `[\w.~%!$&'()*+,;=@:-]+`
+
+var mappingRulePlaceholder = regexp.MustCompile(`\{.+?\}`)
+
+type simulatedMappingRule struct {
+	rule  MatchedMappingRule
+	path  *regexp.Regexp
+	query []mappingRuleQueryParam
+}
+
+type mappingRuleQueryParam struct {
+	name  string
+	value string
+	// placeholder params match any value
+	placeholder bool
+}
+
+// NewMappingRuleSimulator returns a simulator without rules
+func NewMappingRuleSimulator() *MappingRuleSimulator {
+	return &MappingRuleSimulator{rules: []simulatedMappingRule{}}
+}
+
+// NewProxyConfigSimulator returns a simulator with the proxy rules of the given proxy config.
+// Proxy configs hold the product rules and the backend rules, already prefixed with the backend usage path
+func NewProxyConfigSimulator(config ProxyConfig) (*MappingRuleSimulator, error) {
+	s := NewMappingRuleSimulator()
+	if err := s.AddProxyRules(config.Content.Proxy.ProxyRules); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ProductMappingRuleSimulator returns a simulator with the current mapping rules of the product and of the
+// backend apis it uses, so changes can be tested before deploying them
+func (c *ThreeScaleClient) ProductMappingRuleSimulator(productID int64) (*MappingRuleSimulator, error) {
+	s := NewMappingRuleSimulator()
+
+	rules, err := c.ListProductMappingRules(productID)
+	if err != nil {
+		return nil, fmt.Errorf("product %d: mapping rules: %w", productID, err)
+	}
+	if err := s.AddMappingRules("", mappingRuleItems(rules)); err != nil {
+		return nil, err
+	}
+
+	usages, err := c.ListBackendapiUsages(productID)
+	if err != nil {
+		return nil, fmt.Errorf("product %d: backend usages: %w", productID, err)
+	}
+	for _, usage := range usages {
+		rules, err := c.ListBackendapiMappingRules(usage.Element.BackendAPIID)
+		if err != nil {
+			return nil, fmt.Errorf("backend api %d: mapping rules: %w", usage.Element.BackendAPIID, err)
+		}
+		if err := s.AddMappingRules(usage.Element.Path, mappingRuleItems(rules)); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func mappingRuleItems(list *MappingRuleJSONList) []MappingRuleItem {
+	items := make([]MappingRuleItem, 0, len(list.MappingRules))
+	for _, rule := range list.MappingRules {
+		items = append(items, rule.Element)
+	}
+	return items
+}
+
+// AddMappingRules adds mapping rules of a product (pathPrefix "") or of a backend api used by a product at
+// the given path. Rules are evaluated in the order they are added, sorted by position within each call,
+// so product rules are expected first.
+func (s *MappingRuleSimulator) AddMappingRules(pathPrefix string, rules []MappingRuleItem) error {
+	matched := make([]MatchedMappingRule, 0, len(rules))
+	for _, rule := range rules {
+		matched = append(matched, MatchedMappingRule{MappingRuleItem: rule, PathPrefix: pathPrefix})
+	}
+	return s.add(matched)
+}
+
+// AddProxyRules adds the rules of a proxy config, which hold the metric system names
+func (s *MappingRuleSimulator) AddProxyRules(rules []ProxyRule) error {
+	matched := make([]MatchedMappingRule, 0, len(rules))
+	for _, rule := range rules {
+		matched = append(matched, MatchedMappingRule{
+			MappingRuleItem: MappingRuleItem{
+				ID:         rule.ID,
+				MetricID:   rule.MetricID,
+				Pattern:    rule.Pattern,
+				HTTPMethod: rule.HTTPMethod,
+				Delta:      int(rule.Delta),
+				Position:   rule.Position,
+				Last:       rule.Last,
+				CreatedAt:  rule.CreatedAt,
+				UpdatedAt:  rule.UpdatedAt,
+			},
+			MetricSystemName: rule.MetricSystemName,
+		})
+	}
+	return s.add(matched)
+}
+
+func (s *MappingRuleSimulator) add(rules []MatchedMappingRule) error {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Position < rules[j].Position
+	})
+
+	compiled := make([]simulatedMappingRule, 0, len(rules))
+	for _, rule := range rules {
+		pattern := rule.Pattern
+		if rule.PathPrefix != "" {
+			pattern = strings.TrimSuffix(rule.PathPrefix, "/") + pattern
+		}
+
+		path, query, err := compileMappingRulePattern(pattern)
+		if err != nil {
+			return fmt.Errorf("mapping rule %s %s: %w", rule.HTTPMethod, rule.Pattern, err)
+		}
+		compiled = append(compiled, simulatedMappingRule{rule: rule, path: path, query: query})
+	}
+
+	s.rules = append(s.rules, compiled...)
+	return nil
+}
+
+// compileMappingRulePattern converts a mapping rule pattern into the regular expression APIcast matches
+// the request path with, and the query string params it requires
+func compileMappingRulePattern(pattern string) (*regexp.Regexp, []mappingRuleQueryParam, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, nil, fmt.Errorf("pattern %q must start with /", pattern)
+	}
+
+	path, rawQuery := pattern, ""
+	if idx := strings.Index(pattern, "?"); idx >= 0 {
+		path, rawQuery = pattern[:idx], pattern[idx+1:]
+	}
+
+	expr := mappingRulePlaceholder.ReplaceAllLiteralString(path, "\x00")
+	expr = strings.Replace(expr, ".", `\.`, -1)
+	expr = strings.Replace(expr, "\x00", mappingRulePlaceholderValue, -1)
+
+	re, err := regexp.Compile("^" + expr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	query := []mappingRuleQueryParam{}
+	if rawQuery != "" {
+		for _, pair := range strings.Split(strings.TrimSuffix(rawQuery, "$"), "&") {
+			if pair == "" {
+				continue
+			}
+			name, value := pair, ""
+			if idx := strings.Index(pair, "="); idx >= 0 {
+				name, value = pair[:idx], pair[idx+1:]
+			}
+			query = append(query, mappingRuleQueryParam{
+				name:        name,
+				value:       value,
+				placeholder: mappingRulePlaceholder.MatchString(value),
+			})
+		}
+	}
+
+	return re, query, nil
+}
+
+// Match evaluates a request, given by its HTTP method and its path with an optional query string.
+// Every matching rule adds its delta to its metric, until a matching rule flagged as last is found
+func (s *MappingRuleSimulator) Match(method, uri string) (*MappingRuleMatch, error) {
+	u, err := url.ParseRequestURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	args := u.Query()
+
+	result := &MappingRuleMatch{Rules: []MatchedMappingRule{}, Deltas: map[int64]int{}}
+	for _, rule := range s.rules {
+		if !strings.EqualFold(rule.rule.HTTPMethod, method) || !rule.path.MatchString(u.Path) || !rule.matchesQuery(args) {
+			continue
+		}
+
+		result.Rules = append(result.Rules, rule.rule)
+		result.Deltas[rule.rule.MetricID] += rule.rule.Delta
+		if rule.rule.Last {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+func (r simulatedMappingRule) matchesQuery(args url.Values) bool {
+	for _, param := range r.query {
+		values, ok := args[param.name]
+		if !ok {
+			return false
+		}
+		if param.placeholder {
+			continue
+		}
+		found := false
+		for _, value := range values {
+			found = found || value == param.value
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
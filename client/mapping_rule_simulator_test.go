@@ -0,0 +1,167 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestMappingRuleSimulatorPatterns(t *testing.T) {
+	s := NewMappingRuleSimulator()
+	err := s.AddMappingRules("", []MappingRuleItem{
+		{ID: 1, MetricID: 10, HTTPMethod: "GET", Pattern: "/pets/{id}/toys$", Delta: 1, Position: 1},
+		{ID: 2, MetricID: 11, HTTPMethod: "GET", Pattern: "/pets", Delta: 1, Position: 2},
+		{ID: 3, MetricID: 12, HTTPMethod: "GET", Pattern: "/search?q={query}&type=dog", Delta: 3, Position: 3},
+		{ID: 4, MetricID: 13, HTTPMethod: "GET", Pattern: "/file.json$", Delta: 1, Position: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		method string
+		uri    string
+		rules  []int64
+	}{
+		{"GET", "/pets/12/toys", []int64{1, 2}},
+		{"get", "/pets/12/toys", []int64{1, 2}},
+		// end anchor
+		{"GET", "/pets/12/toys/1", []int64{2}},
+		// placeholders do not match slashes
+		{"GET", "/pets/12/34/toys", []int64{2}},
+		// prefix match
+		{"GET", "/petshop", []int64{2}},
+		{"POST", "/pets", []int64{}},
+		{"GET", "/search?type=dog&q=rex", []int64{3}},
+		{"GET", "/search?q=rex&type=cat", []int64{}},
+		{"GET", "/search?type=dog", []int64{}},
+		// dots are literal
+		{"GET", "/file.json", []int64{4}},
+		{"GET", "/fileXjson", []int64{}},
+	} {
+		match, err := s.Match(tc.method, tc.uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []int64{}
+		for _, rule := range match.Rules {
+			ids = append(ids, rule.ID)
+		}
+		equals(t, tc.rules, ids)
+	}
+}
+
+func TestMappingRuleSimulatorPositionAndLast(t *testing.T) {
+	s := NewMappingRuleSimulator()
+	err := s.AddMappingRules("", []MappingRuleItem{
+		{ID: 1, MetricID: 10, HTTPMethod: "GET", Pattern: "/", Delta: 1, Position: 3},
+		{ID: 2, MetricID: 11, HTTPMethod: "GET", Pattern: "/pets", Delta: 2, Position: 1, Last: true},
+		{ID: 3, MetricID: 10, HTTPMethod: "GET", Pattern: "/cats", Delta: 4, Position: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	match, err := s.Match("GET", "/pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 1, len(match.Rules))
+	equals(t, map[int64]int{11: 2}, match.Deltas)
+
+	// deltas of the same metric add up
+	match, err = s.Match("GET", "/cats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, int64(3), match.Rules[0].ID)
+	equals(t, int64(1), match.Rules[1].ID)
+	equals(t, map[int64]int{10: 5}, match.Deltas)
+}
+
+func TestMappingRuleSimulatorBackendPaths(t *testing.T) {
+	s := NewMappingRuleSimulator()
+	if err := s.AddMappingRules("", []MappingRuleItem{{ID: 1, MetricID: 10, HTTPMethod: "GET", Pattern: "/", Delta: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddMappingRules("/v1/", []MappingRuleItem{{ID: 2, MetricID: 20, HTTPMethod: "GET", Pattern: "/pets$", Delta: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddMappingRules("/", []MappingRuleItem{{ID: 3, MetricID: 30, HTTPMethod: "GET", Pattern: "/cats", Delta: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	match, err := s.Match("GET", "/v1/pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 2, len(match.Rules))
+	equals(t, "/v1/", match.Rules[1].PathPrefix)
+
+	match, err = s.Match("GET", "/pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 1, len(match.Rules))
+
+	match, err = s.Match("GET", "/cats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 2, len(match.Rules))
+}
+
+func TestMappingRuleSimulatorErrors(t *testing.T) {
+	s := NewMappingRuleSimulator()
+	if err := s.AddMappingRules("", []MappingRuleItem{{HTTPMethod: "GET", Pattern: "pets"}}); err == nil {
+		t.Fatal("expected error for pattern without leading slash")
+	}
+	if err := s.AddMappingRules("", []MappingRuleItem{{HTTPMethod: "GET", Pattern: "/pets("}}); err == nil {
+		t.Fatal("expected error for invalid pattern")
+	}
+	if _, err := s.Match("GET", "pets"); err == nil {
+		t.Fatal("expected error for relative uri")
+	}
+}
+
+func TestProductMappingRuleSimulator(t *testing.T) {
+	porta := petsPorta(t, true)
+	porta.load("pets_proxy_config_fixture.json")
+	c := porta.client()
+
+	s, err := c.ProductMappingRuleSimulator(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// backend rules are mounted at /v1
+	match, err := s.Match("GET", "/v1/pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, map[int64]int{23: 1}, match.Deltas)
+
+	// the product rule is flagged as last
+	match, err = s.Match("POST", "/adopt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, map[int64]int{12: 1}, match.Deltas)
+
+	// same results from the deployed proxy config
+	if _, err := c.DeployProductProxy(10); err != nil {
+		t.Fatal(err)
+	}
+	config, err := c.GetLatestProxyConfig("10", "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = NewProxyConfigSimulator(config.ProxyConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	match, err = s.Match("POST", "/adopt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "adopt", match.Rules[0].MetricSystemName)
+	equals(t, map[int64]int{12: 1}, match.Deltas)
+}
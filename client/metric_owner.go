@@ -0,0 +1,132 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// metricOwner gives uniform access to the metric, method and mapping rule
+// endpoints of products and backend apis
+type metricOwner struct {
+	c       *ThreeScaleClient
+	id      int64
+	backend bool
+}
+
+func productMetricOwner(c *ThreeScaleClient, productID int64) metricOwner {
+	return metricOwner{c: c, id: productID}
+}
+
+func backendMetricOwner(c *ThreeScaleClient, backendapiID int64) metricOwner {
+	return metricOwner{c: c, id: backendapiID, backend: true}
+}
+
+func (o metricOwner) String() string {
+	if o.backend {
+		return fmt.Sprintf("backend api %d", o.id)
+	}
+	return fmt.Sprintf("product %d", o.id)
+}
+
+// systemName returns the metric system name as known by the user.
+// 3scale appends the backend api ID to the system name of backend metrics and methods
+func (o metricOwner) systemName(remote string) string {
+	if o.backend {
+		return strings.TrimSuffix(remote, fmt.Sprintf(".%d", o.id))
+	}
+	return remote
+}
+
+// hitsID finds the hits metric in the given metric list
+func (o metricOwner) hitsID(list *MetricJSONList) (int64, error) {
+	for _, metric := range list.Metrics {
+		if o.systemName(metric.Element.SystemName) == "hits" {
+			return metric.Element.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("%s: hits metric not found", o)
+}
+
+func (o metricOwner) listMetrics() (*MetricJSONList, error) {
+	if o.backend {
+		return o.c.ListBackendapiMetrics(o.id)
+	}
+	return o.c.ListProductMetrics(o.id)
+}
+
+func (o metricOwner) createMetric(params Params) (*MetricJSON, error) {
+	if o.backend {
+		return o.c.CreateBackendApiMetric(o.id, params)
+	}
+	return o.c.CreateProductMetric(o.id, params)
+}
+
+func (o metricOwner) updateMetric(metricID int64, params Params) (*MetricJSON, error) {
+	if o.backend {
+		return o.c.UpdateBackendApiMetric(o.id, metricID, params)
+	}
+	return o.c.UpdateProductMetric(o.id, metricID, params)
+}
+
+func (o metricOwner) deleteMetric(metricID int64) error {
+	if o.backend {
+		return o.c.DeleteBackendApiMetric(o.id, metricID)
+	}
+	return o.c.DeleteProductMetric(o.id, metricID)
+}
+
+func (o metricOwner) listMethods(hitsID int64) (*MethodList, error) {
+	if o.backend {
+		return o.c.ListBackendapiMethods(o.id, hitsID)
+	}
+	return o.c.ListProductMethods(o.id, hitsID)
+}
+
+func (o metricOwner) createMethod(hitsID int64, params Params) (*Method, error) {
+	if o.backend {
+		return o.c.CreateBackendApiMethod(o.id, hitsID, params)
+	}
+	return o.c.CreateProductMethod(o.id, hitsID, params)
+}
+
+func (o metricOwner) updateMethod(hitsID, methodID int64, params Params) (*Method, error) {
+	if o.backend {
+		return o.c.UpdateBackendApiMethod(o.id, hitsID, methodID, params)
+	}
+	return o.c.UpdateProductMethod(o.id, hitsID, methodID, params)
+}
+
+func (o metricOwner) deleteMethod(hitsID, methodID int64) error {
+	if o.backend {
+		return o.c.DeleteBackendApiMethod(o.id, hitsID, methodID)
+	}
+	return o.c.DeleteProductMethod(o.id, hitsID, methodID)
+}
+
+func (o metricOwner) listMappingRules() (*MappingRuleJSONList, error) {
+	if o.backend {
+		return o.c.ListBackendapiMappingRules(o.id)
+	}
+	return o.c.ListProductMappingRules(o.id)
+}
+
+func (o metricOwner) createMappingRule(params Params) (*MappingRuleJSON, error) {
+	if o.backend {
+		return o.c.CreateBackendapiMappingRule(o.id, params)
+	}
+	return o.c.CreateProductMappingRule(o.id, params)
+}
+
+func (o metricOwner) updateMappingRule(mappingRuleID int64, params Params) (*MappingRuleJSON, error) {
+	if o.backend {
+		return o.c.UpdateBackendapiMappingRule(o.id, mappingRuleID, params)
+	}
+	return o.c.UpdateProductMappingRule(o.id, mappingRuleID, params)
+}
+
+func (o metricOwner) deleteMappingRule(mappingRuleID int64) error {
+	if o.backend {
+		return o.c.DeleteBackendapiMappingRule(o.id, mappingRuleID)
+	}
+	return o.c.DeleteProductMappingRule(o.id, mappingRuleID)
+}
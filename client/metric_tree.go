@@ -0,0 +1,241 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ProductMetricTree reads the metrics and methods of the product and returns them as a MetricTree
+func (c *ThreeScaleClient) ProductMetricTree(productID int64) (*MetricTree, error) {
+	return newMetricTree(productMetricOwner(c, productID))
+}
+
+// BackendApiMetricTree reads the metrics and methods of the backend api and returns them as a MetricTree
+func (c *ThreeScaleClient) BackendApiMetricTree(backendapiID int64) (*MetricTree, error) {
+	return newMetricTree(backendMetricOwner(c, backendapiID))
+}
+
+func newMetricTree(owner metricOwner) (*MetricTree, error) {
+	t := &MetricTree{owner: owner}
+	if err := t.Refresh(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Refresh reads the metrics and methods again, dropping the nodes held so far
+func (t *MetricTree) Refresh() error {
+	metrics, err := t.owner.listMetrics()
+	if err != nil {
+		return fmt.Errorf("%s: metrics: %w", t.owner, err)
+	}
+
+	hitsID, err := t.owner.hitsID(metrics)
+	if err != nil {
+		return err
+	}
+
+	methods, err := t.owner.listMethods(hitsID)
+	if err != nil {
+		return fmt.Errorf("%s: methods: %w", t.owner, err)
+	}
+
+	t.hits = nil
+	t.metrics = []*MetricNode{}
+	t.byID = map[int64]*MetricNode{}
+	t.bySystemName = map[string]*MetricNode{}
+
+	// methods are also listed as metrics
+	parents := map[int64]int64{}
+	for _, method := range methods.Methods {
+		parents[method.Element.ID] = method.Element.ParentID
+		if _, ok := t.byID[method.Element.ID]; !ok {
+			t.add(MetricItem{
+				ID:          method.Element.ID,
+				Name:        method.Element.Name,
+				SystemName:  method.Element.SystemName,
+				Description: method.Element.Description,
+				CreatedAt:   method.Element.CreatedAt,
+				UpdatedAt:   method.Element.UpdatedAt,
+			})
+		}
+	}
+	for _, metric := range metrics.Metrics {
+		if node, ok := t.byID[metric.Element.ID]; ok {
+			node.Item = metric.Element
+			continue
+		}
+		t.add(metric.Element)
+	}
+	t.hits = t.byID[hitsID]
+
+	// link children once every node is known, keeping the list order
+	for _, metric := range metrics.Metrics {
+		node := t.byID[metric.Element.ID]
+		if parentID, ok := parents[node.Item.ID]; ok {
+			t.link(node, parentID)
+		} else {
+			t.metrics = append(t.metrics, node)
+		}
+	}
+	for _, method := range methods.Methods {
+		if node := t.byID[method.Element.ID]; node.Parent == nil {
+			t.link(node, method.Element.ParentID)
+		}
+	}
+
+	return nil
+}
+
+func (t *MetricTree) add(item MetricItem) *MetricNode {
+	node := &MetricNode{Item: item, SystemName: t.owner.systemName(item.SystemName), Children: []*MetricNode{}}
+	t.byID[item.ID] = node
+	t.bySystemName[node.SystemName] = node
+	return node
+}
+
+func (t *MetricTree) link(node *MetricNode, parentID int64) {
+	parent, ok := t.byID[parentID]
+	if !ok {
+		parent = t.hits
+	}
+	node.Parent = parent
+	parent.Children = append(parent.Children, node)
+}
+
+// Hits returns the hits metric, parent of every method
+func (t *MetricTree) Hits() *MetricNode {
+	return t.hits
+}
+
+// Metrics returns the top level metrics, hits included
+func (t *MetricTree) Metrics() []*MetricNode {
+	return append([]*MetricNode{}, t.metrics...)
+}
+
+// Methods returns the methods, children of the hits metric
+func (t *MetricTree) Methods() []*MetricNode {
+	return append([]*MetricNode{}, t.hits.Children...)
+}
+
+// Node returns the metric or method with the given system name, with or without the backend api suffix.
+// Returns nil when not found
+func (t *MetricTree) Node(systemName string) *MetricNode {
+	if node, ok := t.bySystemName[t.owner.systemName(systemName)]; ok {
+		return node
+	}
+	return nil
+}
+
+// NodeByID returns the metric or method with the given ID. Returns nil when not found
+func (t *MetricTree) NodeByID(id int64) *MetricNode {
+	return t.byID[id]
+}
+
+// IsMethod tells whether the node is a method
+func (n *MetricNode) IsMethod() bool {
+	return n.Parent != nil
+}
+
+// Ancestors returns the parent of the node, its parent, and so on up to the top level metric
+func (n *MetricNode) Ancestors() []*MetricNode {
+	ancestors := []*MetricNode{}
+	for parent := n.Parent; parent != nil; parent = parent.Parent {
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// CreateMetric creates a top level metric and adds it to the tree.
+// params are the ones accepted by CreateProductMetric
+func (t *MetricTree) CreateMetric(params Params) (*MetricNode, error) {
+	if err := t.checkAvailable(params); err != nil {
+		return nil, err
+	}
+
+	metric, err := t.owner.createMetric(params)
+	if err != nil {
+		return nil, err
+	}
+
+	node := t.add(metric.Element)
+	t.metrics = append(t.metrics, node)
+	return node, nil
+}
+
+// CreateMethod creates a method under the hits metric and adds it to the tree.
+// params are the ones accepted by CreateProductMethod
+func (t *MetricTree) CreateMethod(params Params) (*MetricNode, error) {
+	if err := t.checkAvailable(params); err != nil {
+		return nil, err
+	}
+
+	method, err := t.owner.createMethod(t.hits.Item.ID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	node := t.add(MetricItem{
+		ID:          method.Element.ID,
+		Name:        method.Element.Name,
+		SystemName:  method.Element.SystemName,
+		Description: method.Element.Description,
+		CreatedAt:   method.Element.CreatedAt,
+		UpdatedAt:   method.Element.UpdatedAt,
+	})
+	t.link(node, t.hits.Item.ID)
+	return node, nil
+}
+
+func (t *MetricTree) checkAvailable(params Params) error {
+	if systemName, ok := params["system_name"]; ok && t.Node(systemName) != nil {
+		return fmt.Errorf("%s: system_name %q already taken", t.owner, systemName)
+	}
+	return nil
+}
+
+// Delete deletes the metric or method with the given system name and removes it from the tree.
+// The hits metric can not be deleted
+func (t *MetricTree) Delete(systemName string) error {
+	node := t.Node(systemName)
+	if node == nil {
+		return &SystemNameNotFoundErr{Kind: t.owner.String() + " metric", SystemName: systemName}
+	}
+	if node == t.hits {
+		return errors.New("hits metric can not be deleted")
+	}
+
+	var err error
+	if node.IsMethod() {
+		err = t.owner.deleteMethod(node.Parent.Item.ID, node.Item.ID)
+	} else {
+		err = t.owner.deleteMetric(node.Item.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	t.remove(node)
+	return nil
+}
+
+// remove drops the node and its descendants from the tree
+func (t *MetricTree) remove(node *MetricNode) {
+	// removing a child shrinks node.Children, walk a copy
+	for _, child := range append([]*MetricNode{}, node.Children...) {
+		t.remove(child)
+	}
+	delete(t.byID, node.Item.ID)
+	delete(t.bySystemName, node.SystemName)
+
+	siblings := &t.metrics
+	if node.Parent != nil {
+		siblings = &node.Parent.Children
+	}
+	for idx, sibling := range *siblings {
+		if sibling == node {
+			*siblings = append((*siblings)[:idx], (*siblings)[idx+1:]...)
+			break
+		}
+	}
+}
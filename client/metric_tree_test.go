@@ -0,0 +1,155 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestProductMetricTree(t *testing.T) {
+	tree, err := petsPorta(t, true).client().ProductMetricTree(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits := tree.Hits()
+	equals(t, int64(11), hits.Item.ID)
+	equals(t, false, hits.IsMethod())
+	equals(t, 1, len(tree.Metrics()))
+
+	adopt := tree.Node("adopt")
+	if adopt == nil {
+		t.Fatal("expected adopt method")
+	}
+	equals(t, true, adopt.IsMethod())
+	equals(t, hits, adopt.Parent)
+	equals(t, []*MetricNode{hits}, adopt.Ancestors())
+	equals(t, []*MetricNode{adopt}, tree.Methods())
+	equals(t, adopt, tree.NodeByID(12))
+
+	if tree.Node("unknown") != nil {
+		t.Fatal("expected nil node")
+	}
+}
+
+func TestBackendApiMetricTree(t *testing.T) {
+	tree, err := petsPorta(t, true).client().BackendApiMetricTree(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// system names are known without the backend suffix
+	storage := tree.Node("storage")
+	if storage == nil {
+		t.Fatal("expected storage metric")
+	}
+	equals(t, "storage.20", storage.Item.SystemName)
+	equals(t, storage, tree.Node(storage.Item.SystemName))
+	equals(t, "MB", storage.Item.Unit)
+	equals(t, false, storage.IsMethod())
+	equals(t, 2, len(tree.Metrics()))
+
+	listPets := tree.Node("list_pets")
+	equals(t, tree.Hits(), listPets.Parent)
+}
+
+func TestMetricTreeCreateDelete(t *testing.T) {
+	porta := petsPorta(t, true)
+	porta.reply(http.MethodPost, "/admin/api/services/10/metrics/11/methods.json", http.StatusCreated, Method{
+		Element: MethodItem{ID: 14, Name: "Feed", SystemName: "feed", ParentID: 11},
+	})
+	porta.reply(http.MethodPost, "/admin/api/services/10/metrics.json", http.StatusCreated, MetricJSON{
+		Element: MetricItem{ID: 16, Name: "Bytes", SystemName: "bytes", Unit: "byte"},
+	})
+	porta.reply(http.MethodDelete, "/admin/api/services/10/metrics/11/methods/14.json", http.StatusOK, nil)
+	porta.reply(http.MethodDelete, "/admin/api/services/10/metrics/16.json", http.StatusOK, nil)
+
+	tree, err := porta.client().ProductMetricTree(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feed, err := tree.CreateMethod(Params{"friendly_name": "Feed", "system_name": "feed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, tree.Hits(), feed.Parent)
+	equals(t, 2, len(tree.Methods()))
+
+	bytes, err := tree.CreateMetric(Params{"friendly_name": "Bytes", "system_name": "bytes", "unit": "byte"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 2, len(tree.Metrics()))
+	equals(t, bytes, tree.Node("bytes"))
+
+	// taken system names are rejected before any request
+	if _, err := tree.CreateMethod(Params{"friendly_name": "Feed", "system_name": "feed"}); err == nil {
+		t.Fatal("expected error creating a duplicated method")
+	}
+	equals(t, []string{
+		"POST /admin/api/services/10/metrics/11/methods.json",
+		"POST /admin/api/services/10/metrics.json",
+	}, porta.writes())
+	equals(t, "feed", porta.served(http.MethodPost)[0].Params.Get("system_name"))
+
+	if err := tree.Delete("feed"); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 1, len(tree.Methods()))
+	if tree.Node("feed") != nil {
+		t.Fatal("expected feed to be removed from the tree")
+	}
+
+	if err := tree.Delete("bytes"); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 1, len(tree.Metrics()))
+
+	if err := tree.Delete("hits"); err == nil {
+		t.Fatal("expected error deleting hits")
+	}
+	if err := tree.Delete("unknown"); !IsNotFound(err) {
+		t.Fatalf("expected not found error; got %v", err)
+	}
+	equals(t, []string{
+		"DELETE /admin/api/services/10/metrics/11/methods/14.json",
+		"DELETE /admin/api/services/10/metrics/16.json",
+	}, porta.writes()[2:])
+
+	// the tree matches a fresh read
+	if err := tree.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 1, len(tree.Metrics()))
+	equals(t, 1, len(tree.Methods()))
+}
+
+func TestMetricTreeRemoveChildren(t *testing.T) {
+	porta := petsPorta(t, true)
+	porta.reply(http.MethodGet, "/admin/api/services/10/metrics/11/methods.json", http.StatusOK, MethodList{Methods: []Method{
+		{Element: MethodItem{ID: 12, SystemName: "adopt", ParentID: 11}},
+		{Element: MethodItem{ID: 14, SystemName: "feed", ParentID: 11}},
+		{Element: MethodItem{ID: 16, SystemName: "walk", ParentID: 11}},
+	}})
+
+	tree, err := porta.client().ProductMetricTree(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := tree.Hits()
+	equals(t, 3, len(hits.Children))
+
+	tree.remove(hits)
+	for _, id := range []int64{11, 12, 14, 16} {
+		if tree.NodeByID(id) != nil {
+			t.Fatalf("expected node %d to be removed", id)
+		}
+	}
+	for _, systemName := range []string{"hits", "adopt", "feed", "walk"} {
+		if tree.Node(systemName) != nil {
+			t.Fatalf("expected node %s to be removed", systemName)
+		}
+	}
+	equals(t, []*MetricNode{}, hits.Children)
+	equals(t, []*MetricNode{}, tree.Metrics())
+}
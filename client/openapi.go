@@ -0,0 +1,257 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HTTP methods of OpenAPI path items, in the order operations are generated
+var openAPIHTTPMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+var openAPISystemNameInvalid = regexp.MustCompile(`[^a-z0-9_]+`)
+
+type openAPIDocument struct {
+	Swagger string `json:"swagger"`
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	} `json:"info"`
+	// OpenAPI 2 location of the API
+	Host     string   `json:"host"`
+	BasePath string   `json:"basePath"`
+	Schemes  []string `json:"schemes"`
+	// OpenAPI 3 location of the API
+	Servers []openAPIServer                       `json:"servers"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+
+	Security            []map[string][]string            `json:"security"`
+	SecurityDefinitions map[string]openAPISecurityScheme `json:"securityDefinitions"`
+	Components          struct {
+		SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+	} `json:"components"`
+}
+
+type openAPISecurityScheme struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	In     string `json:"in"`
+	Scheme string `json:"scheme"`
+}
+
+type openAPIServer struct {
+	URL       string `json:"url"`
+	Variables map[string]struct {
+		Default string `json:"default"`
+	} `json:"variables"`
+}
+
+type openAPIOperation struct {
+	OperationID string `json:"operationId"`
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+}
+
+func parseOpenAPIDocument(spec []byte) (*openAPIDocument, error) {
+	doc := &openAPIDocument{}
+	if err := json.Unmarshal(spec, doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	if doc.Swagger != "2.0" && !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI document: expected swagger 2.0 or openapi 3.x")
+	}
+	return doc, nil
+}
+
+// basePath returns the basePath of OpenAPI 2 documents or the path of the first server of OpenAPI 3
+// documents, without trailing slash
+func (d *openAPIDocument) basePath() string {
+	if d.Swagger != "" {
+		return strings.TrimSuffix(d.BasePath, "/")
+	}
+	if len(d.Servers) == 0 {
+		return ""
+	}
+
+	u, err := url.Parse(d.Servers[0].expandURL())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// expandURL replaces the server variables with their default values
+func (s openAPIServer) expandURL() string {
+	u := s.URL
+	for name, variable := range s.Variables {
+		u = strings.Replace(u, "{"+name+"}", variable.Default, -1)
+	}
+	return u
+}
+
+// ParseOpenAPIOperations reads the operations of an OpenAPI 2 or 3 document in JSON, the body of active docs,
+// and generates a method and a mapping rule for each one.
+// Method system names come from the operationId, or from the HTTP method and the path when missing.
+// Patterns are the path templates, which use the same {placeholder} syntax, prefixed with the base path
+func ParseOpenAPIOperations(spec []byte, opts OpenAPIImportOptions) ([]OpenAPIOperation, error) {
+	doc, err := parseOpenAPIDocument(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	basePath := doc.basePath()
+	if opts.PublicBasePath != "" {
+		basePath = strings.TrimSuffix(opts.PublicBasePath, "/")
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	operations := []OpenAPIOperation{}
+	systemNames := map[string]bool{}
+	for _, path := range paths {
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("path %q must start with /", path)
+		}
+
+		for _, method := range openAPIHTTPMethods {
+			raw, ok := doc.Paths[path][method]
+			if !ok {
+				continue
+			}
+			op := &openAPIOperation{}
+			if err := json.Unmarshal(raw, op); err != nil {
+				return nil, fmt.Errorf("operation %s %s: %w", strings.ToUpper(method), path, err)
+			}
+
+			operation := OpenAPIOperation{
+				HTTPMethod:   strings.ToUpper(method),
+				Path:         path,
+				OperationID:  op.OperationID,
+				FriendlyName: op.OperationID,
+				Description:  op.Summary,
+				Pattern:      basePath + path,
+			}
+			if operation.FriendlyName == "" {
+				operation.FriendlyName = operation.HTTPMethod + " " + path
+			}
+			if operation.Description == "" {
+				operation.Description = op.Description
+			}
+			if !opts.PrefixMatching {
+				operation.Pattern += "$"
+			}
+
+			operation.SystemName = openAPISystemName(operation.FriendlyName)
+			for suffix := 2; systemNames[operation.SystemName]; suffix++ {
+				operation.SystemName = fmt.Sprintf("%s_%d", openAPISystemName(operation.FriendlyName), suffix)
+			}
+			systemNames[operation.SystemName] = true
+
+			operations = append(operations, operation)
+		}
+	}
+
+	return operations, nil
+}
+
+func openAPISystemName(name string) string {
+	return strings.Trim(openAPISystemNameInvalid.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// ImportProductOpenAPI creates or updates a method under hits and a mapping rule for every operation of the
+// OpenAPI document on the product. See ParseOpenAPIOperations
+func (c *ThreeScaleClient) ImportProductOpenAPI(productID int64, spec []byte, opts OpenAPIImportOptions) (*OpenAPIImportResult, error) {
+	operations, err := ParseOpenAPIOperations(spec, opts)
+	if err != nil {
+		return nil, err
+	}
+	return productMetricOwner(c, productID).importOpenAPIOperations(operations)
+}
+
+// ImportBackendApiOpenAPI creates or updates a method under hits and a mapping rule for every operation of the
+// OpenAPI document on the backend api. See ParseOpenAPIOperations
+func (c *ThreeScaleClient) ImportBackendApiOpenAPI(backendapiID int64, spec []byte, opts OpenAPIImportOptions) (*OpenAPIImportResult, error) {
+	operations, err := ParseOpenAPIOperations(spec, opts)
+	if err != nil {
+		return nil, err
+	}
+	return backendMetricOwner(c, backendapiID).importOpenAPIOperations(operations)
+}
+
+func (o metricOwner) importOpenAPIOperations(operations []OpenAPIOperation) (*OpenAPIImportResult, error) {
+	metrics, err := o.listMetrics()
+	if err != nil {
+		return nil, fmt.Errorf("%s: metrics: %w", o, err)
+	}
+	hitsID, err := o.hitsID(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	methods, err := o.listMethods(hitsID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: methods: %w", o, err)
+	}
+	current := map[string]MethodItem{}
+	for _, method := range methods.Methods {
+		current[o.systemName(method.Element.SystemName)] = method.Element
+	}
+
+	rules, err := o.listMappingRules()
+	if err != nil {
+		return nil, fmt.Errorf("%s: mapping rules: %w", o, err)
+	}
+
+	result := &OpenAPIImportResult{
+		Operations:     operations,
+		MethodIDs:      map[string]int64{},
+		MappingRuleIDs: map[string]int64{},
+	}
+
+	for _, operation := range operations {
+		params := Params{"friendly_name": operation.FriendlyName, "description": operation.Description}
+
+		method, ok := current[operation.SystemName]
+		if !ok {
+			params["system_name"] = operation.SystemName
+			created, err := o.createMethod(hitsID, params)
+			if err != nil {
+				return result, fmt.Errorf("%s: create method %s: %w", o, operation.SystemName, err)
+			}
+			method = created.Element
+			result.Changed++
+		} else if changed := changedParams(params, jsonParams(method)); len(changed) > 0 {
+			updated, err := o.updateMethod(hitsID, method.ID, changed)
+			if err != nil {
+				return result, fmt.Errorf("%s: update method %s: %w", o, operation.SystemName, err)
+			}
+			method = updated.Element
+			result.Changed++
+		}
+		current[operation.SystemName] = method
+		result.MethodIDs[operation.SystemName] = method.ID
+
+		rule, changed, err := o.ensureListedMappingRule(rules, operation.HTTPMethod, operation.Pattern, Params{
+			"metric_id": strconv.FormatInt(method.ID, 10),
+			"delta":     "1",
+		})
+		if err != nil {
+			return result, fmt.Errorf("%s: mapping rule %s %s: %w", o, operation.HTTPMethod, operation.Pattern, err)
+		}
+		if changed {
+			result.Changed++
+		}
+		result.MappingRuleIDs[mappingRuleKey(operation.HTTPMethod, operation.Pattern)] = rule.Element.ID
+	}
+
+	return result, nil
+}
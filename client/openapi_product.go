@@ -0,0 +1,226 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ImportOpenAPI creates or updates a product from an OpenAPI 2 or 3 document in JSON, the same way
+// ImportProduct does with the bundle returned by OpenAPIProductBundle.
+// Re-running it with an updated document updates the resources; operations removed from the document
+// keep their methods and mapping rules, use ReconcileProduct with Prune to delete them
+func (c *ThreeScaleClient) ImportOpenAPI(spec []byte, opts OpenAPIProductOptions) (*ProductImportResult, error) {
+	bundle, err := OpenAPIProductBundle(spec, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.ImportProduct(bundle)
+}
+
+// OpenAPIProductBundle describes the product of an OpenAPI document:
+//   - the product, named after info.title, with a method and a mapping rule per operation
+//   - a backend api, used at /, whose private endpoint is the host of the document or of its first server
+//   - the proxy authentication matching the first security requirement of the document
+//   - an activedoc bound to the product with the document as body
+//   - a published default application plan
+func OpenAPIProductBundle(spec []byte, opts OpenAPIProductOptions) (*ProductBundle, error) {
+	doc, err := parseOpenAPIDocument(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	operations, err := ParseOpenAPIOperations(spec, opts.OpenAPIImportOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Info.Title == "" {
+		return nil, fmt.Errorf("info.title is required")
+	}
+	systemName := opts.SystemName
+	if systemName == "" {
+		systemName = openAPISystemName(doc.Info.Title)
+	}
+
+	privateEndpoint := opts.PrivateEndpoint
+	if privateEndpoint == "" {
+		privateEndpoint = doc.privateEndpoint()
+	}
+	if privateEndpoint == "" {
+		return nil, fmt.Errorf("the document declares no host nor server, a private endpoint is required")
+	}
+
+	auth, err := doc.authentication()
+	if err != nil {
+		return nil, err
+	}
+	if auth.backendVersion == BackendVersionOIDC && opts.OIDCIssuerEndpoint != "" {
+		auth.proxy["oidc_issuer_endpoint"] = opts.OIDCIssuerEndpoint
+	}
+
+	planSystemName := opts.ApplicationPlan
+	if planSystemName == "" {
+		planSystemName = "default"
+	}
+
+	backend := BackendApiSpec{
+		SystemName:      systemName + "_backend",
+		Name:            doc.Info.Title + " Backend",
+		PrivateEndpoint: privateEndpoint,
+	}
+
+	product := ProductSpec{
+		SystemName:     systemName,
+		Name:           doc.Info.Title,
+		Description:    doc.Info.Description,
+		BackendVersion: auth.backendVersion,
+		Proxy:          auth.proxy,
+		Methods:        []MethodSpec{},
+		MappingRules:   []MappingRuleSpec{},
+		BackendUsages:  []BackendUsageSpec{{BackendSystemName: backend.SystemName, Path: "/"}},
+		ApplicationPlans: []ApplicationPlanSpec{
+			{SystemName: planSystemName, Name: "Default", Published: true},
+		},
+		ActiveDocs: []ActiveDocSpec{
+			{
+				SystemName:  systemName,
+				Name:        doc.Info.Title,
+				Description: doc.Info.Description,
+				Published:   opts.PublishActiveDoc,
+				Body:        string(spec),
+			},
+		},
+	}
+	for _, operation := range operations {
+		product.Methods = append(product.Methods, MethodSpec{
+			SystemName:  operation.SystemName,
+			Name:        operation.FriendlyName,
+			Description: operation.Description,
+		})
+		product.MappingRules = append(product.MappingRules, MappingRuleSpec{
+			HTTPMethod: operation.HTTPMethod,
+			Pattern:    operation.Pattern,
+			MetricRef:  operation.SystemName,
+			Delta:      1,
+		})
+	}
+
+	return &ProductBundle{Product: product, Backends: []BackendApiSpec{backend}}, nil
+}
+
+// privateEndpoint returns the scheme and host the API is served from. The base path is not included as
+// mapping rule patterns already hold it and APIcast sends the request path as is
+func (d *openAPIDocument) privateEndpoint() string {
+	if d.Swagger != "" {
+		if d.Host == "" {
+			return ""
+		}
+		scheme := "https"
+		if len(d.Schemes) > 0 {
+			scheme = d.Schemes[0]
+		}
+		return scheme + "://" + d.Host
+	}
+
+	if len(d.Servers) == 0 {
+		return ""
+	}
+	u, err := url.Parse(d.Servers[0].expandURL())
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+type openAPIAuthentication struct {
+	backendVersion string
+	proxy          Params
+}
+
+// authentication maps the schemes of the first security requirement to the proxy authentication:
+// an API key is the user key, two API keys or HTTP basic are the app ID and the app key, and OAuth2,
+// OpenID Connect or HTTP bearer tokens are OpenID Connect.
+// Documents without security requirements leave the authentication untouched
+func (d *openAPIDocument) authentication() (*openAPIAuthentication, error) {
+	if len(d.Security) == 0 || len(d.Security[0]) == 0 {
+		return &openAPIAuthentication{}, nil
+	}
+
+	definitions := d.SecurityDefinitions
+	if d.Swagger == "" {
+		definitions = d.Components.SecuritySchemes
+	}
+
+	names := make([]string, 0, len(d.Security[0]))
+	for name := range d.Security[0] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	schemes := make([]openAPISecurityScheme, 0, len(names))
+	for _, name := range names {
+		scheme, ok := definitions[name]
+		if !ok {
+			return nil, fmt.Errorf("security scheme %s is not defined", name)
+		}
+		schemes = append(schemes, scheme)
+	}
+
+	first := schemes[0]
+	switch {
+	case len(schemes) == 1 && first.Type == "apiKey":
+		location, err := openAPICredentialsLocation(first)
+		if err != nil {
+			return nil, err
+		}
+		return &openAPIAuthentication{
+			backendVersion: BackendVersionUserKey,
+			proxy:          Params{"credentials_location": location, "auth_user_key": first.Name},
+		}, nil
+
+	case len(schemes) == 2 && first.Type == "apiKey" && schemes[1].Type == "apiKey":
+		location, err := openAPICredentialsLocation(first)
+		if err != nil {
+			return nil, err
+		}
+		if second, _ := openAPICredentialsLocation(schemes[1]); second != location {
+			return nil, fmt.Errorf("app id and app key security schemes must be in the same location")
+		}
+		appID, appKey := first, schemes[1]
+		if strings.Contains(strings.ToLower(appID.Name), "key") {
+			appID, appKey = appKey, appID
+		}
+		return &openAPIAuthentication{
+			backendVersion: BackendVersionAppIDAppKey,
+			proxy:          Params{"credentials_location": location, "auth_app_id": appID.Name, "auth_app_key": appKey.Name},
+		}, nil
+
+	case len(schemes) == 1 && first.Type == "basic",
+		len(schemes) == 1 && first.Type == "http" && strings.EqualFold(first.Scheme, "basic"):
+		return &openAPIAuthentication{
+			backendVersion: BackendVersionAppIDAppKey,
+			proxy:          Params{"credentials_location": "authorization"},
+		}, nil
+
+	case len(schemes) == 1 && (first.Type == "oauth2" || first.Type == "openIdConnect"),
+		len(schemes) == 1 && first.Type == "http" && strings.EqualFold(first.Scheme, "bearer"):
+		return &openAPIAuthentication{
+			backendVersion: BackendVersionOIDC,
+			proxy:          Params{"credentials_location": "headers"},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported security requirement %s", strings.Join(names, ", "))
+}
+
+func openAPICredentialsLocation(scheme openAPISecurityScheme) (string, error) {
+	switch scheme.In {
+	case "header":
+		return "headers", nil
+	case "query":
+		return "query", nil
+	}
+	return "", fmt.Errorf("unsupported API key location %q", scheme.In)
+}
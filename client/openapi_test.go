@@ -0,0 +1,196 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+const testOpenAPI3 = `{
+  "openapi": "3.0.2",
+  "info": {"title": "Pets", "version": "1.0.0"},
+  "servers": [{"url": "https://{host}/{basePath}", "variables": {"host": {"default": "pets.example.com"}, "basePath": {"default": "v1"}}}],
+  "paths": {
+    "/pets": {
+      "parameters": [],
+      "get": {"operationId": "listPets", "summary": "List pets"},
+      "post": {"operationId": "createPet", "description": "Create a pet"}
+    },
+    "/pets/{petId}": {
+      "get": {"summary": "Show a pet"}
+    }
+  }
+}`
+
+const testOpenAPI2 = `{
+  "swagger": "2.0",
+  "info": {"title": "Pets", "version": "1.0.0"},
+  "basePath": "/v2/",
+  "paths": {
+    "/pets/{petId}": {"delete": {"operationId": "deletePet"}}
+  }
+}`
+
+func TestParseOpenAPIOperations(t *testing.T) {
+	operations, err := ParseOpenAPIOperations([]byte(testOpenAPI3), OpenAPIImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []OpenAPIOperation{
+		{HTTPMethod: "GET", Path: "/pets", OperationID: "listPets", SystemName: "listpets", FriendlyName: "listPets", Description: "List pets", Pattern: "/v1/pets$"},
+		{HTTPMethod: "POST", Path: "/pets", OperationID: "createPet", SystemName: "createpet", FriendlyName: "createPet", Description: "Create a pet", Pattern: "/v1/pets$"},
+		{HTTPMethod: "GET", Path: "/pets/{petId}", SystemName: "get_pets_petid", FriendlyName: "GET /pets/{petId}", Description: "Show a pet", Pattern: "/v1/pets/{petId}$"},
+	}, operations)
+
+	operations, err = ParseOpenAPIOperations([]byte(testOpenAPI2), OpenAPIImportOptions{PrefixMatching: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "/v2/pets/{petId}", operations[0].Pattern)
+
+	operations, err = ParseOpenAPIOperations([]byte(testOpenAPI2), OpenAPIImportOptions{PublicBasePath: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "/pets/{petId}$", operations[0].Pattern)
+}
+
+func TestParseOpenAPIOperationsErrors(t *testing.T) {
+	for _, spec := range []string{
+		`not json`,
+		`{"openapi": "4.0"}`,
+		`{"swagger": "2.0", "paths": {"pets": {"get": {}}}}`,
+	} {
+		if _, err := ParseOpenAPIOperations([]byte(spec), OpenAPIImportOptions{}); err == nil {
+			t.Fatalf("expected error for %s", spec)
+		}
+	}
+}
+
+// openAPIPorta serves the hits metric of an owner without methods and mapping rules at the given paths.
+// Created methods echo the params. See mappingRulesPorta
+func openAPIPorta(t *testing.T, metricsPath, rulesPath string) *mockPorta {
+	porta := mappingRulesPorta(t, rulesPath)
+	porta.reply(http.MethodGet, metricsPath+".json", http.StatusOK, MetricJSONList{Metrics: []MetricJSON{
+		{Element: MetricItem{ID: 11, Name: "Hits", SystemName: "hits", Unit: "hit"}},
+	}})
+	methodsPath := metricsPath + "/11/methods.json"
+	porta.reply(http.MethodGet, methodsPath, http.StatusOK, MethodList{Methods: []Method{}})
+
+	nextID := int64(200)
+	porta.handle(http.MethodPost, methodsPath, func(req *http.Request) *http.Response {
+		if err := req.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		method := MethodItem{
+			ID:          nextID,
+			Name:        req.PostForm.Get("friendly_name"),
+			SystemName:  req.PostForm.Get("system_name"),
+			Description: req.PostForm.Get("description"),
+			ParentID:    11,
+		}
+		nextID++
+		return helperJSONResponse(t, http.StatusCreated, Method{Element: method})
+	})
+	return porta
+}
+
+func TestImportProductOpenAPI(t *testing.T) {
+	rulesPath := "/admin/api/services/10/proxy/mapping_rules"
+	porta := openAPIPorta(t, "/admin/api/services/10/metrics", rulesPath)
+	c := porta.client()
+
+	result, err := c.ImportProductOpenAPI(10, []byte(testOpenAPI3), OpenAPIImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 6, result.Changed)
+	equals(t, map[string]int64{"listpets": 200, "createpet": 201, "get_pets_petid": 202}, result.MethodIDs)
+	equals(t, map[string]int64{"GET /v1/pets$": 100, "POST /v1/pets$": 101, "GET /v1/pets/{petId}$": 102}, result.MappingRuleIDs)
+
+	methods := porta.calls(http.MethodPost, "/admin/api/services/10/metrics/11/methods.json")
+	equals(t, "createpet", methods[1].Params.Get("system_name"))
+	equals(t, "Create a pet", methods[1].Params.Get("description"))
+	rules := porta.calls(http.MethodPost, rulesPath+".json")
+	equals(t, "POST", rules[1].Params.Get("http_method"))
+	equals(t, "/v1/pets$", rules[1].Params.Get("pattern"))
+	equals(t, "201", rules[1].Params.Get("metric_id"))
+	// mapping rules are listed once
+	equals(t, 1, len(porta.calls(http.MethodGet, rulesPath+".json")))
+
+	// applying the same document again changes nothing
+	porta.reply(http.MethodGet, "/admin/api/services/10/metrics/11/methods.json", http.StatusOK, MethodList{Methods: []Method{
+		{Element: MethodItem{ID: 200, Name: "listPets", SystemName: "listpets", Description: "List pets", ParentID: 11}},
+		{Element: MethodItem{ID: 201, Name: "createPet", SystemName: "createpet", Description: "Create a pet", ParentID: 11}},
+		{Element: MethodItem{ID: 202, Name: "GET /pets/{petId}", SystemName: "get_pets_petid", Description: "Show a pet", ParentID: 11}},
+	}})
+	porta.reply(http.MethodGet, rulesPath+".json", http.StatusOK, MappingRuleJSONList{MappingRules: []MappingRuleJSON{
+		{Element: MappingRuleItem{ID: 100, MetricID: 200, HTTPMethod: "GET", Pattern: "/v1/pets$", Delta: 1, Position: 1}},
+		{Element: MappingRuleItem{ID: 101, MetricID: 201, HTTPMethod: "POST", Pattern: "/v1/pets$", Delta: 1, Position: 2}},
+		{Element: MappingRuleItem{ID: 102, MetricID: 202, HTTPMethod: "GET", Pattern: "/v1/pets/{petId}$", Delta: 1, Position: 3}},
+	}})
+	porta.reset()
+	result, err = c.ImportProductOpenAPI(10, []byte(testOpenAPI3), OpenAPIImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 0, result.Changed)
+	equals(t, []string{}, porta.writes())
+	equals(t, 1, len(porta.calls(http.MethodGet, rulesPath+".json")))
+}
+
+func TestImportOpenAPIRepeatedOperation(t *testing.T) {
+	rulesPath := "/admin/api/services/10/proxy/mapping_rules"
+	porta := openAPIPorta(t, "/admin/api/services/10/metrics", rulesPath)
+
+	// the rule created for the first operation is known when the second one is imported
+	operations := []OpenAPIOperation{
+		{HTTPMethod: "GET", Pattern: "/pets$", SystemName: "list", FriendlyName: "list"},
+		{HTTPMethod: "GET", Pattern: "/pets$", SystemName: "list_again", FriendlyName: "list again"},
+	}
+	result, err := productMetricOwner(porta.client(), 10).importOpenAPIOperations(operations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 4, result.Changed)
+	equals(t, []string{
+		"POST /admin/api/services/10/metrics/11/methods.json",
+		"POST " + rulesPath + ".json",
+		"POST /admin/api/services/10/metrics/11/methods.json",
+		"PUT " + rulesPath + "/100.json",
+	}, porta.writes())
+	equals(t, "201", porta.calls(http.MethodPut, rulesPath+"/100.json")[0].Params.Get("metric_id"))
+	equals(t, 1, len(porta.calls(http.MethodGet, rulesPath+".json")))
+}
+
+func TestImportBackendApiOpenAPI(t *testing.T) {
+	rulesPath := "/admin/api/backend_apis/20/mapping_rules"
+	porta := openAPIPorta(t, "/admin/api/backend_apis/20/metrics", rulesPath)
+	c := porta.client()
+
+	result, err := c.ImportBackendApiOpenAPI(20, []byte(testOpenAPI2), OpenAPIImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 2, result.Changed)
+	equals(t, []string{
+		"POST /admin/api/backend_apis/20/metrics/11/methods.json",
+		"POST " + rulesPath + ".json",
+	}, porta.writes())
+	rule := porta.calls(http.MethodPost, rulesPath+".json")[0].Params
+	equals(t, "DELETE", rule.Get("http_method"))
+	equals(t, "/v2/pets/{petId}$", rule.Get("pattern"))
+	equals(t, strconv.FormatInt(result.MethodIDs["deletepet"], 10), rule.Get("metric_id"))
+
+	porta.reply(http.MethodGet, "/admin/api/backend_apis/20/metrics/11/methods.json", http.StatusOK, MethodList{Methods: []Method{
+		{Element: MethodItem{ID: 200, Name: "deletePet", SystemName: "deletepet.20", ParentID: 11}},
+	}})
+	porta.reply(http.MethodGet, rulesPath+".json", http.StatusOK, MappingRuleJSONList{MappingRules: []MappingRuleJSON{
+		{Element: MappingRuleItem{ID: 100, MetricID: 200, HTTPMethod: "DELETE", Pattern: "/v2/pets/{petId}$", Delta: 1, Position: 1}},
+	}})
+	result, err = c.ImportBackendApiOpenAPI(20, []byte(testOpenAPI2), OpenAPIImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 0, result.Changed)
+}
@@ -15,20 +15,24 @@ const (
 
 // ListAPIcastPolicies List existing apicast policies in the registry for the client provider account
 func (c *ThreeScaleClient) ListAPIcastPolicies() (*APIcastPolicyRegistry, error) {
+	obj := &APIcastPolicyRegistry{}
+	err := c.listAPIcastPolicies(obj)
+	return obj, err
+}
+
+func (c *ThreeScaleClient) listAPIcastPolicies(obj interface{}) error {
 	req, err := c.buildGetReq(apicastPolicyRegistryEndpoint)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
-	obj := &APIcastPolicyRegistry{}
-	err = handleJsonResp(resp, http.StatusOK, obj)
-	return obj, err
+	return handleJsonResp(resp, http.StatusOK, obj)
 }
 
 // ReadAPIcastPolicy Reads 3scale apicast policy from registry
@@ -53,26 +57,30 @@ func (c *ThreeScaleClient) ReadAPIcastPolicy(id int64) (*APIcastPolicy, error) {
 
 // CreateAPIcastPolicy Create 3scale apicast policy in the registry
 func (c *ThreeScaleClient) CreateAPIcastPolicy(item *APIcastPolicy) (*APIcastPolicy, error) {
-	bodyArr, err := json.Marshal(item.Element)
+	respObj := &APIcastPolicy{}
+	err := c.createAPIcastPolicy(item.Element, respObj)
+	return respObj, err
+}
+
+func (c *ThreeScaleClient) createAPIcastPolicy(element interface{}, respObj interface{}) error {
+	bodyArr, err := json.Marshal(element)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	body := bytes.NewReader(bodyArr)
 
 	req, err := c.buildPostJSONReq(apicastPolicyRegistryEndpoint, body)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
-	respObj := &APIcastPolicy{}
-	err = handleJsonResp(resp, http.StatusCreated, respObj)
-	return respObj, err
+	return handleJsonResp(resp, http.StatusCreated, respObj)
 }
 
 // UpdateAPIcastPolicy Update existing apicast policy in the registry
@@ -85,28 +93,32 @@ func (c *ThreeScaleClient) UpdateAPIcastPolicy(item *APIcastPolicy) (*APIcastPol
 		return nil, errors.New("UpdateAPIcastPolicy needs not nil ID")
 	}
 
-	endpoint := fmt.Sprintf(apicastPolicyEndpoint, *item.Element.ID)
+	respObj := &APIcastPolicy{}
+	err := c.updateAPIcastPolicy(*item.Element.ID, item.Element, respObj)
+	return respObj, err
+}
+
+func (c *ThreeScaleClient) updateAPIcastPolicy(id int64, element interface{}, respObj interface{}) error {
+	endpoint := fmt.Sprintf(apicastPolicyEndpoint, id)
 
-	bodyArr, err := json.Marshal(item.Element)
+	bodyArr, err := json.Marshal(element)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	body := bytes.NewReader(bodyArr)
 
 	req, err := c.buildUpdateJSONReq(endpoint, body)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
-	respObj := &APIcastPolicy{}
-	err = handleJsonResp(resp, http.StatusOK, respObj)
-	return respObj, err
+	return handleJsonResp(resp, http.StatusOK, respObj)
 }
 
 // DeleteAPIcastPolicy Delete existing apicast policy in the registry
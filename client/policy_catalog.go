@@ -0,0 +1,244 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const policiesListEndpoint = "/admin/api/policies.json"
+
+// numeric identifiers of semantic versions
+var policyVersionNumber = regexp.MustCompile(`^\d+$`)
+
+// ListBuiltinPolicies returns the manifests of the policies shipped with the gateway and available to the
+// tenant, the custom policies of the registry left out, sorted by name and version
+func (c *ThreeScaleClient) ListBuiltinPolicies() ([]APIcastPolicySchema, error) {
+	schemas, err := c.listPolicySchemas()
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := c.ListAPIcastPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("policy registry: %w", err)
+	}
+	custom := map[string]bool{}
+	for _, policy := range registry.Items {
+		custom[policySchemaKey(stringValue(policy.Element.Name), stringValue(policy.Element.Version))] = true
+	}
+
+	builtins := []APIcastPolicySchema{}
+	for _, schema := range schemas {
+		if !custom[policySchemaKey(stringValue(schema.Name), stringValue(schema.Version))] {
+			builtins = append(builtins, schema)
+		}
+	}
+	return builtins, nil
+}
+
+// listPolicySchemas returns the manifests of every policy available to the tenant, sorted by name and version
+func (c *ThreeScaleClient) listPolicySchemas() ([]APIcastPolicySchema, error) {
+	req, err := c.buildGetReq(policiesListEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// policies are listed by name, custom ones of the registry included
+	byName := map[string][]APIcastPolicySchema{}
+	if err := handleJsonResp(resp, http.StatusOK, &byName); err != nil {
+		return nil, err
+	}
+
+	schemas := []APIcastPolicySchema{}
+	for name, list := range byName {
+		for _, schema := range list {
+			if schema.Name == nil {
+				policyName := name
+				schema.Name = &policyName
+			}
+			schemas = append(schemas, schema)
+		}
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		a, b := schemas[i], schemas[j]
+		if *a.Name != *b.Name {
+			return *a.Name < *b.Name
+		}
+		return comparePolicyVersions(stringValue(a.Version), stringValue(b.Version)) < 0
+	})
+	return schemas, nil
+}
+
+// PolicyCatalog lists the policies of the gateway and the custom policies of the registry, sorted by name
+// and version. Policies are built-in unless they are in the registry
+func (c *ThreeScaleClient) PolicyCatalog() (*PolicyCatalog, error) {
+	schemas, err := c.listPolicySchemas()
+	if err != nil {
+		return nil, fmt.Errorf("policies: %w", err)
+	}
+
+	registry, err := c.ListAPIcastPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("policy registry: %w", err)
+	}
+	custom := map[string]APIcastPolicyItem{}
+	for _, policy := range registry.Items {
+		custom[policySchemaKey(stringValue(policy.Element.Name), stringValue(policy.Element.Version))] = policy.Element
+	}
+
+	catalog := &PolicyCatalog{Policies: []PolicyCatalogItem{}}
+	for idx := range schemas {
+		item := PolicyCatalogItem{
+			Name:    stringValue(schemas[idx].Name),
+			Version: stringValue(schemas[idx].Version),
+			Builtin: true,
+			Schema:  &schemas[idx],
+		}
+		key := policySchemaKey(item.Name, item.Version)
+		if policy, ok := custom[key]; ok {
+			item.Builtin = false
+			item.RegistryID = policy.ID
+			delete(custom, key)
+		}
+		catalog.Policies = append(catalog.Policies, item)
+	}
+
+	// registry policies not listed yet among the policies of the tenant
+	for _, policy := range custom {
+		catalog.Policies = append(catalog.Policies, PolicyCatalogItem{
+			Name:       stringValue(policy.Name),
+			Version:    stringValue(policy.Version),
+			RegistryID: policy.ID,
+			Schema:     policy.Schema,
+		})
+	}
+
+	sort.SliceStable(catalog.Policies, func(i, j int) bool {
+		a, b := catalog.Policies[i], catalog.Policies[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return comparePolicyVersions(a.Version, b.Version) < 0
+	})
+	return catalog, nil
+}
+
+// comparePolicyVersions orders versions following semantic versioning precedence. Versions that are not
+// semantic, such as "builtin", go after the semantic ones, in lexical order
+func comparePolicyVersions(a, b string) int {
+	matchA, matchB := policySemver.FindStringSubmatch(a), policySemver.FindStringSubmatch(b)
+	switch {
+	case matchA == nil && matchB == nil:
+		return strings.Compare(a, b)
+	case matchA == nil:
+		return 1
+	case matchB == nil:
+		return -1
+	}
+
+	for idx := 1; idx <= 3; idx++ {
+		if cmp := compareNumericIdentifiers(matchA[idx], matchB[idx]); cmp != 0 {
+			return cmp
+		}
+	}
+
+	// a pre-release has lower precedence than its release
+	preA, preB := matchA[4], matchB[4]
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+
+	fieldsA, fieldsB := strings.Split(preA, "."), strings.Split(preB, ".")
+	for idx := 0; idx < len(fieldsA) && idx < len(fieldsB); idx++ {
+		numericA, numericB := policyVersionNumber.MatchString(fieldsA[idx]), policyVersionNumber.MatchString(fieldsB[idx])
+		var cmp int
+		switch {
+		case numericA && numericB:
+			cmp = compareNumericIdentifiers(fieldsA[idx], fieldsB[idx])
+		case numericA:
+			// numeric identifiers have lower precedence than alphanumeric ones
+			cmp = -1
+		case numericB:
+			cmp = 1
+		default:
+			cmp = strings.Compare(fieldsA[idx], fieldsB[idx])
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return len(fieldsA) - len(fieldsB)
+}
+
+// compareNumericIdentifiers compares version numbers without leading zeros, whatever their size
+func compareNumericIdentifiers(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// Lookup returns the policy of the catalog with the given name and version, or nil
+func (c *PolicyCatalog) Lookup(name, version string) *PolicyCatalogItem {
+	for idx := range c.Policies {
+		if c.Policies[idx].Name == name && c.Policies[idx].Version == version {
+			return &c.Policies[idx]
+		}
+	}
+	return nil
+}
+
+// Names returns the distinct policy names of the catalog, sorted
+func (c *PolicyCatalog) Names() []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, policy := range c.Policies {
+		if !seen[policy.Name] {
+			seen[policy.Name] = true
+			names = append(names, policy.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Versions returns the versions of the policy with the given name, sorted by semantic version
+func (c *PolicyCatalog) Versions(name string) []string {
+	versions := []string{}
+	for _, policy := range c.Policies {
+		if policy.Name == name {
+			versions = append(versions, policy.Version)
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return comparePolicyVersions(versions[i], versions[j]) < 0 })
+	return versions
+}
+
+// Validator returns a validator for the policies of the catalog
+func (c *PolicyCatalog) Validator() *PolicyValidator {
+	v := NewPolicyValidator()
+	for _, policy := range c.Policies {
+		schema := APIcastPolicySchema{}
+		if policy.Schema != nil {
+			schema = *policy.Schema
+		}
+		// the catalog name and version prevail over the manifest ones
+		schema.Name, schema.Version = &policy.Name, &policy.Version
+		v.add(schema)
+	}
+	return v
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPolicyCatalog(t *testing.T) {
+	porta := newMockPorta(t)
+	// policies shipped with the gateway may have other versions than "builtin"
+	porta.reply(http.MethodGet, policiesListEndpoint, http.StatusOK, `{
+		"apicast": [{"name": "apicast", "version": "builtin"}],
+		"cors": [{"name": "cors", "version": "builtin"}],
+		"camel": [{"name": "camel", "version": "1.0.0"}],
+		"signer": [
+			{"name": "signer", "version": "0.2.0", "summary": "Signs requests"},
+			{"name": "signer", "version": "0.10.0", "summary": "Signs requests"},
+			{"name": "signer", "version": "0.2.0-beta.2", "summary": "Signs requests"},
+			{"name": "signer", "version": "0.2.0-beta.10", "summary": "Signs requests"},
+			{"name": "signer", "version": "0.1.0", "summary": "Signs requests"}
+		]
+	}`)
+	porta.reply(http.MethodGet, "/admin/api/registry/policies.json", http.StatusOK, `{"policies": [
+		{"policy": {"id": 60, "name": "signer", "version": "0.2.0", "schema": {"name": "signer", "version": "0.2.0", "summary": "Signs requests"}}},
+		{"policy": {"id": 61, "name": "signer", "version": "0.10.0", "schema": {"name": "signer", "version": "0.10.0", "summary": "Signs requests"}}},
+		{"policy": {"id": 62, "name": "signer", "version": "0.2.0-beta.2", "schema": {"name": "signer", "version": "0.2.0-beta.2", "summary": "Signs requests"}}},
+		{"policy": {"id": 63, "name": "signer", "version": "0.2.0-beta.10", "schema": {"name": "signer", "version": "0.2.0-beta.10", "summary": "Signs requests"}}},
+		{"policy": {"id": 64, "name": "signer", "version": "0.1.0", "schema": {"name": "signer", "version": "0.1.0", "summary": "Signs requests"}}}
+	]}`)
+	c := porta.client()
+
+	builtins, err := c.ListBuiltinPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, schema := range builtins {
+		names = append(names, *schema.Name)
+	}
+	// custom policies listed along the built-ins are left out
+	equals(t, []string{"apicast", "camel", "cors"}, names)
+
+	catalog, err := c.PolicyCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"apicast", "camel", "cors", "signer"}, catalog.Names())
+	equals(t, []string{"0.1.0", "0.2.0-beta.2", "0.2.0-beta.10", "0.2.0", "0.10.0"}, catalog.Versions("signer"))
+	equals(t, true, catalog.Lookup("camel", "1.0.0").Builtin)
+	equals(t, []string{BuiltinPolicyVersion}, catalog.Versions(CORSPolicyName))
+	equals(t, []string{}, catalog.Versions("missing"))
+
+	signer := catalog.Lookup("signer", "0.2.0")
+	if signer == nil {
+		t.Fatal("expected signer 0.2.0 in the catalog")
+	}
+	equals(t, false, signer.Builtin)
+	equals(t, int64(60), *signer.RegistryID)
+	equals(t, "Signs requests", *signer.Schema.Summary)
+
+	cors := catalog.Lookup(CORSPolicyName, BuiltinPolicyVersion)
+	equals(t, true, cors.Builtin)
+	equals(t, (*int64)(nil), cors.RegistryID)
+	equals(t, (*PolicyCatalogItem)(nil), catalog.Lookup("signer", "1.0.0"))
+
+	// policies without configuration schema accept any configuration
+	err = catalog.Validator().Validate(&PoliciesConfigList{Policies: []PolicyConfig{
+		{Name: "signer", Version: "0.1.0", Configuration: map[string]interface{}{"any": true}},
+		{Name: "camel", Version: "1.0.0"},
+	}})
+	equals(t, nil, err)
+}
+
+func TestPolicyCatalogError(t *testing.T) {
+	porta := newMockPorta(t)
+	porta.load("apicast_policies_fixture.json")
+	porta.reply(http.MethodGet, policiesListEndpoint, http.StatusForbidden, map[string]string{"error": "forbidden"})
+	c := porta.client()
+
+	if _, err := c.PolicyCatalog(); err == nil {
+		t.Fatal("expected error listing built-in policies")
+	}
+}
+
+func TestComparePolicyVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"0.9.0", "0.10.0", -1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+		{"1.0.0", BuiltinPolicyVersion, -1},
+		{"latest", BuiltinPolicyVersion, 1},
+	} {
+		cmp := comparePolicyVersions(tc.a, tc.b)
+		if (cmp < 0) != (tc.expected < 0) || (cmp > 0) != (tc.expected > 0) {
+			t.Errorf("comparePolicyVersions(%q, %q) = %d, expected sign of %d", tc.a, tc.b, cmp, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrAPIcastPolicyMissing is returned when a policy chain to persist lacks the apicast policy, or has it
+// disabled, without which APIcast does not authorize nor report requests
+var ErrAPIcastPolicyMissing = errors.New("policy chain must hold the apicast policy enabled")
+
+// UpdatePolicyChain reads the policy chain of the product, lets update modify it and persists it
+// when changed. The chain is not persisted when update fails, removes or disables the apicast policy
+func (c *ThreeScaleClient) UpdatePolicyChain(productID int64, update func(chain *PoliciesConfigList) error) (*PoliciesConfigList, error) {
+	chain, err := c.Policies(productID)
+	if err != nil {
+		return nil, fmt.Errorf("product %d: policies: %w", productID, err)
+	}
+
+	current, err := json.Marshal(chain)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := update(chain); err != nil {
+		return nil, err
+	}
+	if !chain.apicastEnabled() {
+		return nil, ErrAPIcastPolicyMissing
+	}
+
+	desired, err := json.Marshal(chain)
+	if err != nil {
+		return nil, err
+	}
+	if string(current) == string(desired) {
+		return chain, nil
+	}
+
+	return c.UpdatePolicies(productID, chain)
+}
+
+func (l *PoliciesConfigList) apicastEnabled() bool {
+	for _, policy := range l.Policies {
+		if policy.Name == APIcastPolicyName && policy.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// Index returns the position of the first policy with the given name, or -1
+func (l *PoliciesConfigList) Index(name string) int {
+	for idx, policy := range l.Policies {
+		if policy.Name == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+// Policy returns the first policy with the given name, or nil
+func (l *PoliciesConfigList) Policy(name string) *PolicyConfig {
+	if idx := l.Index(name); idx >= 0 {
+		return &l.Policies[idx]
+	}
+	return nil
+}
+
+// InsertBefore inserts the policies right before the first policy with the given name,
+// e.g. before "apicast" so they run first
+func (l *PoliciesConfigList) InsertBefore(name string, policies ...PolicyConfig) error {
+	idx := l.Index(name)
+	if idx < 0 {
+		return fmt.Errorf("policy %s not found in the chain", name)
+	}
+	l.insert(idx, policies)
+	return nil
+}
+
+// InsertAfter inserts the policies right after the first policy with the given name
+func (l *PoliciesConfigList) InsertAfter(name string, policies ...PolicyConfig) error {
+	idx := l.Index(name)
+	if idx < 0 {
+		return fmt.Errorf("policy %s not found in the chain", name)
+	}
+	l.insert(idx+1, policies)
+	return nil
+}
+
+func (l *PoliciesConfigList) insert(idx int, policies []PolicyConfig) {
+	chain := make([]PolicyConfig, 0, len(l.Policies)+len(policies))
+	chain = append(chain, l.Policies[:idx]...)
+	chain = append(chain, policies...)
+	chain = append(chain, l.Policies[idx:]...)
+	l.Policies = chain
+}
+
+// Ensure makes the chain hold the policy: the first policy with the same name takes its version,
+// configuration and enabled flag, or the policy is inserted before apicast (appended without apicast).
+// It returns whether the chain changed
+func (l *PoliciesConfigList) Ensure(policy PolicyConfig) bool {
+	if current := l.Policy(policy.Name); current != nil {
+		if current.Version == policy.Version && current.Enabled == policy.Enabled &&
+			policyConfigurationEqual(current.Configuration, policy.Configuration) {
+			return false
+		}
+		*current = policy
+		return true
+	}
+
+	if idx := l.Index(APIcastPolicyName); idx >= 0 {
+		l.insert(idx, []PolicyConfig{policy})
+	} else {
+		l.Policies = append(l.Policies, policy)
+	}
+	return true
+}
+
+// policyConfigurationEqual compares configurations as JSON documents, so a nil configuration equals an
+// empty one and numbers equal whatever their Go type
+func policyConfigurationEqual(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	rawA, errA := json.Marshal(a)
+	rawB, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(rawA) == string(rawB)
+}
+
+// Remove removes every policy with the given name and returns how many were removed
+func (l *PoliciesConfigList) Remove(name string) int {
+	chain := make([]PolicyConfig, 0, len(l.Policies))
+	for _, policy := range l.Policies {
+		if policy.Name != name {
+			chain = append(chain, policy)
+		}
+	}
+	removed := len(l.Policies) - len(chain)
+	l.Policies = chain
+	return removed
+}
+
+// SetEnabled enables or disables every policy with the given name.
+// It returns false when the chain has no such policy
+func (l *PoliciesConfigList) SetEnabled(name string, enabled bool) bool {
+	found := false
+	for idx := range l.Policies {
+		if l.Policies[idx].Name == name {
+			l.Policies[idx].Enabled = enabled
+			found = true
+		}
+	}
+	return found
+}
+
+// Reorder sorts the chain following the given names, which must list every policy of the chain once.
+// Policies repeated in the chain are listed as many times and keep their relative order
+func (l *PoliciesConfigList) Reorder(names ...string) error {
+	if len(names) != len(l.Policies) {
+		return fmt.Errorf("expected the %d policies of the chain; got %d", len(l.Policies), len(names))
+	}
+
+	byName := map[string][]PolicyConfig{}
+	for _, policy := range l.Policies {
+		byName[policy.Name] = append(byName[policy.Name], policy)
+	}
+
+	chain := make([]PolicyConfig, 0, len(names))
+	for _, name := range names {
+		policies := byName[name]
+		if len(policies) == 0 {
+			return fmt.Errorf("policy %s not found in the chain or listed too many times", name)
+		}
+		byName[name] = policies[1:]
+		chain = append(chain, policies[0])
+	}
+	l.Policies = chain
+	return nil
+}
+
+// Dedupe removes the policies with the same name, version and configuration as a previous one and
+// returns how many were removed. Policies repeated on purpose, with different configurations, are kept
+func (l *PoliciesConfigList) Dedupe() int {
+	chain := make([]PolicyConfig, 0, len(l.Policies))
+	for _, policy := range l.Policies {
+		duplicated := false
+		for _, kept := range chain {
+			if kept.Name == policy.Name && kept.Version == policy.Version &&
+				policyConfigurationEqual(kept.Configuration, policy.Configuration) {
+				duplicated = true
+				break
+			}
+		}
+		if !duplicated {
+			chain = append(chain, policy)
+		}
+	}
+	removed := len(l.Policies) - len(chain)
+	l.Policies = chain
+	return removed
+}
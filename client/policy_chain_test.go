@@ -0,0 +1,189 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func testPolicyChain() *PoliciesConfigList {
+	return &PoliciesConfigList{Policies: []PolicyConfig{
+		{Name: "headers", Version: "builtin", Enabled: true},
+		{Name: "apicast", Version: "builtin", Enabled: true},
+		{Name: "logging", Version: "builtin", Enabled: false},
+	}}
+}
+
+func policyChainNames(chain *PoliciesConfigList) []string {
+	names := []string{}
+	for _, policy := range chain.Policies {
+		names = append(names, policy.Name)
+	}
+	return names
+}
+
+func TestPolicyChainInsert(t *testing.T) {
+	chain := testPolicyChain()
+	if err := chain.InsertBefore("apicast", PolicyConfig{Name: "cors"}, PolicyConfig{Name: "ip_check"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.InsertAfter("logging", PolicyConfig{Name: "upstream"}); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"headers", "cors", "ip_check", "apicast", "logging", "upstream"}, policyChainNames(chain))
+
+	if err := chain.InsertBefore("routing", PolicyConfig{Name: "cors"}); err == nil {
+		t.Fatal("expected error for missing policy")
+	}
+	if err := chain.InsertAfter("routing", PolicyConfig{Name: "cors"}); err == nil {
+		t.Fatal("expected error for missing policy")
+	}
+}
+
+func TestPolicyChainEnsure(t *testing.T) {
+	chain := testPolicyChain()
+
+	cors := PolicyConfig{Name: "cors", Version: "builtin", Configuration: map[string]interface{}{"max_age": 300}, Enabled: true}
+	equals(t, true, chain.Ensure(cors))
+	equals(t, []string{"headers", "cors", "apicast", "logging"}, policyChainNames(chain))
+
+	// same configuration, whatever the number type
+	cors.Configuration = map[string]interface{}{"max_age": float64(300)}
+	equals(t, false, chain.Ensure(cors))
+
+	cors.Configuration = map[string]interface{}{"max_age": 600}
+	equals(t, true, chain.Ensure(cors))
+	equals(t, 600, chain.Policy("cors").Configuration["max_age"])
+	equals(t, 4, len(chain.Policies))
+
+	// appended without apicast
+	chain = &PoliciesConfigList{}
+	equals(t, true, chain.Ensure(cors))
+	equals(t, []string{"cors"}, policyChainNames(chain))
+}
+
+func TestPolicyChainRemoveEnableDedupe(t *testing.T) {
+	chain := testPolicyChain()
+	chain.Policies = append(chain.Policies, PolicyConfig{Name: "headers", Version: "builtin"})
+
+	equals(t, 1, chain.Dedupe())
+	equals(t, []string{"headers", "apicast", "logging"}, policyChainNames(chain))
+	equals(t, 0, chain.Dedupe())
+
+	// repeated policies with other configurations or versions are kept
+	chain.Policies = append(chain.Policies,
+		PolicyConfig{Name: "headers", Version: "builtin", Configuration: map[string]interface{}{"request": []interface{}{}}},
+		PolicyConfig{Name: "logging", Version: "1.0.0"},
+		PolicyConfig{Name: "headers", Version: "builtin", Configuration: map[string]interface{}{"request": []interface{}{}}},
+	)
+	equals(t, 1, chain.Dedupe())
+	equals(t, []string{"headers", "apicast", "logging", "headers", "logging"}, policyChainNames(chain))
+	chain.Policies = chain.Policies[:3]
+
+	equals(t, true, chain.SetEnabled("logging", true))
+	equals(t, true, chain.Policy("logging").Enabled)
+	equals(t, false, chain.SetEnabled("cors", true))
+
+	equals(t, 1, chain.Remove("headers"))
+	equals(t, 0, chain.Remove("headers"))
+	equals(t, []string{"apicast", "logging"}, policyChainNames(chain))
+	equals(t, (*PolicyConfig)(nil), chain.Policy("headers"))
+}
+
+func TestPolicyChainReorder(t *testing.T) {
+	chain := testPolicyChain()
+	if err := chain.Reorder("logging", "headers", "apicast"); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"logging", "headers", "apicast"}, policyChainNames(chain))
+
+	for _, names := range [][]string{
+		{"logging", "headers"},
+		{"logging", "headers", "cors"},
+		{"logging", "logging", "apicast"},
+	} {
+		if err := chain.Reorder(names...); err == nil {
+			t.Fatalf("expected error for %v", names)
+		}
+	}
+
+	// repeated policies keep their relative order
+	chain.Policies = append(chain.Policies, PolicyConfig{Name: "headers", Version: "1.0.0"})
+	if err := chain.Reorder("headers", "apicast", "logging", "headers"); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"headers", "apicast", "logging", "headers"}, policyChainNames(chain))
+	equals(t, "builtin", chain.Policies[0].Version)
+	equals(t, "1.0.0", chain.Policies[3].Version)
+
+	if err := chain.Reorder("headers", "apicast", "logging", "logging"); err == nil {
+		t.Fatal("expected error for policy listed too many times")
+	}
+}
+
+func TestUpdatePolicyChain(t *testing.T) {
+	porta := newMockPorta(t)
+	path := "/admin/api/services/10/proxy/policies.json"
+	porta.reply(http.MethodGet, path, http.StatusOK, PoliciesConfigList{Policies: []PolicyConfig{
+		{Name: "apicast", Version: "builtin", Configuration: map[string]interface{}{}, Enabled: true},
+	}})
+	porta.handle(http.MethodPut, path, func(req *http.Request) *http.Response {
+		return helperJSONResponse(t, http.StatusOK, json.RawMessage(porta.calls(http.MethodPut, path)[0].Body))
+	})
+	c := porta.client()
+
+	cors, err := NewPolicyConfig(CORSPolicyConfig{AllowOrigin: "*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := c.UpdatePolicyChain(10, func(chain *PoliciesConfigList) error {
+		chain.Ensure(cors)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"cors", "apicast"}, policyChainNames(chain))
+	equals(t, []string{"PUT " + path}, porta.writes())
+	persisted := &PoliciesConfigList{}
+	if err := json.Unmarshal(porta.calls(http.MethodPut, path)[0].Body, persisted); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"cors", "apicast"}, policyChainNames(persisted))
+
+	// unchanged chains are not persisted
+	porta.reply(http.MethodGet, path, http.StatusOK, json.RawMessage(porta.calls(http.MethodPut, path)[0].Body))
+	porta.reset()
+	if _, err := c.UpdatePolicyChain(10, func(chain *PoliciesConfigList) error {
+		chain.Ensure(cors)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{}, porta.writes())
+
+	// apicast stays in the chain
+	_, err = c.UpdatePolicyChain(10, func(chain *PoliciesConfigList) error {
+		chain.Remove(APIcastPolicyName)
+		return nil
+	})
+	if !errors.Is(err, ErrAPIcastPolicyMissing) {
+		t.Fatalf("expected missing apicast error, got %v", err)
+	}
+
+	// and enabled
+	_, err = c.UpdatePolicyChain(10, func(chain *PoliciesConfigList) error {
+		chain.SetEnabled(APIcastPolicyName, false)
+		return nil
+	})
+	if !errors.Is(err, ErrAPIcastPolicyMissing) {
+		t.Fatalf("expected disabled apicast error, got %v", err)
+	}
+
+	updateErr := errors.New("boom")
+	if _, err := c.UpdatePolicyChain(10, func(chain *PoliciesConfigList) error { return updateErr }); err != updateErr {
+		t.Fatalf("expected update error, got %v", err)
+	}
+	equals(t, []string{}, porta.writes())
+}
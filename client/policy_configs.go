@@ -0,0 +1,103 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Names of the built-in APIcast policies
+const (
+	APIcastPolicyName       = "apicast"
+	CORSPolicyName          = "cors"
+	HeadersPolicyName       = "headers"
+	URLRewritingPolicyName  = "url_rewriting"
+	IPCheckPolicyName       = "ip_check"
+	RateLimitPolicyName     = "rate_limit"
+	CachingPolicyName       = "caching"
+	RoutingPolicyName       = "routing"
+	JWTClaimCheckPolicyName = "jwt_claim_check"
+	UpstreamPolicyName      = "upstream"
+	LoggingPolicyName       = "logging"
+)
+
+// BuiltinPolicyVersion is the version of the policies shipped with APIcast
+const BuiltinPolicyVersion = "builtin"
+
+// PolicyName returns the name of the CORS request handling policy
+func (CORSPolicyConfig) PolicyName() string { return CORSPolicyName }
+
+// PolicyName returns the name of the header modification policy
+func (HeadersPolicyConfig) PolicyName() string { return HeadersPolicyName }
+
+// PolicyName returns the name of the URL rewriting policy
+func (URLRewritingPolicyConfig) PolicyName() string { return URLRewritingPolicyName }
+
+// PolicyName returns the name of the IP check policy
+func (IPCheckPolicyConfig) PolicyName() string { return IPCheckPolicyName }
+
+// PolicyName returns the name of the edge limiting policy
+func (RateLimitPolicyConfig) PolicyName() string { return RateLimitPolicyName }
+
+// PolicyName returns the name of the 3scale auth caching policy
+func (CachingPolicyConfig) PolicyName() string { return CachingPolicyName }
+
+// PolicyName returns the name of the routing policy
+func (RoutingPolicyConfig) PolicyName() string { return RoutingPolicyName }
+
+// PolicyName returns the name of the JWT claim check policy
+func (JWTClaimCheckPolicyConfig) PolicyName() string { return JWTClaimCheckPolicyName }
+
+// PolicyName returns the name of the upstream policy
+func (UpstreamPolicyConfig) PolicyName() string { return UpstreamPolicyName }
+
+// PolicyName returns the name of the logging policy
+func (LoggingPolicyConfig) PolicyName() string { return LoggingPolicyName }
+
+// NewPolicyConfig returns the enabled built-in policy with the given configuration, ready to be added to
+// a policy chain
+func NewPolicyConfig(config PolicyConfiguration) (PolicyConfig, error) {
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return PolicyConfig{}, fmt.Errorf("policy %s: %w", config.PolicyName(), err)
+	}
+
+	configuration := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &configuration); err != nil {
+		return PolicyConfig{}, fmt.Errorf("policy %s: %w", config.PolicyName(), err)
+	}
+
+	return PolicyConfig{
+		Name:          config.PolicyName(),
+		Version:       BuiltinPolicyVersion,
+		Configuration: configuration,
+		Enabled:       true,
+	}, nil
+}
+
+// Decode fills the typed configuration with the configuration of the policy.
+// It fails when the policy is not the one the configuration is for, or its configuration does not fit
+func (p PolicyConfig) Decode(config PolicyConfiguration) error {
+	if p.Name != config.PolicyName() {
+		return fmt.Errorf("policy %s cannot be decoded as %s", p.Name, config.PolicyName())
+	}
+
+	raw, err := json.Marshal(p.Configuration)
+	if err != nil {
+		return fmt.Errorf("policy %s: %w", p.Name, err)
+	}
+	if err := json.Unmarshal(raw, config); err != nil {
+		return fmt.Errorf("policy %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// DecodePolicy fills the typed configuration with the configuration of the first policy of the chain
+// it is for. It returns false when the chain has no such policy
+func (l *PoliciesConfigList) DecodePolicy(config PolicyConfiguration) (bool, error) {
+	for _, policy := range l.Policies {
+		if policy.Name == config.PolicyName() {
+			return true, policy.Decode(config)
+		}
+	}
+	return false, nil
+}
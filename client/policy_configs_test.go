@@ -0,0 +1,155 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestNewPolicyConfig(t *testing.T) {
+	policy, err := NewPolicyConfig(CORSPolicyConfig{
+		AllowHeaders: []string{"Authorization"},
+		AllowOrigin:  "*",
+		MaxAge:       300,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, PolicyConfig{
+		Name:    "cors",
+		Version: "builtin",
+		Configuration: map[string]interface{}{
+			"allow_headers": []interface{}{"Authorization"},
+			"allow_origin":  "*",
+			"max_age":       float64(300),
+		},
+		Enabled: true,
+	}, policy)
+
+	// unset defaults are left to APIcast
+	disabled := false
+	policy, err = NewPolicyConfig(LoggingPolicyConfig{EnableAccessLogs: &disabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, map[string]interface{}{"enable_access_logs": false}, policy.Configuration)
+}
+
+func TestPolicyConfigDecode(t *testing.T) {
+	for _, config := range []PolicyConfiguration{
+		&CORSPolicyConfig{AllowMethods: []string{"GET"}, AllowCredentials: true},
+		&HeadersPolicyConfig{Request: []HeaderPolicyCommand{{Op: "set", Header: "X-Id", Value: "{{ service.id }}", ValueType: "liquid"}}},
+		&URLRewritingPolicyConfig{
+			Commands:          []URLRewritingCommand{{Op: "gsub", Regex: "^/v1", Replace: "/", Break: true, Methods: []string{"GET"}}},
+			QueryArgsCommands: []QueryArgCommand{{Op: "delete", Arg: "debug"}},
+		},
+		&IPCheckPolicyConfig{CheckType: "blacklist", IPs: []string{"10.0.0.0/8"}, ClientIPSources: []string{"X-Forwarded-For"}},
+		&RateLimitPolicyConfig{
+			ConnectionLimiters:  []ConnectionLimiter{{Key: RateLimiterKey{Name: "conn"}, Conn: 10, Burst: 5, Delay: 0.5}},
+			LeakyBucketLimiters: []LeakyBucketLimiter{{Key: RateLimiterKey{Name: "{{ remote_addr }}", NameType: "liquid", Scope: "global"}, Rate: 10, Burst: 2}},
+			FixedWindowLimiters: []FixedWindowLimiter{{Key: RateLimiterKey{Name: "window"}, Count: 100, Window: 60}},
+			LimitsExceededError: &RateLimitErrorHandling{StatusCode: 429, ErrorHandling: "exit"},
+		},
+		&CachingPolicyConfig{CachingType: "resilient"},
+		&RoutingPolicyConfig{Rules: []RoutingRule{{
+			URL:       "https://cats.example.com",
+			Condition: RoutingCondition{Operations: []RoutingOperation{{Match: "header", HeaderName: "X-Pet", Op: "==", Value: "cat"}}},
+		}}},
+		&JWTClaimCheckPolicyConfig{Rules: []JWTClaimCheckRule{{
+			Resource:   "/admin",
+			Methods:    []string{"ANY"},
+			Operations: []JWTClaimOperation{{Op: "==", JWTClaim: "role", Value: "admin"}},
+		}}, ErrorMessage: "forbidden"},
+		&UpstreamPolicyConfig{Rules: []UpstreamRule{{Regex: "^/cats", URL: "https://cats.example.com"}}},
+		&LoggingPolicyConfig{
+			EnableJSONLogs:   true,
+			JSONObjectConfig: []LoggingJSONField{{Key: "status", Value: "{{status}}", ValueType: "liquid"}},
+			Condition:        &LoggingCondition{CombineOp: "or", Operations: []LoggingConditionOperation{{Op: "==", Match: "{{status}}", MatchType: "liquid", Value: "500"}}},
+		},
+	} {
+		policy, err := NewPolicyConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		equals(t, config.PolicyName(), policy.Name)
+
+		// decoding into an empty value of the same type gives back the configuration
+		decoded := newEmptyPolicyConfiguration(config)
+		if err := policy.Decode(decoded); err != nil {
+			t.Fatal(err)
+		}
+		equals(t, config, decoded)
+	}
+}
+
+func newEmptyPolicyConfiguration(config PolicyConfiguration) PolicyConfiguration {
+	switch config.(type) {
+	case *CORSPolicyConfig:
+		return &CORSPolicyConfig{}
+	case *HeadersPolicyConfig:
+		return &HeadersPolicyConfig{}
+	case *URLRewritingPolicyConfig:
+		return &URLRewritingPolicyConfig{}
+	case *IPCheckPolicyConfig:
+		return &IPCheckPolicyConfig{}
+	case *RateLimitPolicyConfig:
+		return &RateLimitPolicyConfig{}
+	case *CachingPolicyConfig:
+		return &CachingPolicyConfig{}
+	case *RoutingPolicyConfig:
+		return &RoutingPolicyConfig{}
+	case *JWTClaimCheckPolicyConfig:
+		return &JWTClaimCheckPolicyConfig{}
+	case *UpstreamPolicyConfig:
+		return &UpstreamPolicyConfig{}
+	case *LoggingPolicyConfig:
+		return &LoggingPolicyConfig{}
+	}
+	return nil
+}
+
+func TestPolicyConfigDecodeErrors(t *testing.T) {
+	policy := PolicyConfig{Name: "cors", Configuration: map[string]interface{}{"max_age": "forever"}}
+	if err := policy.Decode(&CORSPolicyConfig{}); err == nil {
+		t.Fatal("expected error for invalid configuration")
+	}
+	if err := policy.Decode(&HeadersPolicyConfig{}); err == nil {
+		t.Fatal("expected error for another policy")
+	}
+}
+
+func TestDecodePolicyFromPolicies(t *testing.T) {
+	porta := petsPorta(t, true)
+	c := porta.client()
+
+	upstream, err := NewPolicyConfig(UpstreamPolicyConfig{Rules: []UpstreamRule{{Regex: "^/", URL: "https://pets.example.com"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.UpdatePolicies(10, &PoliciesConfigList{Policies: []PolicyConfig{
+		upstream,
+		{Name: APIcastPolicyName, Version: BuiltinPolicyVersion, Configuration: map[string]interface{}{}, Enabled: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the chain is read back as uploaded
+	path := "/admin/api/services/10/proxy/policies.json"
+	porta.reply(http.MethodGet, path, http.StatusOK, json.RawMessage(porta.calls(http.MethodPut, path)[0].Body))
+	policies, err := c.Policies(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &UpstreamPolicyConfig{}
+	found, err := policies.DecodePolicy(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, found)
+	equals(t, "https://pets.example.com", config.Rules[0].URL)
+
+	found, err = policies.DecodePolicy(&CORSPolicyConfig{})
+	equals(t, false, found)
+	equals(t, nil, err)
+}
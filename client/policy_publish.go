@@ -0,0 +1,263 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PolicyManifestFile is the name of the manifest of an APIcast policy in its directory
+const PolicyManifestFile = "apicast-policy.json"
+
+// schemas of APIcast policy manifests, i.e. http://apicast.io/policy-v1/schema#manifest#
+var policyManifestSchema = regexp.MustCompile(`^http://apicast\.io/policy-v1(\.\d+)?/schema#manifest#?$`)
+
+// semantic versions, see https://semver.org
+var policySemver = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// policy names usable in a policy chain
+var policyNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type policyManifestFile struct {
+	path     string
+	manifest APIcastPolicySchema
+	// raw holds the validated manifest, fields unknown to APIcastPolicySchema included
+	raw json.RawMessage
+}
+
+// rawAPIcastPolicyItem is an APIcastPolicyItem with the manifest kept as sent or received
+type rawAPIcastPolicyItem struct {
+	ID      *int64          `json:"id,omitempty"`
+	Name    *string         `json:"name,omitempty"`
+	Version *string         `json:"version,omitempty"`
+	Schema  json.RawMessage `json:"schema,omitempty"`
+}
+
+type rawAPIcastPolicy struct {
+	Element rawAPIcastPolicyItem `json:"policy"`
+}
+
+type rawAPIcastPolicyRegistry struct {
+	Items []rawAPIcastPolicy `json:"policies"`
+}
+
+func (f policyManifestFile) key() string {
+	return policySchemaKey(stringValue(f.manifest.Name), stringValue(f.manifest.Version))
+}
+
+// ValidatePolicyManifest checks an apicast-policy.json manifest holds a manifest $schema, a name, a summary,
+// a semantic version and a configuration schema, and returns it. A description given as a single string is
+// turned into a list of lines. Problems are reported in a *PolicyManifestValidationErr
+func ValidatePolicyManifest(content []byte) (*APIcastPolicySchema, error) {
+	manifest, _, err := validatePolicyManifest(content)
+	return manifest, err
+}
+
+// validatePolicyManifest validates the manifest and returns it along with the JSON document to upload
+func validatePolicyManifest(content []byte) (*APIcastPolicySchema, json.RawMessage, error) {
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, nil, &PolicyManifestValidationErr{Errors: []ValidationError{{Pointer: "", Message: err.Error()}}}
+	}
+
+	problems := []ValidationError{}
+	requireString := func(field string) string {
+		value, ok := doc[field].(string)
+		if !ok || value == "" {
+			problems = append(problems, ValidationError{Pointer: jsonPointer(field), Message: "must be a non empty string"})
+		}
+		return value
+	}
+
+	if schema := requireString("$schema"); schema != "" && !policyManifestSchema.MatchString(schema) {
+		problems = append(problems, ValidationError{Pointer: jsonPointer("$schema"), Message: fmt.Sprintf("%s is not an APIcast policy manifest schema", schema)})
+	}
+	if name := requireString("name"); name != "" && !policyNamePattern.MatchString(name) {
+		problems = append(problems, ValidationError{Pointer: jsonPointer("name"), Message: "must only hold letters, digits, underscores and dashes"})
+	}
+	requireString("summary")
+	if version := requireString("version"); version != "" && !policySemver.MatchString(version) {
+		problems = append(problems, ValidationError{Pointer: jsonPointer("version"), Message: fmt.Sprintf("%s is not a semantic version", version)})
+	}
+
+	switch description := doc["description"].(type) {
+	case nil:
+	case string:
+		doc["description"] = strings.Split(description, "\n")
+	case []interface{}:
+		for idx, line := range description {
+			if _, ok := line.(string); !ok {
+				problems = append(problems, ValidationError{Pointer: jsonPointer("description", fmt.Sprint(idx)), Message: "must be a string"})
+			}
+		}
+	default:
+		problems = append(problems, ValidationError{Pointer: jsonPointer("description"), Message: "must be a string or a list of strings"})
+	}
+
+	if configuration, ok := doc["configuration"].(map[string]interface{}); !ok {
+		problems = append(problems, ValidationError{Pointer: jsonPointer("configuration"), Message: "must be a JSON schema object"})
+	} else if schemaType, ok := configuration["type"]; ok && schemaType != "object" {
+		problems = append(problems, ValidationError{Pointer: jsonPointer("configuration", "type"), Message: "must be object"})
+	}
+
+	if len(problems) > 0 {
+		return nil, nil, &PolicyManifestValidationErr{Errors: problems}
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest := &APIcastPolicySchema{}
+	if err := json.Unmarshal(raw, manifest); err != nil {
+		return nil, nil, err
+	}
+	return manifest, raw, nil
+}
+
+// PublishPolicies makes the custom policies of the registry match the apicast-policy.json manifests found
+// in the directories, subdirectories included. Policies are matched by name and version: missing ones are
+// created and the others updated when their manifest changed. Every manifest is validated, see
+// ValidatePolicyManifest, before anything is uploaded. Manifests are uploaded as written, fields
+// APIcastPolicySchema does not hold included
+func (c *ThreeScaleClient) PublishPolicies(dirs []string, opts PolicyPublishOptions) (*PolicyPublishResult, error) {
+	files, err := readPolicyManifests(dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &rawAPIcastPolicyRegistry{}
+	if err := c.listAPIcastPolicies(registry); err != nil {
+		return nil, fmt.Errorf("policy registry: %w", err)
+	}
+	remote := map[string]rawAPIcastPolicyItem{}
+	for _, policy := range registry.Items {
+		remote[policySchemaKey(stringValue(policy.Element.Name), stringValue(policy.Element.Version))] = policy.Element
+	}
+
+	result := &PolicyPublishResult{
+		Created:   []string{},
+		Updated:   []string{},
+		Deleted:   []string{},
+		Unchanged: []string{},
+		IDs:       map[string]int64{},
+	}
+
+	local := map[string]bool{}
+	for _, file := range files {
+		file := file
+		key := file.key()
+		local[key] = true
+
+		current, ok := remote[key]
+		if !ok {
+			created := &rawAPIcastPolicy{}
+			err := c.createAPIcastPolicy(rawAPIcastPolicyItem{
+				Name:    file.manifest.Name,
+				Version: file.manifest.Version,
+				Schema:  file.raw,
+			}, created)
+			if err != nil {
+				return result, fmt.Errorf("create policy %s: %w", key, err)
+			}
+			result.Created = append(result.Created, key)
+			result.IDs[key] = *created.Element.ID
+			continue
+		}
+		result.IDs[key] = *current.ID
+
+		if policyManifestEqual(current.Schema, file.raw) {
+			result.Unchanged = append(result.Unchanged, key)
+			continue
+		}
+		if err := c.updateAPIcastPolicy(*current.ID, rawAPIcastPolicyItem{Schema: file.raw}, &rawAPIcastPolicy{}); err != nil {
+			return result, fmt.Errorf("update policy %s: %w", key, err)
+		}
+		result.Updated = append(result.Updated, key)
+	}
+
+	if !opts.Delete {
+		return result, nil
+	}
+
+	keys := make([]string, 0, len(remote))
+	for key := range remote {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if local[key] {
+			continue
+		}
+		if err := c.DeleteAPIcastPolicy(*remote[key].ID); err != nil {
+			return result, fmt.Errorf("delete policy %s: %w", key, err)
+		}
+		result.Deleted = append(result.Deleted, key)
+	}
+
+	return result, nil
+}
+
+// policyManifestEqual compares manifests as JSON documents, whatever the key order of the remote one
+func policyManifestEqual(current, desired json.RawMessage) bool {
+	var a, b interface{}
+	if err := json.Unmarshal(current, &a); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(desired, &b); err != nil {
+		return false
+	}
+	return jsonEqual(a, b)
+}
+
+// readPolicyManifests reads and validates the manifests found in the directories, sorted by name and version
+func readPolicyManifests(dirs []string) ([]policyManifestFile, error) {
+	files := []policyManifestFile{}
+	paths := map[string]string{}
+
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || info.Name() != PolicyManifestFile {
+				return nil
+			}
+
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			manifest, raw, err := validatePolicyManifest(content)
+			if err != nil {
+				var validationErr *PolicyManifestValidationErr
+				if errors.As(err, &validationErr) {
+					validationErr.Path = path
+				}
+				return err
+			}
+
+			file := policyManifestFile{path: path, manifest: *manifest, raw: raw}
+			if previous, ok := paths[file.key()]; ok {
+				return fmt.Errorf("%s and %s both hold policy %s", previous, path, file.key())
+			}
+			paths[file.key()] = path
+			files = append(files, file)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].key() < files[j].key() })
+	return files, nil
+}
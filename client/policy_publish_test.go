@@ -0,0 +1,257 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPolicyManifest = `{
+  "$schema": "http://apicast.io/policy-v1/schema#manifest#",
+  "name": "signer",
+  "summary": "Signs requests",
+  "description": "Adds a signature header\nto upstream requests",
+  "version": "0.1.0",
+  "configuration": {
+    "type": "object",
+    "properties": {"secret": {"type": "string"}}
+  }
+}`
+
+func writePolicyManifest(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, PolicyManifestFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidatePolicyManifest(t *testing.T) {
+	manifest, err := ValidatePolicyManifest([]byte(testPolicyManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "signer", *manifest.Name)
+	equals(t, []string{"Adds a signature header", "to upstream requests"}, *manifest.Description)
+
+	_, err = ValidatePolicyManifest([]byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"name": "my signer",
+		"version": "1.0",
+		"description": 42,
+		"configuration": {"type": "array"}
+	}`))
+	validationErr := &PolicyManifestValidationErr{}
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	equals(t, []ValidationError{
+		{Pointer: "/$schema", Message: "http://json-schema.org/draft-07/schema# is not an APIcast policy manifest schema"},
+		{Pointer: "/name", Message: "must only hold letters, digits, underscores and dashes"},
+		{Pointer: "/summary", Message: "must be a non empty string"},
+		{Pointer: "/version", Message: "1.0 is not a semantic version"},
+		{Pointer: "/description", Message: "must be a string or a list of strings"},
+		{Pointer: "/configuration/type", Message: "must be object"},
+	}, validationErr.Errors)
+
+	if _, err := ValidatePolicyManifest([]byte(`not json`)); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
+
+// registryPolicyResponse answers with the policy of the request body, given the id
+func registryPolicyResponse(t *testing.T, req *http.Request, code int, id int64) *http.Response {
+	item := APIcastPolicyItem{}
+	if err := json.NewDecoder(req.Body).Decode(&item); err != nil {
+		t.Fatal(err)
+	}
+	item.ID = &id
+	return helperJSONResponse(t, code, APIcastPolicy{Element: item})
+}
+
+func TestPublishPolicies(t *testing.T) {
+	porta := newMockPorta(t)
+	registry := "/admin/api/registry/policies.json"
+	legacy := APIcastPolicy{}
+	if err := json.Unmarshal([]byte(`{"policy": {"id": 60, "name": "legacy", "version": "1.0.0", "schema": {"name": "legacy", "version": "1.0.0"}}}`), &legacy); err != nil {
+		t.Fatal(err)
+	}
+	porta.reply(http.MethodGet, registry, http.StatusOK, APIcastPolicyRegistry{Items: []APIcastPolicy{legacy}})
+	porta.handle(http.MethodPost, registry, func(req *http.Request) *http.Response {
+		return registryPolicyResponse(t, req, http.StatusCreated, int64(99+len(porta.calls(http.MethodPost, registry))))
+	})
+	porta.handle(http.MethodPut, "/admin/api/registry/policies/101.json", func(req *http.Request) *http.Response {
+		return registryPolicyResponse(t, req, http.StatusOK, 101)
+	})
+	porta.reply(http.MethodDelete, "/admin/api/registry/policies/60.json", http.StatusOK, nil)
+	c := porta.client()
+
+	dir, err := ioutil.TempDir("", "policies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writePolicyManifest(t, filepath.Join(dir, "signer", "0.1.0"), testPolicyManifest)
+	writePolicyManifest(t, filepath.Join(dir, "signer", "0.2.0"), strings.Replace(testPolicyManifest, `"0.1.0"`, `"0.2.0"`, 1))
+
+	result, err := c.PublishPolicies([]string{dir}, PolicyPublishOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"signer@0.1.0", "signer@0.2.0"}, result.Created)
+	equals(t, []string{}, result.Deleted)
+	equals(t, map[string]int64{"signer@0.1.0": 100, "signer@0.2.0": 101}, result.IDs)
+	equals(t, []string{"POST " + registry, "POST " + registry}, porta.writes())
+	created := []APIcastPolicy{}
+	for idx, req := range porta.calls(http.MethodPost, registry) {
+		item := APIcastPolicyItem{}
+		if err := json.Unmarshal(req.Body, &item); err != nil {
+			t.Fatal(err)
+		}
+		id := int64(100 + idx)
+		item.ID = &id
+		created = append(created, APIcastPolicy{Element: item})
+	}
+	equals(t, "0.2.0", *created[1].Element.Version)
+	equals(t, "Signs requests", *created[1].Element.Schema.Summary)
+
+	// nothing uploaded when nothing changed
+	porta.reply(http.MethodGet, registry, http.StatusOK, APIcastPolicyRegistry{Items: append([]APIcastPolicy{legacy}, created...)})
+	porta.reset()
+	result, err = c.PublishPolicies([]string{dir}, PolicyPublishOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"signer@0.1.0", "signer@0.2.0"}, result.Unchanged)
+	equals(t, 1, len(porta.served()))
+
+	writePolicyManifest(t, filepath.Join(dir, "signer", "0.2.0"),
+		strings.Replace(strings.Replace(testPolicyManifest, `"0.1.0"`, `"0.2.0"`, 1), "Signs requests", "Signs upstream requests", 1))
+	porta.reset()
+	result, err = c.PublishPolicies([]string{dir}, PolicyPublishOptions{Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"signer@0.2.0"}, result.Updated)
+	equals(t, []string{"signer@0.1.0"}, result.Unchanged)
+	equals(t, []string{"legacy@1.0.0"}, result.Deleted)
+	equals(t, []string{"PUT /admin/api/registry/policies/101.json", "DELETE /admin/api/registry/policies/60.json"}, porta.writes())
+	updated := APIcastPolicyItem{}
+	if err := json.Unmarshal(porta.served(http.MethodPut)[0].Body, &updated); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "Signs upstream requests", *updated.Schema.Summary)
+}
+
+func TestPublishPoliciesInvalid(t *testing.T) {
+	porta := newMockPorta(t)
+	c := porta.client()
+
+	dir, err := ioutil.TempDir("", "policies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writePolicyManifest(t, filepath.Join(dir, "signer"), testPolicyManifest)
+	writePolicyManifest(t, filepath.Join(dir, "broken"), strings.Replace(testPolicyManifest, `"0.1.0"`, `"latest"`, 1))
+
+	// nothing is uploaded when a manifest is invalid
+	_, err = c.PublishPolicies([]string{dir}, PolicyPublishOptions{})
+	validationErr := &PolicyManifestValidationErr{}
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	equals(t, filepath.Join(dir, "broken", PolicyManifestFile), validationErr.Path)
+	equals(t, []mockRequest{}, porta.served())
+
+	// the same policy version twice
+	other, err := ioutil.TempDir("", "policies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(other)
+	writePolicyManifest(t, other, testPolicyManifest)
+	if _, err := c.PublishPolicies([]string{filepath.Join(dir, "signer"), other}, PolicyPublishOptions{}); err == nil {
+		t.Fatal("expected error for duplicated policy")
+	}
+}
+
+func TestPublishPoliciesUnknownFields(t *testing.T) {
+	porta := newMockPorta(t)
+	registry := "/admin/api/registry/policies.json"
+	porta.reply(http.MethodGet, registry, http.StatusOK, `{"policies": []}`)
+	porta.handle(http.MethodPost, registry, func(req *http.Request) *http.Response {
+		return registryPolicyResponse(t, req, http.StatusCreated, 100)
+	})
+	porta.handle(http.MethodPut, "/admin/api/registry/policies/100.json", func(req *http.Request) *http.Response {
+		return registryPolicyResponse(t, req, http.StatusOK, 100)
+	})
+	c := porta.client()
+
+	dir, err := ioutil.TempDir("", "policies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writePolicyManifest(t, dir, strings.Replace(testPolicyManifest, `"version": "0.1.0",`,
+		`"version": "0.1.0", "order": {"before": [{"name": "apicast", "version": "builtin"}]},`, 1))
+
+	if _, err := c.PublishPolicies([]string{dir}, PolicyPublishOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	created := rawAPIcastPolicyItem{}
+	if err := json.Unmarshal(porta.served(http.MethodPost)[0].Body, &created); err != nil {
+		t.Fatal(err)
+	}
+	schema := map[string]interface{}{}
+	if err := json.Unmarshal(created.Schema, &schema); err != nil {
+		t.Fatal(err)
+	}
+	order := map[string]interface{}{"before": []interface{}{map[string]interface{}{"name": "apicast", "version": "builtin"}}}
+	equals(t, order, schema["order"])
+	equals(t, []interface{}{"Adds a signature header", "to upstream requests"}, schema["description"])
+
+	// the remote manifest keeps the order
+	id := int64(100)
+	created.ID = &id
+	porta.reply(http.MethodGet, registry, http.StatusOK, rawAPIcastPolicyRegistry{Items: []rawAPIcastPolicy{{Element: created}}})
+	porta.reset()
+	result, err := c.PublishPolicies([]string{dir}, PolicyPublishOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"signer@0.1.0"}, result.Unchanged)
+	equals(t, []string{}, porta.writes())
+
+	// and is updated when it lacks it
+	delete(schema, "order")
+	created.Schema, err = json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	porta.reply(http.MethodGet, registry, http.StatusOK, rawAPIcastPolicyRegistry{Items: []rawAPIcastPolicy{{Element: created}}})
+	porta.reset()
+	result, err = c.PublishPolicies([]string{dir}, PolicyPublishOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"signer@0.1.0"}, result.Updated)
+	updated := rawAPIcastPolicyItem{}
+	if err := json.Unmarshal(porta.served(http.MethodPut)[0].Body, &updated); err != nil {
+		t.Fatal(err)
+	}
+	schema = map[string]interface{}{}
+	if err := json.Unmarshal(updated.Schema, &schema); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, order, schema["order"])
+}
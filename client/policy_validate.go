@@ -0,0 +1,98 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// NewPolicyValidator returns a validator for the policies described by the given manifests.
+// Policies without a configuration schema accept any configuration
+func NewPolicyValidator(schemas ...APIcastPolicySchema) *PolicyValidator {
+	v := &PolicyValidator{schemas: map[string]json.RawMessage{}}
+	for _, schema := range schemas {
+		v.add(schema)
+	}
+	return v
+}
+
+func (v *PolicyValidator) add(schema APIcastPolicySchema) {
+	configuration := json.RawMessage(`{}`)
+	if schema.Configuration != nil && len(*schema.Configuration) > 0 {
+		configuration = *schema.Configuration
+	}
+	v.schemas[policySchemaKey(stringValue(schema.Name), stringValue(schema.Version))] = configuration
+}
+
+func policySchemaKey(name, version string) string {
+	return name + "@" + version
+}
+
+// PolicyValidator fetches the policy catalog of the tenant and returns a validator for its policies
+func (c *ThreeScaleClient) PolicyValidator() (*PolicyValidator, error) {
+	catalog, err := c.PolicyCatalog()
+	if err != nil {
+		return nil, err
+	}
+	return catalog.Validator(), nil
+}
+
+// ValidatePolicy validates the configuration of the policy against the schema of its name and version.
+// Pointers of the returned problems are relative to the policy
+func (v *PolicyValidator) ValidatePolicy(policy PolicyConfig) ([]ValidationError, error) {
+	schema, ok := v.schemas[policySchemaKey(policy.Name, policy.Version)]
+	if !ok {
+		return []ValidationError{{
+			Pointer: jsonPointer("version"),
+			Message: fmt.Sprintf("policy %s has no version %s", policy.Name, policy.Version),
+		}}, nil
+	}
+
+	configuration := policy.Configuration
+	if configuration == nil {
+		configuration = map[string]interface{}{}
+	}
+	errs, err := validateJSONSchema(schema, configuration, jsonPointer("configuration"))
+	if err != nil {
+		return nil, fmt.Errorf("policy %s version %s: %w", policy.Name, policy.Version, err)
+	}
+	return errs, nil
+}
+
+// Validate validates every policy of the chain. When a configuration does not match its schema it returns
+// a *PolicyValidationErr with pointers into the chain document, e.g. /policies_config/0/configuration/max_age
+func (v *PolicyValidator) Validate(chain *PoliciesConfigList) error {
+	problems := []ValidationError{}
+	for idx, policy := range chain.Policies {
+		errs, err := v.ValidatePolicy(policy)
+		if err != nil {
+			return err
+		}
+		base := jsonPointer("policies_config", strconv.Itoa(idx))
+		for _, e := range errs {
+			problems = append(problems, ValidationError{Pointer: base + e.Pointer, Message: e.Message})
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PolicyValidationErr{Errors: problems}
+	}
+	return nil
+}
+
+// ValidatePolicies validates the policy chain against the schemas of the policies available to the tenant
+func (c *ThreeScaleClient) ValidatePolicies(chain *PoliciesConfigList) error {
+	v, err := c.PolicyValidator()
+	if err != nil {
+		return err
+	}
+	return v.Validate(chain)
+}
+
+// UpdateValidatedPolicies validates the policy chain and only updates the policies of the product when valid
+func (c *ThreeScaleClient) UpdateValidatedPolicies(productID int64, chain *PoliciesConfigList) (*PoliciesConfigList, error) {
+	if err := c.ValidatePolicies(chain); err != nil {
+		return nil, err
+	}
+	return c.UpdatePolicies(productID, chain)
+}
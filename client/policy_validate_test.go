@@ -0,0 +1,80 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestPolicyValidator(t *testing.T) {
+	porta := newMockPorta(t)
+	porta.load("apicast_policies_fixture.json")
+	c := porta.client()
+
+	v, err := c.PolicyValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cors, err := NewPolicyConfig(CORSPolicyConfig{AllowMethods: []string{"GET"}, MaxAge: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := &PoliciesConfigList{Policies: []PolicyConfig{
+		cors,
+		{Name: "signer", Version: "0.1.0", Configuration: map[string]interface{}{"secret": "s3cr3t"}, Enabled: true},
+		{Name: APIcastPolicyName, Version: BuiltinPolicyVersion, Enabled: true},
+	}}
+	if err := v.Validate(chain); err != nil {
+		t.Fatal(err)
+	}
+
+	chain.Policies[0].Configuration["allow_methods"] = []interface{}{"GET", "FETCH"}
+	chain.Policies[1].Configuration = nil
+	chain.Policies = append(chain.Policies, PolicyConfig{Name: "signer", Version: "9.9.9"})
+
+	err = v.Validate(chain)
+	validationErr := &PolicyValidationErr{}
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	equals(t, []ValidationError{
+		{Pointer: "/policies_config/0/configuration/allow_methods/1", Message: `must be one of ["GET","HEAD","POST","PUT","DELETE","PATCH","OPTIONS"]`},
+		{Pointer: "/policies_config/1/configuration/secret", Message: "is required"},
+		{Pointer: "/policies_config/3/version", Message: "policy signer has no version 9.9.9"},
+	}, validationErr.Errors)
+}
+
+func TestUpdateValidatedPolicies(t *testing.T) {
+	porta := newMockPorta(t)
+	porta.load("apicast_policies_fixture.json")
+	path := "/admin/api/services/10/proxy/policies.json"
+	porta.handle(http.MethodPut, path, func(req *http.Request) *http.Response {
+		return helperJSONResponse(t, http.StatusOK, json.RawMessage(porta.calls(http.MethodPut, path)[0].Body))
+	})
+	c := porta.client()
+
+	chain := &PoliciesConfigList{Policies: []PolicyConfig{
+		{Name: HeadersPolicyName, Version: BuiltinPolicyVersion, Configuration: map[string]interface{}{
+			"request": []interface{}{map[string]interface{}{"op": "set", "value": "1"}},
+		}, Enabled: true},
+		{Name: APIcastPolicyName, Version: BuiltinPolicyVersion, Enabled: true},
+	}}
+
+	_, err := c.UpdateValidatedPolicies(10, chain)
+	validationErr := &PolicyValidationErr{}
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	equals(t, "invalid policy chain: #/policies_config/0/configuration/request/0/header: is required", err.Error())
+	equals(t, []string{}, porta.writes())
+
+	chain.Policies[0].Configuration["request"] = []interface{}{map[string]interface{}{"op": "set", "header": "X-Pets", "value": "1"}}
+	updated, err := c.UpdateValidatedPolicies(10, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"PUT " + path}, porta.writes())
+	equals(t, chain, updated)
+}
@@ -22,6 +22,13 @@ const (
 	PRODUCTS_PER_PAGE                      int = 500
 )
 
+// Product authentication modes, values of backend_version
+const (
+	BackendVersionUserKey     = "1"
+	BackendVersionAppIDAppKey = "2"
+	BackendVersionOIDC        = "oidc"
+)
+
 // BackendApi Read 3scale Backend
 func (c *ThreeScaleClient) Product(id int64) (*Product, error) {
 	endpoint := fmt.Sprintf(productResourceEndpoint, id)
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CopyProduct copies the product with the given ID from the src tenant into the dst tenant, along with
+// its backends, metrics, methods, mapping rules, proxy settings, policies, OIDC configuration,
+// application plans, limits, pricing rules and activedocs. src and dst may point to the same tenant,
+// in which case opts must rename the product and the activedocs, and either rename or reuse the backends,
+// otherwise the copy fails before changing anything.
+// The copy is idempotent: resources already present in dst are matched by system name and updated.
+// The returned result maps every source ID to the ID of its copy
+func CopyProduct(src *ThreeScaleClient, productID int64, dst *ThreeScaleClient, opts CopyProductOptions) (*ProductCopyResult, error) {
+	if src == nil || dst == nil {
+		return nil, errors.New("CopyProduct needs not nil clients")
+	}
+
+	bundle, srcIDs, err := src.exportProduct(productID)
+	if err != nil {
+		return nil, fmt.Errorf("export product %d: %w", productID, err)
+	}
+
+	renames := map[string]string{}
+	for _, backend := range bundle.Backends {
+		renames[backend.SystemName] = renamed(opts.BackendSystemNames, backend.SystemName)
+	}
+
+	if src.sameTenant(dst) {
+		if err := checkSameTenantCopy(bundle, opts, renames); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := renameProductBundle(bundle, opts, renames); err != nil {
+		return nil, err
+	}
+
+	if opts.ReuseBackends {
+		if err := dropExistingBackends(dst, bundle); err != nil {
+			return nil, err
+		}
+	}
+
+	dstIDs, importErr := dst.ImportProduct(bundle)
+	if dstIDs == nil {
+		return nil, importErr
+	}
+
+	result := &ProductCopyResult{
+		ProductID:          dstIDs.ProductID,
+		BackendIDs:         map[int64]int64{},
+		MetricIDs:          mapIDs(srcIDs.MetricIDs, dstIDs.MetricIDs, nil),
+		ApplicationPlanIDs: mapIDs(srcIDs.ApplicationPlanIDs, dstIDs.ApplicationPlanIDs, nil),
+		ActiveDocIDs:       mapIDs(srcIDs.ActiveDocIDs, dstIDs.ActiveDocIDs, opts.ActiveDocSystemNames),
+	}
+
+	for systemName, srcID := range srcIDs.BackendIDs {
+		dstID, ok := dstIDs.BackendIDs[renames[systemName]]
+		if !ok {
+			continue
+		}
+		result.BackendIDs[srcID] = dstID
+
+		dstMetricIDs, ok := dstIDs.BackendMetricIDs[renames[systemName]]
+		if !ok {
+			// reused backend whose metrics are not referenced by the product
+			owner := backendMetricOwner(dst, dstID)
+			list, err := owner.listMetrics()
+			if err != nil {
+				return result, fmt.Errorf("backend %s: metrics: %w", renames[systemName], err)
+			}
+			dstMetricIDs = map[string]int64{}
+			for _, metric := range list.Metrics {
+				dstMetricIDs[owner.systemName(metric.Element.SystemName)] = metric.Element.ID
+			}
+		}
+
+		for srcMetricID, dstMetricID := range mapIDs(srcIDs.BackendMetricIDs[systemName], dstMetricIDs, nil) {
+			result.MetricIDs[srcMetricID] = dstMetricID
+		}
+	}
+
+	return result, importErr
+}
+
+// sameTenant returns true when both clients target the same admin portal
+func (c *ThreeScaleClient) sameTenant(other *ThreeScaleClient) bool {
+	return c == other || strings.EqualFold(c.adminPortal.rawURL, other.adminPortal.rawURL)
+}
+
+// checkSameTenantCopy fails when a copy within the source tenant would match the source resources
+// by system name, updating them instead of creating copies
+func checkSameTenantCopy(bundle *ProductBundle, opts CopyProductOptions, backends map[string]string) error {
+	if opts.SystemName == "" || opts.SystemName == bundle.Product.SystemName {
+		return fmt.Errorf("product %s: copies within the same tenant need a new system name", bundle.Product.SystemName)
+	}
+
+	if !opts.ReuseBackends {
+		for _, backend := range bundle.Backends {
+			if backends[backend.SystemName] == backend.SystemName {
+				return fmt.Errorf("backend %s: copies within the same tenant need a new system name or ReuseBackends", backend.SystemName)
+			}
+		}
+	}
+
+	for _, doc := range bundle.Product.ActiveDocs {
+		if renamed(opts.ActiveDocSystemNames, doc.SystemName) == doc.SystemName {
+			return fmt.Errorf("activedoc %s: copies within the same tenant need a new system name", doc.SystemName)
+		}
+	}
+
+	return nil
+}
+
+func renamed(names map[string]string, systemName string) string {
+	if name, ok := names[systemName]; ok {
+		return name
+	}
+	return systemName
+}
+
+// renameProductBundle applies the copy options to the exported bundle
+func renameProductBundle(bundle *ProductBundle, opts CopyProductOptions, backends map[string]string) error {
+	if opts.SystemName != "" {
+		bundle.Product.SystemName = opts.SystemName
+	}
+	if opts.Name != "" {
+		bundle.Product.Name = opts.Name
+	}
+
+	for idx := range bundle.Backends {
+		bundle.Backends[idx].SystemName = backends[bundle.Backends[idx].SystemName]
+	}
+
+	for idx := range bundle.Product.BackendUsages {
+		usage := &bundle.Product.BackendUsages[idx]
+		usage.BackendSystemName = backends[usage.BackendSystemName]
+	}
+
+	for planIdx := range bundle.Product.ApplicationPlans {
+		plan := &bundle.Product.ApplicationPlans[planIdx]
+		for idx := range plan.Limits {
+			if ref := &plan.Limits[idx].MetricRefSpec; ref.BackendSystemName != "" {
+				ref.BackendSystemName = backends[ref.BackendSystemName]
+			}
+		}
+		for idx := range plan.PricingRules {
+			if ref := &plan.PricingRules[idx].MetricRefSpec; ref.BackendSystemName != "" {
+				ref.BackendSystemName = backends[ref.BackendSystemName]
+			}
+		}
+	}
+
+	for idx := range bundle.Product.ActiveDocs {
+		doc := &bundle.Product.ActiveDocs[idx]
+		doc.SystemName = renamed(opts.ActiveDocSystemNames, doc.SystemName)
+	}
+
+	return validateProductBundle(bundle)
+}
+
+// dropExistingBackends removes from the bundle the backends already present in the tenant,
+// so they are used as they are
+func dropExistingBackends(c *ThreeScaleClient, bundle *ProductBundle) error {
+	list, err := c.ListBackendApis()
+	if err != nil {
+		return fmt.Errorf("backend apis: %w", err)
+	}
+
+	existing := map[string]bool{}
+	for _, backend := range list.Backends {
+		existing[backend.Element.SystemName] = true
+	}
+
+	backends := []BackendApiSpec{}
+	for _, backend := range bundle.Backends {
+		if !existing[backend.SystemName] {
+			backends = append(backends, backend)
+		}
+	}
+	bundle.Backends = backends
+	return nil
+}
+
+// mapIDs joins two system name indexes into an ID index. Source system names are renamed when
+// present in renames
+func mapIDs(src, dst map[string]int64, renames map[string]string) map[int64]int64 {
+	ids := map[int64]int64{}
+	for systemName, srcID := range src {
+		if dstID, ok := dst[renamed(renames, systemName)]; ok {
+			ids[srcID] = dstID
+		}
+	}
+	return ids
+}
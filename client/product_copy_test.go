@@ -0,0 +1,167 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// copyPorta serves the product of testProductBundle as copied into another tenant,
+// with IDs shifted by 100. Unless copied, the product, backend and activedoc lists are empty
+func copyPorta(t *testing.T, copied bool) (*mockPorta, *ThreeScaleClient) {
+	porta := newMockPorta(t)
+	porta.load("pets_product_copy_fixture.json")
+	if !copied {
+		porta.reply(http.MethodGet, "/admin/api/services.json", http.StatusOK, ProductList{Products: []Product{}})
+		porta.reply(http.MethodGet, "/admin/api/backend_apis.json", http.StatusOK, BackendApiList{Backends: []BackendApi{}})
+		porta.reply(http.MethodGet, "/admin/api/active_docs.json", http.StatusOK, ActiveDocList{ActiveDocs: []ActiveDoc{}})
+	}
+
+	adminPortal, err := NewAdminPortalFromStr("https://production.test.com:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return porta, NewThreeScale(adminPortal, "someAccessToken", NewTestClient(porta.serve))
+}
+
+func TestCopyProductAcrossTenants(t *testing.T) {
+	staging := petsPorta(t, true)
+	src := staging.client()
+	production, dst := copyPorta(t, false)
+
+	result, err := CopyProduct(src, 10, dst, CopyProductOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// IDs are remapped
+	expected := &ProductCopyResult{
+		ProductID:          110,
+		BackendIDs:         map[int64]int64{20: 120},
+		MetricIDs:          map[int64]int64{11: 111, 12: 112, 21: 121, 22: 122, 23: 123},
+		ApplicationPlanIDs: map[int64]int64{40: 140},
+		ActiveDocIDs:       map[int64]int64{50: 150},
+	}
+	equals(t, expected, result)
+	equals(t, []string{}, staging.writes())
+
+	equals(t, "pets", production.calls(http.MethodPost, "/admin/api/services.json")[0].Params.Get("system_name"))
+	equals(t, "pets_backend", production.calls(http.MethodPost, "/admin/api/backend_apis.json")[0].Params.Get("system_name"))
+	rules := production.calls(http.MethodPost, "/admin/api/backend_apis/120/mapping_rules.json")
+	equals(t, "123", rules[0].Params.Get("metric_id"))
+	equals(t, "122", rules[1].Params.Get("metric_id"))
+	equals(t, "112", production.calls(http.MethodPost, "/admin/api/services/110/proxy/mapping_rules.json")[0].Params.Get("metric_id"))
+	equals(t, "120", production.calls(http.MethodPost, "/admin/api/services/110/backend_usages.json")[0].Params.Get("backend_api_id"))
+	equals(t, "basic", production.calls(http.MethodPost, "/admin/api/services/110/application_plans.json")[0].Params.Get("system_name"))
+	equals(t, "1000", production.calls(http.MethodPost, "/admin/api/application_plans/140/metrics/122/limits.json")[0].Params.Get("value"))
+
+	doc := ActiveDocItem{}
+	if err := json.Unmarshal(production.calls(http.MethodPost, "/admin/api/active_docs.json")[0].Body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, int64(110), *doc.ServiceID)
+
+	policies := PoliciesConfigList{}
+	if err := json.Unmarshal(production.calls(http.MethodPut, "/admin/api/services/110/proxy/policies.json")[0].Body, &policies); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, testProductBundle().Product.Policies, policies.Policies)
+
+	// copying again changes nothing
+	production, dst = copyPorta(t, true)
+	again, err := CopyProduct(src, 10, dst, CopyProductOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, expected, again)
+	equals(t, []string{}, production.writes())
+}
+
+// sameTenantCopyPorta serves the pets product along with the writes of its copy into the same tenant
+func sameTenantCopyPorta(t *testing.T) *mockPorta {
+	porta := petsPorta(t, true)
+	porta.load("pets_product_copy_fixture.json")
+	// product, backend and activedoc lists show the source resources
+	porta.load("pets_product_fixture.json", http.MethodGet)
+	return porta
+}
+
+func TestCopyProductSameTenant(t *testing.T) {
+	porta := sameTenantCopyPorta(t)
+	porta.reply(http.MethodPost, "/admin/api/application_plans/140/metrics/22/limits.json", http.StatusCreated, ApplicationPlanLimit{ApplicationPlanLimitItem{ID: 142}})
+	c := porta.client()
+
+	result, err := CopyProduct(c, 10, c, CopyProductOptions{
+		SystemName:           "pets_copy",
+		Name:                 "Pets API copy",
+		ReuseBackends:        true,
+		ActiveDocSystemNames: map[string]string{"pets_doc": "pets_copy_doc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	product := porta.calls(http.MethodPost, "/admin/api/services.json")[0]
+	equals(t, "pets_copy", product.Params.Get("system_name"))
+	equals(t, "Pets API copy", product.Params.Get("name"))
+
+	// backend is shared with the source product
+	equals(t, 0, len(porta.calls(http.MethodPost, "/admin/api/backend_apis.json")))
+	equals(t, map[int64]int64{20: 20}, result.BackendIDs)
+	equals(t, int64(22), result.MetricIDs[22])
+	equals(t, "20", porta.calls(http.MethodPost, "/admin/api/services/110/backend_usages.json")[0].Params.Get("backend_api_id"))
+	equals(t, 1, len(porta.calls(http.MethodPost, "/admin/api/application_plans/140/metrics/22/limits.json")))
+
+	// the source activedoc is left untouched
+	equals(t, 0, len(porta.calls(http.MethodPut, "/admin/api/active_docs/50.json")))
+	doc := ActiveDocItem{}
+	if err := json.Unmarshal(porta.calls(http.MethodPost, "/admin/api/active_docs.json")[0].Body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "pets_copy_doc", *doc.SystemName)
+	equals(t, int64(110), *doc.ServiceID)
+	equals(t, int64(150), result.ActiveDocIDs[50])
+}
+
+func TestCopyProductRenamedBackend(t *testing.T) {
+	porta := sameTenantCopyPorta(t)
+	c := porta.client()
+
+	result, err := CopyProduct(c, 10, c, CopyProductOptions{
+		SystemName:           "pets_copy",
+		BackendSystemNames:   map[string]string{"pets_backend": "pets_backend_copy"},
+		ActiveDocSystemNames: map[string]string{"pets_doc": "pets_copy_doc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equals(t, "pets_backend_copy", porta.calls(http.MethodPost, "/admin/api/backend_apis.json")[0].Params.Get("system_name"))
+	equals(t, map[int64]int64{20: 120}, result.BackendIDs)
+	equals(t, 2, len(porta.calls(http.MethodPost, "/admin/api/backend_apis/120/mapping_rules.json")))
+	equals(t, 0, len(porta.calls(http.MethodPost, "/admin/api/backend_apis/20/mapping_rules.json")))
+}
+
+func TestCopyProductSameTenantCollisions(t *testing.T) {
+	porta := petsPorta(t, true)
+	c := porta.client()
+
+	for _, tc := range []struct {
+		name     string
+		opts     CopyProductOptions
+		expected string
+	}{
+		{"product", CopyProductOptions{ReuseBackends: true, ActiveDocSystemNames: map[string]string{"pets_doc": "pets_copy_doc"}}, "product pets"},
+		{"backend", CopyProductOptions{SystemName: "pets_copy", ActiveDocSystemNames: map[string]string{"pets_doc": "pets_copy_doc"}}, "backend pets_backend"},
+		{"activedoc", CopyProductOptions{SystemName: "pets_copy", ReuseBackends: true}, "activedoc pets_doc"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CopyProduct(c, 10, porta.client(), tc.opts)
+			if err == nil || !strings.HasPrefix(err.Error(), tc.expected+":") {
+				t.Fatalf("expected %s collision, got %v", tc.expected, err)
+			}
+			equals(t, []string{}, porta.writes())
+		})
+	}
+}
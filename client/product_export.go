@@ -0,0 +1,314 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+)
+
+// proxy fields that are either read only or specific to the tenant owning the product
+var unexportedProxyFields = []string{
+	"service_id", "endpoint", "sandbox_endpoint", "api_backend", "created_at", "updated_at", "lock_version",
+}
+
+// ExportProduct reads the product with the given ID, together with the backends it uses,
+// and returns it as a ProductBundle that ImportProduct or ReconcileProduct accept.
+// IDs are replaced by system names, so the bundle can be imported into any tenant.
+// Custom application plans are not exported.
+func (c *ThreeScaleClient) ExportProduct(productID int64) (*ProductBundle, error) {
+	bundle, _, err := c.exportProduct(productID)
+	return bundle, err
+}
+
+// exportProduct returns the bundle along with the IDs of the exported resources indexed by system name
+func (c *ThreeScaleClient) exportProduct(productID int64) (*ProductBundle, *ProductImportResult, error) {
+	e := newProductExporter(c)
+	bundle, err := e.export(productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bundle, e.state.result, nil
+}
+
+type productExporter struct {
+	c     *ThreeScaleClient
+	state *reconcileState
+	// metric and method references indexed by ID
+	refs map[int64]MetricRefSpec
+	// exported backends indexed by ID
+	backends map[int64]*BackendApiSpec
+}
+
+func newProductExporter(c *ThreeScaleClient) *productExporter {
+	return &productExporter{
+		c:        c,
+		state:    newReconcileState(c),
+		refs:     map[int64]MetricRefSpec{},
+		backends: map[int64]*BackendApiSpec{},
+	}
+}
+
+func (e *productExporter) export(productID int64) (*ProductBundle, error) {
+	product, err := e.c.Product(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := e.state.result
+	ids.ProductID = productID
+	spec := ProductSpec{
+		SystemName:       product.Element.SystemName,
+		Name:             product.Element.Name,
+		Description:      product.Element.Description,
+		DeploymentOption: product.Element.DeploymentOption,
+		BackendVersion:   product.Element.BackendVersion,
+	}
+
+	usages, err := e.c.ListBackendapiUsages(productID)
+	if err != nil {
+		return nil, fmt.Errorf("backend usages: %w", err)
+	}
+
+	bundle := &ProductBundle{Product: spec, Backends: []BackendApiSpec{}}
+	for _, usage := range usages {
+		backend, err := e.exportBackend(usage.Element.BackendAPIID)
+		if err != nil {
+			return nil, fmt.Errorf("backend %d: %w", usage.Element.BackendAPIID, err)
+		}
+		bundle.Backends = append(bundle.Backends, *backend)
+		bundle.Product.BackendUsages = append(bundle.Product.BackendUsages, BackendUsageSpec{
+			BackendSystemName: backend.SystemName,
+			Path:              usage.Element.Path,
+		})
+	}
+
+	bundle.Product.Metrics, bundle.Product.Methods, bundle.Product.MappingRules, err = e.exportMetrics("", productMetricOwner(e.c, productID))
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := e.c.ProductProxy(productID)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+	bundle.Product.Proxy = jsonParams(proxy.Element)
+	for _, field := range unexportedProxyFields {
+		delete(bundle.Product.Proxy, field)
+	}
+
+	policies, err := e.c.Policies(productID)
+	if err != nil {
+		return nil, fmt.Errorf("policies: %w", err)
+	}
+	bundle.Product.Policies = policies.Policies
+
+	oidc, err := e.c.OIDCConfiguration(productID)
+	if err != nil {
+		return nil, fmt.Errorf("oidc configuration: %w", err)
+	}
+	oidc.Element.ID = 0
+	bundle.Product.OIDC = &oidc.Element
+
+	if bundle.Product.ApplicationPlans, err = e.exportApplicationPlans(productID); err != nil {
+		return nil, err
+	}
+
+	if bundle.Product.ActiveDocs, err = e.exportActiveDocs(productID); err != nil {
+		return nil, fmt.Errorf("activedocs: %w", err)
+	}
+
+	return bundle, nil
+}
+
+func (e *productExporter) exportBackend(backendID int64) (*BackendApiSpec, error) {
+	if spec, ok := e.backends[backendID]; ok {
+		e.state.result.BackendIDs[spec.SystemName] = backendID
+		return spec, nil
+	}
+
+	backend, err := e.c.BackendApi(backendID)
+	if err != nil {
+		return nil, err
+	}
+
+	systemName := backend.Element.SystemName
+	e.state.result.BackendIDs[systemName] = backendID
+	spec := &BackendApiSpec{
+		SystemName:      systemName,
+		Name:            backend.Element.Name,
+		Description:     backend.Element.Description,
+		PrivateEndpoint: backend.Element.PrivateEndpoint,
+	}
+
+	spec.Metrics, spec.Methods, spec.MappingRules, err = e.exportMetrics(systemName, backendMetricOwner(e.c, backendID))
+	if err != nil {
+		return nil, err
+	}
+	e.backends[backendID] = spec
+	return spec, nil
+}
+
+// exportMetrics reads the metrics, methods and mapping rules of the product (backend == "")
+// or of the given backend. The hits metric is implicit and not exported
+func (e *productExporter) exportMetrics(backend string, owner metricOwner) ([]MetricSpec, []MethodSpec, []MappingRuleSpec, error) {
+	metricList, err := owner.listMetrics()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("metrics: %w", err)
+	}
+
+	hitsID, err := owner.hitsID(metricList)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	methodList, err := owner.listMethods(hitsID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("methods: %w", err)
+	}
+
+	ids := e.state.metricIDs(backend)
+	methods := []MethodSpec{}
+	isMethod := map[int64]bool{}
+	for _, method := range methodList.Methods {
+		isMethod[method.Element.ID] = true
+		methods = append(methods, MethodSpec{
+			SystemName:  owner.systemName(method.Element.SystemName),
+			Name:        method.Element.Name,
+			Description: method.Element.Description,
+		})
+	}
+
+	metrics := []MetricSpec{}
+	for _, metric := range metricList.Metrics {
+		systemName := owner.systemName(metric.Element.SystemName)
+		ids[systemName] = metric.Element.ID
+		e.refs[metric.Element.ID] = MetricRefSpec{MetricSystemName: systemName, BackendSystemName: backend}
+		if metric.Element.ID == hitsID || isMethod[metric.Element.ID] {
+			continue
+		}
+		metrics = append(metrics, MetricSpec{
+			SystemName:  systemName,
+			Name:        metric.Element.Name,
+			Unit:        metric.Element.Unit,
+			Description: metric.Element.Description,
+		})
+	}
+
+	ruleList, err := owner.listMappingRules()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("mapping rules: %w", err)
+	}
+
+	sort.SliceStable(ruleList.MappingRules, func(i, j int) bool {
+		return ruleList.MappingRules[i].Element.Position < ruleList.MappingRules[j].Element.Position
+	})
+
+	rules := []MappingRuleSpec{}
+	for _, rule := range ruleList.MappingRules {
+		ref, ok := e.refs[rule.Element.MetricID]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("mapping rule %d: metric %d not found", rule.Element.ID, rule.Element.MetricID)
+		}
+		rules = append(rules, MappingRuleSpec{
+			HTTPMethod: rule.Element.HTTPMethod,
+			Pattern:    rule.Element.Pattern,
+			MetricRef:  ref.MetricSystemName,
+			Delta:      rule.Element.Delta,
+			Last:       rule.Element.Last,
+			Position:   rule.Element.Position,
+		})
+	}
+
+	return metrics, methods, rules, nil
+}
+
+func (e *productExporter) exportApplicationPlans(productID int64) ([]ApplicationPlanSpec, error) {
+	list, err := e.c.ListApplicationPlansByProduct(productID)
+	if err != nil {
+		return nil, fmt.Errorf("application plans: %w", err)
+	}
+
+	plans := []ApplicationPlanSpec{}
+	for _, plan := range list.Plans {
+		item := plan.Element
+		if item.Custom {
+			continue
+		}
+		e.state.result.ApplicationPlanIDs[item.SystemName] = item.ID
+
+		spec := ApplicationPlanSpec{
+			SystemName:         item.SystemName,
+			Name:               item.Name,
+			Published:          item.State == "published",
+			ApprovalRequired:   item.ApprovalRequired,
+			SetupFee:           item.SetupFee,
+			CostPerMonth:       item.CostPerMonth,
+			TrialPeriodDays:    item.TrialPeriodDays,
+			CancellationPeriod: item.CancellationPeriod,
+			Limits:             []LimitSpec{},
+			PricingRules:       []PricingRuleSpec{},
+		}
+
+		limits, err := e.c.ListApplicationPlansLimits(item.ID)
+		if err != nil {
+			return nil, fmt.Errorf("application plan %s: limits: %w", item.SystemName, err)
+		}
+		for _, limit := range limits.Limits {
+			ref, ok := e.refs[limit.Element.MetricID]
+			if !ok {
+				return nil, fmt.Errorf("application plan %s: limit %d: metric %d not found", item.SystemName, limit.Element.ID, limit.Element.MetricID)
+			}
+			spec.Limits = append(spec.Limits, LimitSpec{MetricRefSpec: ref, Period: limit.Element.Period, Value: limit.Element.Value})
+		}
+
+		rules, err := e.c.ListApplicationPlansPricingRules(item.ID)
+		if err != nil {
+			return nil, fmt.Errorf("application plan %s: pricing rules: %w", item.SystemName, err)
+		}
+		for _, rule := range rules.Rules {
+			ref, ok := e.refs[rule.Element.MetricID]
+			if !ok {
+				return nil, fmt.Errorf("application plan %s: pricing rule %d: metric %d not found", item.SystemName, rule.Element.ID, rule.Element.MetricID)
+			}
+			spec.PricingRules = append(spec.PricingRules, PricingRuleSpec{
+				MetricRefSpec: ref,
+				CostPerUnit:   rule.Element.CostPerUnit,
+				Min:           rule.Element.Min,
+				Max:           rule.Element.Max,
+			})
+		}
+
+		plans = append(plans, spec)
+	}
+
+	return plans, nil
+}
+
+func (e *productExporter) exportActiveDocs(productID int64) ([]ActiveDocSpec, error) {
+	list, err := e.c.ListActiveDocs()
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []ActiveDocSpec{}
+	for _, doc := range list.ActiveDocs {
+		item := doc.Element
+		if item.ServiceID == nil || *item.ServiceID != productID || item.SystemName == nil {
+			continue
+		}
+		e.state.result.ActiveDocIDs[*item.SystemName] = *item.ID
+		docs = append(docs, activeDocSpec(item))
+	}
+
+	return docs, nil
+}
+
+func activeDocSpec(item ActiveDocItem) ActiveDocSpec {
+	return ActiveDocSpec{
+		SystemName:             stringValue(item.SystemName),
+		Name:                   stringValue(item.Name),
+		Description:            stringValue(item.Description),
+		Published:              boolValue(item.Published),
+		SkipSwaggerValidations: boolValue(item.SkipSwaggerValidations),
+		Body:                   stringValue(item.Body),
+	}
+}
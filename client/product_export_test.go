@@ -0,0 +1,58 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestExportProduct(t *testing.T) {
+	porta := petsPorta(t, true)
+	c := porta.client()
+
+	bundle, err := c.ExportProduct(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equals(t, "pets", bundle.Product.SystemName)
+	equals(t, []BackendUsageSpec{{BackendSystemName: "pets_backend", Path: "/v1"}}, bundle.Product.BackendUsages)
+	equals(t, 1, len(bundle.Backends))
+	equals(t, "pets_backend", bundle.Backends[0].SystemName)
+	equals(t, []MetricSpec{{SystemName: "storage", Name: "Storage", Unit: "MB"}}, bundle.Backends[0].Metrics)
+	equals(t, []MethodSpec{{SystemName: "list_pets", Name: "List pets"}}, bundle.Backends[0].Methods)
+	equals(t, 2, len(bundle.Backends[0].MappingRules))
+	equals(t, "list_pets", bundle.Backends[0].MappingRules[0].MetricRef)
+	equals(t, "418", bundle.Product.Proxy["error_status_no_match"])
+	if _, ok := bundle.Product.Proxy["service_id"]; ok {
+		t.Fatal("service_id must not be exported")
+	}
+
+	equals(t, 1, len(bundle.Product.ApplicationPlans))
+	plan := bundle.Product.ApplicationPlans[0]
+	equals(t, true, plan.Published)
+	equals(t, []LimitSpec{
+		{MetricRefSpec: MetricRefSpec{MetricSystemName: "adopt"}, Period: "day", Value: 3},
+		{MetricRefSpec: MetricRefSpec{MetricSystemName: "storage", BackendSystemName: "pets_backend"}, Period: "month", Value: 1000},
+	}, plan.Limits)
+	equals(t, []PricingRuleSpec{{MetricRefSpec: MetricRefSpec{MetricSystemName: "hits"}, CostPerUnit: "0.01", Min: 1, Max: 100}}, plan.PricingRules)
+	equals(t, `{"openapi":"3.0.0"}`, bundle.Product.ActiveDocs[0].Body)
+
+	// exported bundle describes the live state
+	changes, err := c.PlanProduct(bundle, ReconcileOptions{Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changes.IsEmpty() {
+		t.Fatalf("expected empty plan; got\n%s", changes)
+	}
+	equals(t, []string{}, porta.writes())
+}
+
+func TestExportProductNotFound(t *testing.T) {
+	porta := newMockPorta(t)
+
+	_, err := porta.client().ExportProduct(404)
+	if !IsNotFound(err) {
+		t.Fatalf("expected not found error; got %v", err)
+	}
+	equals(t, "GET /admin/api/services/404.json", porta.served()[0].String())
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ImportProduct creates or updates the product described by the bundle, together with
+// the backends, metrics, methods, mapping rules, backend usages, proxy settings, policies,
+// application plans, limits, pricing rules and activedocs it declares.
+// Resources are matched by system_name and processed in dependency order, so metric
+// system names referenced by mapping rules, limits and pricing rules are resolved to the
+// IDs assigned by 3scale. Remote resources missing from the bundle are left untouched.
+func (c *ThreeScaleClient) ImportProduct(bundle *ProductBundle) (*ProductImportResult, error) {
+	if bundle == nil {
+		return nil, errors.New("ImportProduct needs not nil pointer")
+	}
+
+	plan, err := c.PlanProduct(bundle, ReconcileOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.state.result, plan.Apply()
+}
+
+func validateProductBundle(bundle *ProductBundle) error {
+	if bundle.Product.SystemName == "" {
+		return errors.New("product system_name is required")
+	}
+
+	backends := map[string]bool{}
+	for _, backend := range bundle.Backends {
+		if backend.SystemName == "" {
+			return errors.New("backend system_name is required")
+		}
+		if backends[backend.SystemName] {
+			return fmt.Errorf("backend %s declared more than once", backend.SystemName)
+		}
+		backends[backend.SystemName] = true
+	}
+
+	plans := map[string]bool{}
+	for _, plan := range bundle.Product.ApplicationPlans {
+		if plan.SystemName == "" {
+			return errors.New("application plan system_name is required")
+		}
+		if plans[plan.SystemName] {
+			return fmt.Errorf("application plan %s declared more than once", plan.SystemName)
+		}
+		plans[plan.SystemName] = true
+	}
+
+	for _, doc := range bundle.Product.ActiveDocs {
+		if doc.SystemName == "" {
+			return errors.New("activedoc system_name is required")
+		}
+	}
+
+	return nil
+}
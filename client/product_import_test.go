@@ -0,0 +1,265 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func testProductBundle() *ProductBundle {
+	return &ProductBundle{
+		Backends: []BackendApiSpec{
+			{
+				SystemName:      "pets_backend",
+				Name:            "Pets Backend",
+				PrivateEndpoint: "https://pets.internal:443",
+				Metrics: []MetricSpec{
+					{SystemName: "storage", Name: "Storage", Unit: "MB"},
+				},
+				Methods: []MethodSpec{
+					{SystemName: "list_pets", Name: "List pets"},
+				},
+				MappingRules: []MappingRuleSpec{
+					{HTTPMethod: "GET", Pattern: "/pets$", MetricRef: "list_pets", Delta: 1},
+					{HTTPMethod: "POST", Pattern: "/pets", MetricRef: "storage", Delta: 5},
+				},
+			},
+		},
+		Product: ProductSpec{
+			SystemName:     "pets",
+			Name:           "Pets API",
+			Description:    "all about pets",
+			BackendVersion: "1",
+			Proxy:          Params{"error_status_no_match": "418"},
+			Methods: []MethodSpec{
+				{SystemName: "adopt", Name: "Adopt"},
+			},
+			MappingRules: []MappingRuleSpec{
+				{HTTPMethod: "POST", Pattern: "/adopt", MetricRef: "adopt", Delta: 1, Last: true},
+			},
+			BackendUsages: []BackendUsageSpec{
+				{BackendSystemName: "pets_backend", Path: "/v1"},
+			},
+			Policies: []PolicyConfig{
+				{Name: "cors", Version: "builtin", Configuration: map[string]interface{}{"allow_credentials": true}, Enabled: true},
+				{Name: "apicast", Version: "builtin", Configuration: map[string]interface{}{}, Enabled: true},
+			},
+			ApplicationPlans: []ApplicationPlanSpec{
+				{
+					SystemName:   "basic",
+					Name:         "Basic",
+					Published:    true,
+					CostPerMonth: 10,
+					Limits: []LimitSpec{
+						{MetricRefSpec: MetricRefSpec{MetricSystemName: "adopt"}, Period: "day", Value: 3},
+						{MetricRefSpec: MetricRefSpec{MetricSystemName: "storage", BackendSystemName: "pets_backend"}, Period: "month", Value: 1000},
+					},
+					PricingRules: []PricingRuleSpec{
+						{MetricRefSpec: MetricRefSpec{MetricSystemName: "hits"}, CostPerUnit: "0.01", Min: 1, Max: 100},
+					},
+				},
+			},
+			ActiveDocs: []ActiveDocSpec{
+				{SystemName: "pets_doc", Name: "Pets", Body: `{"openapi":"3.0.0"}`, Published: true},
+			},
+		},
+	}
+}
+
+// petsPorta serves the product of testProductBundle as 3scale returns it once imported.
+// Unless imported, the product, backend and activedoc lists are empty, as in a new tenant
+func petsPorta(t *testing.T, imported bool) *mockPorta {
+	porta := newMockPorta(t)
+	porta.load("pets_product_fixture.json")
+	if !imported {
+		porta.reply(http.MethodGet, "/admin/api/services.json", http.StatusOK, ProductList{Products: []Product{}})
+		porta.reply(http.MethodGet, "/admin/api/backend_apis.json", http.StatusOK, BackendApiList{Backends: []BackendApi{}})
+		porta.reply(http.MethodGet, "/admin/api/active_docs.json", http.StatusOK, ActiveDocList{ActiveDocs: []ActiveDoc{}})
+	}
+	return porta
+}
+
+func TestImportProductCreatesResources(t *testing.T) {
+	porta := petsPorta(t, false)
+
+	result, err := porta.client().ImportProduct(testProductBundle())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equals(t, &ProductImportResult{
+		ProductID:          10,
+		MetricIDs:          map[string]int64{"hits": 11, "adopt": 12},
+		BackendIDs:         map[string]int64{"pets_backend": 20},
+		BackendMetricIDs:   map[string]map[string]int64{"pets_backend": {"hits": 21, "storage": 22, "list_pets": 23}},
+		ApplicationPlanIDs: map[string]int64{"basic": 40},
+		ActiveDocIDs:       map[string]int64{"pets_doc": 50},
+	}, result)
+
+	equals(t, []string{
+		"POST /admin/api/backend_apis.json",
+		"POST /admin/api/backend_apis/20/metrics.json",
+		"POST /admin/api/backend_apis/20/metrics/21/methods.json",
+		"POST /admin/api/backend_apis/20/mapping_rules.json",
+		"POST /admin/api/backend_apis/20/mapping_rules.json",
+		"POST /admin/api/services.json",
+		"POST /admin/api/services/10/metrics/11/methods.json",
+		"POST /admin/api/services/10/proxy/mapping_rules.json",
+		"POST /admin/api/services/10/backend_usages.json",
+		"PUT /admin/api/services/10/proxy.json",
+		"PUT /admin/api/services/10/proxy/policies.json",
+		"POST /admin/api/services/10/application_plans.json",
+		"POST /admin/api/application_plans/40/metrics/12/limits.json",
+		"POST /admin/api/application_plans/40/metrics/22/limits.json",
+		"POST /admin/api/application_plans/40/metrics/11/pricing_rules.json",
+		"POST /admin/api/active_docs.json",
+	}, porta.writes())
+
+	// Backend mapping rules reference the IDs of the newly created metric and method
+	rules := porta.calls(http.MethodPost, "/admin/api/backend_apis/20/mapping_rules.json")
+	equals(t, "23", rules[0].Params.Get("metric_id"))
+	equals(t, "22", rules[1].Params.Get("metric_id"))
+	equals(t, "5", rules[1].Params.Get("delta"))
+
+	productRule := porta.calls(http.MethodPost, "/admin/api/services/10/proxy/mapping_rules.json")[0]
+	equals(t, "12", productRule.Params.Get("metric_id"))
+	equals(t, "true", productRule.Params.Get("last"))
+
+	usage := porta.calls(http.MethodPost, "/admin/api/services/10/backend_usages.json")[0]
+	equals(t, "20", usage.Params.Get("backend_api_id"))
+	equals(t, "/v1", usage.Params.Get("path"))
+
+	equals(t, "418", porta.calls(http.MethodPut, "/admin/api/services/10/proxy.json")[0].Params.Get("error_status_no_match"))
+
+	plan := porta.calls(http.MethodPost, "/admin/api/services/10/application_plans.json")[0]
+	equals(t, "publish", plan.Params.Get("state_event"))
+	equals(t, "10", plan.Params.Get("cost_per_month"))
+
+	equals(t, "3", porta.calls(http.MethodPost, "/admin/api/application_plans/40/metrics/12/limits.json")[0].Params.Get("value"))
+	equals(t, "1000", porta.calls(http.MethodPost, "/admin/api/application_plans/40/metrics/22/limits.json")[0].Params.Get("value"))
+
+	doc := ActiveDocItem{}
+	if err := json.Unmarshal(porta.calls(http.MethodPost, "/admin/api/active_docs.json")[0].Body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, int64(10), *doc.ServiceID)
+	equals(t, true, *doc.Published)
+}
+
+func TestImportProductIsIdempotent(t *testing.T) {
+	porta := petsPorta(t, true)
+
+	result, err := porta.client().ImportProduct(testProductBundle())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equals(t, []string{}, porta.writes())
+	equals(t, int64(10), result.ProductID)
+	equals(t, map[string]int64{"basic": 40}, result.ApplicationPlanIDs)
+}
+
+func TestImportProductUpdatesChangedFields(t *testing.T) {
+	porta := petsPorta(t, true)
+
+	bundle := testProductBundle()
+	bundle.Product.Description = "new description"
+	bundle.Backends[0].MappingRules[1].Delta = 10
+	bundle.Product.ApplicationPlans[0].Limits[0].Value = 5
+	bundle.Product.ApplicationPlans[0].PricingRules[0].CostPerUnit = "0.02"
+	bundle.Product.ActiveDocs[0].Body = `{"openapi":"3.0.1"}`
+
+	if _, err := porta.client().ImportProduct(bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	// product, backend mapping rule, limit and activedoc are updated, the pricing rule is replaced
+	equals(t, []string{
+		"PUT /admin/api/backend_apis/20/mapping_rules/25.json",
+		"PUT /admin/api/services/10.json",
+		"PUT /admin/api/application_plans/40/metrics/12/limits/41.json",
+		"DELETE /admin/api/application_plans/40/metrics/11/pricing_rules/43.json",
+		"POST /admin/api/application_plans/40/metrics/11/pricing_rules.json",
+		"PUT /admin/api/active_docs/50.json",
+	}, porta.writes())
+
+	equals(t, "new description", porta.calls(http.MethodPut, "/admin/api/services/10.json")[0].Params.Get("description"))
+	equals(t, "10", porta.calls(http.MethodPut, "/admin/api/backend_apis/20/mapping_rules/25.json")[0].Params.Get("delta"))
+	equals(t, "5", porta.calls(http.MethodPut, "/admin/api/application_plans/40/metrics/12/limits/41.json")[0].Params.Get("value"))
+	equals(t, "0.02", porta.calls(http.MethodPost, "/admin/api/application_plans/40/metrics/11/pricing_rules.json")[0].Params.Get("cost_per_unit"))
+
+	doc := ActiveDocItem{}
+	if err := json.Unmarshal(porta.calls(http.MethodPut, "/admin/api/active_docs/50.json")[0].Body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, `{"openapi":"3.0.1"}`, *doc.Body)
+}
+
+func TestImportProductUnknownMetric(t *testing.T) {
+	porta := petsPorta(t, false)
+
+	bundle := testProductBundle()
+	bundle.Product.MappingRules[0].MetricRef = "unknown"
+
+	_, err := porta.client().ImportProduct(bundle)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if !strings.Contains(err.Error(), "metric unknown not found") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equals(t, []string{}, porta.writes())
+}
+
+func TestImportProductValidation(t *testing.T) {
+	porta := newMockPorta(t)
+	c := porta.client()
+
+	if _, err := c.ImportProduct(nil); err == nil {
+		t.Fatal("expected error for nil bundle")
+	}
+
+	bundle := testProductBundle()
+	bundle.Product.SystemName = ""
+	if _, err := c.ImportProduct(bundle); err == nil {
+		t.Fatal("expected error for missing product system name")
+	}
+
+	bundle = testProductBundle()
+	bundle.Backends = append(bundle.Backends, bundle.Backends[0])
+	if _, err := c.ImportProduct(bundle); err == nil {
+		t.Fatal("expected error for duplicated backend")
+	}
+
+	equals(t, []mockRequest{}, porta.served())
+}
+
+func TestImportProductRemoteError(t *testing.T) {
+	porta := petsPorta(t, false)
+	porta.reply(http.MethodPost, "/admin/api/services/10/application_plans.json", http.StatusForbidden, map[string]string{"error": "forbidden"})
+
+	result, err := porta.client().ImportProduct(testProductBundle())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if result == nil || result.ProductID != 10 {
+		t.Fatal("expected partial result with the product ID")
+	}
+
+	if !IsForbidden(errorsUnwrapAll(err)) {
+		t.Fatalf("expected forbidden error; got %v", err)
+	}
+}
+
+func errorsUnwrapAll(err error) error {
+	for {
+		unwrapper, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return err
+		}
+		err = unwrapper.Unwrap()
+	}
+}
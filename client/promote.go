@@ -0,0 +1,185 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultPromoteTimeout      = time.Minute
+	defaultPromotePollInterval = time.Second
+)
+
+// ErrProxyConfigNotDeployed is returned when no new sandbox proxy config version shows up after a deploy
+var ErrProxyConfigNotDeployed = errors.New("no new sandbox proxy config version deployed")
+
+// ErrPromotionNotVerified is returned when the latest production proxy config is not the promoted version
+var ErrPromotionNotVerified = errors.New("promoted proxy config is not the latest production version")
+
+// ErrRollbackNotApplied is returned when production does not run the rolled back proxy config version
+var ErrRollbackNotApplied = errors.New("rolled back proxy config is not the latest production version")
+
+// Promote deploys the proxy of the product to sandbox, waits for the new sandbox proxy config version, diffs it
+// against the latest production version, promotes it to production and verifies it is the latest production
+// version. opts.Review can cancel the promotion once the diff is known, and opts.Rollback rolls production back
+// to its previous version when the verification fails, see RollbackProduction. The returned result tells how far
+// the flow went
+func (c *ThreeScaleClient) Promote(productID int64, opts PromoteOptions) (*PromoteResult, error) {
+	id := strconv.FormatInt(productID, 10)
+	result := &PromoteResult{}
+
+	previousSandbox, err := c.latestProxyConfig(id, "sandbox")
+	if err != nil {
+		return result, fmt.Errorf("product %d: sandbox proxy config: %w", productID, err)
+	}
+	production, err := c.latestProxyConfig(id, "production")
+	if err != nil {
+		return result, fmt.Errorf("product %d: production proxy config: %w", productID, err)
+	}
+	if production == nil {
+		production = &ProxyConfig{Environment: "production"}
+	}
+	result.PreviousProductionVersion = production.Version
+	result.ProductionVersion = production.Version
+
+	if _, err := c.DeployProductProxy(productID); err != nil {
+		return result, fmt.Errorf("product %d: deploy: %w", productID, err)
+	}
+
+	previousVersion := 0
+	if previousSandbox != nil {
+		previousVersion = previousSandbox.Version
+	}
+	sandbox, err := c.waitForSandboxProxyConfig(id, previousVersion, opts)
+	if err != nil {
+		return result, fmt.Errorf("product %d: %w", productID, err)
+	}
+	result.SandboxVersion = sandbox.Version
+
+	result.Diff, err = DiffProxyConfigs(*production, *sandbox)
+	if err != nil {
+		return result, err
+	}
+	if opts.Review != nil {
+		if err := opts.Review(result.Diff); err != nil {
+			return result, err
+		}
+	}
+
+	if _, err := c.PromoteProxyConfig(id, "sandbox", strconv.Itoa(sandbox.Version), "production"); err != nil {
+		return result, fmt.Errorf("product %d: promote version %d: %w", productID, sandbox.Version, err)
+	}
+	result.Promoted = true
+
+	verifyErr := c.verifyProductionProxyConfig(id, sandbox.Version)
+	if verifyErr == nil {
+		result.ProductionVersion = sandbox.Version
+		return result, nil
+	}
+
+	if !opts.Rollback || result.PreviousProductionVersion == 0 {
+		return result, fmt.Errorf("product %d: %w", productID, verifyErr)
+	}
+	if err := c.RollbackProduction(productID, result.PreviousProductionVersion); err != nil {
+		return result, fmt.Errorf("product %d: %v; rollback: %w", productID, verifyErr, err)
+	}
+	result.RolledBack = true
+	result.ProductionVersion = result.PreviousProductionVersion
+	return result, fmt.Errorf("product %d: rolled back to version %d: %w", productID, result.PreviousProductionVersion, verifyErr)
+}
+
+// RollbackProduction makes production run the given production proxy config version again, e.g. the version
+// production ran before the last promotion. Nothing is promoted when the latest production version behaves the
+// same, otherwise the sandbox version is promoted again. Porta keeps the version number of a promoted proxy
+// config and serves the highest production version as the latest one, so an older version promoted again does
+// not replace a newer one and ErrRollbackNotApplied is returned: restore the product settings and Promote them
+func (c *ThreeScaleClient) RollbackProduction(productID int64, version int) error {
+	id := strconv.FormatInt(productID, 10)
+	target, err := c.GetProxyConfig(id, "production", strconv.Itoa(version))
+	if err != nil {
+		return fmt.Errorf("product %d: production proxy config version %d: %w", productID, version, err)
+	}
+
+	running, err := c.productionRuns(id, target.ProxyConfig)
+	if err != nil || running {
+		return err
+	}
+	if _, err := c.PromoteProxyConfig(id, "sandbox", strconv.Itoa(version), "production"); err != nil {
+		return fmt.Errorf("product %d: promote version %d: %w", productID, version, err)
+	}
+
+	running, err = c.productionRuns(id, target.ProxyConfig)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return fmt.Errorf("product %d: version %d: %w", productID, version, ErrRollbackNotApplied)
+	}
+	return nil
+}
+
+// productionRuns returns true when the latest production proxy config behaves as the given one
+func (c *ThreeScaleClient) productionRuns(id string, config ProxyConfig) (bool, error) {
+	production, err := c.latestProxyConfig(id, "production")
+	if err != nil {
+		return false, fmt.Errorf("production proxy config: %w", err)
+	}
+	if production == nil {
+		return false, nil
+	}
+	diff, err := DiffProxyConfigs(config, *production)
+	if err != nil {
+		return false, err
+	}
+	return diff.IsEmpty(), nil
+}
+
+// latestProxyConfig returns the latest proxy config of the environment, or nil when none was deployed yet
+func (c *ThreeScaleClient) latestProxyConfig(id, env string) (*ProxyConfig, error) {
+	latest, err := c.GetLatestProxyConfig(id, env)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &latest.ProxyConfig, nil
+}
+
+func (c *ThreeScaleClient) waitForSandboxProxyConfig(id string, previousVersion int, opts PromoteOptions) (*ProxyConfig, error) {
+	timeout, interval := opts.Timeout, opts.PollInterval
+	if timeout <= 0 {
+		timeout = defaultPromoteTimeout
+	}
+	if interval <= 0 {
+		interval = defaultPromotePollInterval
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		sandbox, err := c.latestProxyConfig(id, "sandbox")
+		if err != nil {
+			return nil, fmt.Errorf("sandbox proxy config: %w", err)
+		}
+		if sandbox != nil && sandbox.Version > previousVersion {
+			return sandbox, nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return nil, ErrProxyConfigNotDeployed
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (c *ThreeScaleClient) verifyProductionProxyConfig(id string, version int) error {
+	production, err := c.latestProxyConfig(id, "production")
+	if err != nil {
+		return fmt.Errorf("production proxy config: %w", err)
+	}
+	if production == nil || production.Version != version {
+		return ErrPromotionNotVerified
+	}
+	return nil
+}
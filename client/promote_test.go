@@ -0,0 +1,196 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const promoteDeployPath = "/admin/api/services/10/proxy/deploy.json"
+
+// petsProxyConfigVersions returns the proxy configs of the pets versions fixture: version 1 and version 2
+// with a new error status, cors policy and proxy rules
+func petsProxyConfigVersions(t *testing.T) (ProxyConfig, ProxyConfig) {
+	fixture := map[string]ProxyConfigElement{}
+	if err := json.Unmarshal(helperLoadBytes(t, "pets_proxy_config_versions_fixture.json"), &fixture); err != nil {
+		t.Fatal(err)
+	}
+	return fixture["GET /admin/api/services/10/proxy/configs/production/latest.json"].ProxyConfig,
+		fixture["GET /admin/api/services/10/proxy/configs/sandbox/latest.json"].ProxyConfig
+}
+
+// promotePorta serves the latest proxy configs of product 10, none at first. Each deploy makes the next
+// of the deployed configs the latest sandbox one, and promoting a sandbox version makes it the latest
+// production one unless a higher version already is
+func promotePorta(t *testing.T, deployed ...ProxyConfig) *mockPorta {
+	porta := newMockPorta(t)
+	latest := map[string]*ProxyConfig{}
+	for _, env := range []string{"sandbox", "production"} {
+		env := env
+		porta.handle(http.MethodGet, fmt.Sprintf("/admin/api/services/10/proxy/configs/%s/latest.json", env), func(req *http.Request) *http.Response {
+			if latest[env] == nil {
+				return helperJSONResponse(t, http.StatusNotFound, map[string]string{"status": "Not found"})
+			}
+			return helperJSONResponse(t, http.StatusOK, ProxyConfigElement{*latest[env]})
+		})
+	}
+
+	deploys := 0
+	porta.handle(http.MethodPost, promoteDeployPath, func(req *http.Request) *http.Response {
+		config := deployed[deploys]
+		deploys++
+		config.Environment = "sandbox"
+		latest["sandbox"] = &config
+		return helperJSONResponse(t, http.StatusCreated, `{"proxy": {"service_id": 10}}`)
+	})
+
+	for _, config := range deployed {
+		config := config
+		porta.handle(http.MethodPost, fmt.Sprintf("/admin/api/services/10/proxy/configs/sandbox/%d/promote.json", config.Version), func(req *http.Request) *http.Response {
+			promoted := config
+			promoted.Environment = req.PostFormValue("to")
+			if current := latest[promoted.Environment]; current == nil || current.Version < promoted.Version {
+				latest[promoted.Environment] = &promoted
+			}
+			porta.reply(http.MethodGet, fmt.Sprintf("/admin/api/services/10/proxy/configs/production/%d.json", promoted.Version), http.StatusOK, ProxyConfigElement{promoted})
+			return helperJSONResponse(t, http.StatusCreated, ProxyConfigElement{promoted})
+		})
+	}
+	return porta
+}
+
+// promoteWrites returns the paths of the deploys and promotions
+func promoteWrites(porta *mockPorta) []string {
+	paths := []string{}
+	for _, req := range porta.served(http.MethodPost) {
+		paths = append(paths, req.Path)
+	}
+	return paths
+}
+
+func TestPromote(t *testing.T) {
+	v1, v2 := petsProxyConfigVersions(t)
+	porta := promotePorta(t, v1, v2)
+	c := porta.client()
+
+	result, err := c.Promote(10, PromoteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, &PromoteResult{
+		SandboxVersion:            1,
+		PreviousProductionVersion: 0,
+		ProductionVersion:         1,
+		Diff:                      result.Diff,
+		Promoted:                  true,
+	}, result)
+	equals(t, 0, result.Diff.FromVersion)
+	equals(t, []string{promoteDeployPath, "/admin/api/services/10/proxy/configs/sandbox/1/promote.json"}, promoteWrites(porta))
+	equals(t, "production", porta.served(http.MethodPost)[1].Params.Get("to"))
+
+	porta.reset()
+	var reviewed *ProxyConfigDiff
+	result, err = c.Promote(10, PromoteOptions{Review: func(diff *ProxyConfigDiff) error {
+		reviewed = diff
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 2, result.SandboxVersion)
+	equals(t, 1, result.PreviousProductionVersion)
+	equals(t, 2, result.ProductionVersion)
+	equals(t, result.Diff, reviewed)
+	equals(t, true, strings.Contains(reviewed.String(), "+ proxy_rule DELETE /pets$"))
+	equals(t, []string{promoteDeployPath, "/admin/api/services/10/proxy/configs/sandbox/2/promote.json"}, promoteWrites(porta))
+}
+
+func TestPromoteReviewCancels(t *testing.T) {
+	v1, _ := petsProxyConfigVersions(t)
+	porta := promotePorta(t, v1)
+	c := porta.client()
+
+	cancel := errors.New("not today")
+	result, err := c.Promote(10, PromoteOptions{Review: func(diff *ProxyConfigDiff) error { return cancel }})
+	if err != cancel {
+		t.Fatalf("expected review error, got %v", err)
+	}
+	equals(t, false, result.Promoted)
+	equals(t, 1, result.SandboxVersion)
+	equals(t, []string{promoteDeployPath}, promoteWrites(porta))
+}
+
+func TestPromoteRollback(t *testing.T) {
+	v1, v2 := petsProxyConfigVersions(t)
+	porta := promotePorta(t, v1, v2)
+	c := porta.client()
+
+	if _, err := c.Promote(10, PromoteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the promotion is acknowledged but never lands in production
+	porta.reply(http.MethodPost, "/admin/api/services/10/proxy/configs/sandbox/2/promote.json", http.StatusCreated,
+		ProxyConfigElement{ProxyConfig{Version: 2, Environment: "production"}})
+	porta.reset()
+
+	result, err := c.Promote(10, PromoteOptions{Rollback: true})
+	if !errors.Is(err, ErrPromotionNotVerified) {
+		t.Fatalf("expected verification error, got %v", err)
+	}
+	equals(t, true, result.Promoted)
+	equals(t, true, result.RolledBack)
+	equals(t, 1, result.ProductionVersion)
+	// production still runs version 1, nothing to promote again
+	equals(t, []string{promoteDeployPath, "/admin/api/services/10/proxy/configs/sandbox/2/promote.json"}, promoteWrites(porta))
+	equals(t, 1, len(porta.calls(http.MethodGet, "/admin/api/services/10/proxy/configs/production/1.json")))
+}
+
+func TestRollbackProductionNotApplied(t *testing.T) {
+	v1, v2 := petsProxyConfigVersions(t)
+	porta := promotePorta(t, v1, v2)
+	c := porta.client()
+
+	// versions 1 and 2
+	for i := 0; i < 2; i++ {
+		if _, err := c.Promote(10, PromoteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the latest production version stays the highest one
+	porta.reset()
+	if err := c.RollbackProduction(10, 1); !errors.Is(err, ErrRollbackNotApplied) {
+		t.Fatalf("expected rollback not applied, got %v", err)
+	}
+	equals(t, []string{"/admin/api/services/10/proxy/configs/sandbox/1/promote.json"}, promoteWrites(porta))
+
+	porta.reset()
+	if err := c.RollbackProduction(10, 2); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{}, porta.writes())
+
+	if err := c.RollbackProduction(10, 3); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestPromoteDeployTimeout(t *testing.T) {
+	porta := promotePorta(t)
+	// deployed, but no sandbox proxy config shows up
+	porta.reply(http.MethodPost, promoteDeployPath, http.StatusCreated, `{"proxy": {"service_id": 10}}`)
+	c := porta.client()
+
+	result, err := c.Promote(10, PromoteOptions{Timeout: 5 * time.Millisecond, PollInterval: time.Millisecond})
+	if !errors.Is(err, ErrProxyConfigNotDeployed) {
+		t.Fatalf("expected deploy timeout, got %v", err)
+	}
+	equals(t, false, result.Promoted)
+	// the deploy only
+	equals(t, []string{"POST " + promoteDeployPath}, porta.writes())
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -137,6 +138,90 @@ func (c *ThreeScaleClient) PromoteProxyConfig(svcId string, env string, version
 	return pe, err
 }
 
+// UnmarshalJSON decodes the proxy config and keeps it in Raw
+func (p *ProxyConfig) UnmarshalJSON(data []byte) error {
+	type proxyConfig ProxyConfig
+	config := proxyConfig{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	*p = ProxyConfig(config)
+	p.Raw = append(json.RawMessage{}, data...)
+	return nil
+}
+
+// UnmarshalJSON decodes the policy and keeps its configuration in ConfigurationValues
+func (p *PolicyChain) UnmarshalJSON(data []byte) error {
+	type policyChain PolicyChain
+	policy := struct {
+		policyChain
+		Configuration map[string]interface{} `json:"configuration"`
+	}{}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return err
+	}
+	*p = PolicyChain(policy.policyChain)
+	p.ConfigurationValues = policy.Configuration
+	return nil
+}
+
+// MarshalJSON encodes the policy with the configuration of ConfigurationValues
+func (p PolicyChain) MarshalJSON() ([]byte, error) {
+	type policyChain PolicyChain
+	return json.Marshal(struct {
+		policyChain
+		Configuration map[string]interface{} `json:"configuration"`
+	}{policyChain(p), emptyIfNil(p.ConfigurationValues)})
+}
+
+// UnmarshalJSON decodes the proxy rule and keeps its querystring parameters in QuerystringParameterValues
+func (r *ProxyRule) UnmarshalJSON(data []byte) error {
+	type proxyRule ProxyRule
+	rule := struct {
+		proxyRule
+		QuerystringParameters map[string]interface{} `json:"querystring_parameters"`
+	}{}
+	if err := json.Unmarshal(data, &rule); err != nil {
+		return err
+	}
+	*r = ProxyRule(rule.proxyRule)
+	r.QuerystringParameterValues = rule.QuerystringParameters
+	return nil
+}
+
+// MarshalJSON encodes the proxy rule with the querystring parameters of QuerystringParameterValues
+func (r ProxyRule) MarshalJSON() ([]byte, error) {
+	type proxyRule ProxyRule
+	return json.Marshal(struct {
+		proxyRule
+		QuerystringParameters map[string]interface{} `json:"querystring_parameters"`
+	}{proxyRule(r), emptyIfNil(r.QuerystringParameterValues)})
+}
+
+// emptyIfNil encodes a missing configuration as an empty object, as Configuration does
+func emptyIfNil(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return map[string]interface{}{}
+	}
+	return values
+}
+
+// rawContent returns the content of the proxy config as read from Porta, or the encoded Content otherwise
+func (p ProxyConfig) rawContent() (json.RawMessage, error) {
+	if len(p.Raw) > 0 {
+		doc := struct {
+			Content json.RawMessage `json:"content"`
+		}{}
+		if err := json.Unmarshal(p.Raw, &doc); err != nil {
+			return nil, err
+		}
+		if len(doc.Content) > 0 {
+			return doc.Content, nil
+		}
+	}
+	return json.Marshal(p.Content)
+}
+
 func (c *ThreeScaleClient) getProxyConfig(endpoint string) (ProxyConfigElement, error) {
 	var pc ProxyConfigElement
 	req, err := c.buildGetReq(endpoint)
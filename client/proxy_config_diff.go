@@ -0,0 +1,333 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// proxy fields compared apart, or which differ between versions without changing the behaviour of APIcast
+var proxyConfigDiffIgnored = map[string]bool{
+	"id":           true,
+	"tenant_id":    true,
+	"service_id":   true,
+	"created_at":   true,
+	"updated_at":   true,
+	"deployed_at":  true,
+	"lock_version": true,
+	"hosts":        true,
+	"backend":      true,
+	"policy_chain": true,
+	"proxy_rules":  true,
+}
+
+// DiffProxyConfigs lists what changes from proxy config a to proxy config b: proxy settings, hosts, backend,
+// policies of the chain and their order, and proxy rules. Policies are matched by name and proxy rules
+// by http method and pattern
+func DiffProxyConfigs(a, b ProxyConfig) (*ProxyConfigDiff, error) {
+	diff := &ProxyConfigDiff{
+		FromEnvironment: a.Environment,
+		FromVersion:     a.Version,
+		ToEnvironment:   b.Environment,
+		ToVersion:       b.Version,
+		Changes:         []Change{},
+	}
+	from, to := a.Content.Proxy, b.Content.Proxy
+
+	fields, err := proxySettingsDiff(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > 0 {
+		diff.Changes = append(diff.Changes, Change{Action: ChangeUpdate, Kind: "proxy", Name: b.Content.SystemName, Fields: fields})
+	}
+
+	diff.Changes = append(diff.Changes, proxyHostsDiff(from.Hosts, to.Hosts)...)
+
+	if fields := backendDiff(from.Backend, to.Backend); len(fields) > 0 {
+		diff.Changes = append(diff.Changes, Change{Action: ChangeUpdate, Kind: "backend", Name: "backend", Fields: fields})
+	}
+
+	diff.Changes = append(diff.Changes, policyChainDiff(from.PolicyChain, to.PolicyChain)...)
+	diff.Changes = append(diff.Changes, proxyRulesDiff(from.ProxyRules, to.ProxyRules)...)
+
+	return diff, nil
+}
+
+// IsEmpty returns true when both proxy configs behave the same
+func (d *ProxyConfigDiff) IsEmpty() bool {
+	return len(d.Changes) == 0
+}
+
+// String renders the diff for human review
+func (d *ProxyConfigDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s v%d -> %s v%d\n", d.FromEnvironment, d.FromVersion, d.ToEnvironment, d.ToVersion)
+	if d.IsEmpty() {
+		b.WriteString("No changes\n")
+		return b.String()
+	}
+	for _, change := range d.Changes {
+		b.WriteString(change.String())
+	}
+	return b.String()
+}
+
+func proxySettingsDiff(a, b ContentProxy) ([]FieldDiff, error) {
+	current, err := proxySettings(a)
+	if err != nil {
+		return nil, err
+	}
+	desired, err := proxySettings(b)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]interface{}{}
+	for key := range current {
+		keys[key] = nil
+	}
+	for key := range desired {
+		keys[key] = nil
+	}
+
+	fields := []FieldDiff{}
+	for _, key := range sortedStringKeys(keys) {
+		if proxyConfigDiffIgnored[key] || jsonEqual(current[key], desired[key]) {
+			continue
+		}
+		fields = append(fields, FieldDiff{Field: key, Current: diffValue(current[key]), Desired: diffValue(desired[key])})
+	}
+	return fields, nil
+}
+
+func proxySettings(proxy ContentProxy) (map[string]interface{}, error) {
+	raw, err := json.Marshal(proxy)
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]interface{}{}
+	err = json.Unmarshal(raw, &settings)
+	return settings, err
+}
+
+// diffValue prints strings as is and other values as JSON, null being empty
+func diffValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	return jsonString(value)
+}
+
+func proxyHostsDiff(a, b []string) []Change {
+	current, desired := map[string]bool{}, map[string]bool{}
+	for _, host := range a {
+		current[host] = true
+	}
+	for _, host := range b {
+		desired[host] = true
+	}
+
+	changes := []Change{}
+	for _, host := range sortedUniqueStrings(a, b) {
+		switch {
+		case desired[host] && !current[host]:
+			changes = append(changes, Change{Action: ChangeCreate, Kind: "host", Name: host})
+		case current[host] && !desired[host]:
+			changes = append(changes, Change{Action: ChangeDelete, Kind: "host", Name: host})
+		}
+	}
+	return changes
+}
+
+func sortedUniqueStrings(lists ...[]string) []string {
+	seen := map[string]bool{}
+	values := []string{}
+	for _, list := range lists {
+		for _, value := range list {
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+func backendDiff(a, b Backend) []FieldDiff {
+	fields := []FieldDiff{}
+	if a.Endpoint != b.Endpoint {
+		fields = append(fields, FieldDiff{Field: "endpoint", Current: a.Endpoint, Desired: b.Endpoint})
+	}
+	if a.Host != b.Host {
+		fields = append(fields, FieldDiff{Field: "host", Current: a.Host, Desired: b.Host})
+	}
+	return fields
+}
+
+// diffKeys returns a key per item, repeated keys suffixed by their occurrence, e.g. "headers#2"
+func diffKeys(n int, key func(int) string) []string {
+	keys := make([]string, 0, n)
+	seen := map[string]int{}
+	for idx := 0; idx < n; idx++ {
+		k := key(idx)
+		seen[k]++
+		if seen[k] > 1 {
+			k = fmt.Sprintf("%s#%d", k, seen[k])
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func policyChainDiff(a, b []PolicyChain) []Change {
+	currentKeys := diffKeys(len(a), func(idx int) string { return a[idx].Name })
+	desiredKeys := diffKeys(len(b), func(idx int) string { return b[idx].Name })
+
+	current := map[string]PolicyChain{}
+	for idx, key := range currentKeys {
+		current[key] = a[idx]
+	}
+	desired := map[string]PolicyChain{}
+	for idx, key := range desiredKeys {
+		desired[key] = b[idx]
+	}
+
+	changes := []Change{}
+	for _, key := range currentKeys {
+		if _, ok := desired[key]; !ok {
+			changes = append(changes, Change{Action: ChangeDelete, Kind: "policy", Name: key})
+		}
+	}
+	for _, key := range desiredKeys {
+		policy := desired[key]
+		previous, ok := current[key]
+		if !ok {
+			changes = append(changes, Change{Action: ChangeCreate, Kind: "policy", Name: key, Fields: []FieldDiff{
+				{Field: "version", Desired: policy.Version},
+				{Field: "configuration", Desired: configurationString(policy.ConfigurationValues)},
+			}})
+			continue
+		}
+
+		fields := []FieldDiff{}
+		if previous.Version != policy.Version {
+			fields = append(fields, FieldDiff{Field: "version", Current: previous.Version, Desired: policy.Version})
+		}
+		if !policyConfigurationEqual(previous.ConfigurationValues, policy.ConfigurationValues) {
+			fields = append(fields, FieldDiff{Field: "configuration", Current: configurationString(previous.ConfigurationValues), Desired: configurationString(policy.ConfigurationValues)})
+		}
+		if len(fields) > 0 {
+			changes = append(changes, Change{Action: ChangeUpdate, Kind: "policy", Name: key, Fields: fields})
+		}
+	}
+
+	// order of the policies found in both chains
+	currentOrder, desiredOrder := []string{}, []string{}
+	for _, key := range currentKeys {
+		if _, ok := desired[key]; ok {
+			currentOrder = append(currentOrder, key)
+		}
+	}
+	for _, key := range desiredKeys {
+		if _, ok := current[key]; ok {
+			desiredOrder = append(desiredOrder, key)
+		}
+	}
+	if strings.Join(currentOrder, ",") != strings.Join(desiredOrder, ",") {
+		changes = append(changes, Change{Action: ChangeUpdate, Kind: "policy_chain", Name: "order", Fields: []FieldDiff{
+			{Field: "order", Current: strings.Join(currentOrder, ","), Desired: strings.Join(desiredOrder, ",")},
+		}})
+	}
+
+	return changes
+}
+
+// configurationString prints the configuration as JSON, a missing one being empty
+func configurationString(configuration map[string]interface{}) string {
+	if len(configuration) == 0 {
+		return "{}"
+	}
+	return jsonString(configuration)
+}
+
+func proxyRuleFields(rule ProxyRule) map[string]string {
+	redirectURL := ""
+	switch v := rule.RedirectURL.(type) {
+	case nil:
+	case string:
+		redirectURL = v
+	default:
+		redirectURL = jsonString(v)
+	}
+
+	return map[string]string{
+		"metric_system_name":     rule.MetricSystemName,
+		"delta":                  strconv.FormatInt(rule.Delta, 10),
+		"last":                   strconv.FormatBool(rule.Last),
+		"position":               strconv.Itoa(rule.Position),
+		"querystring_parameters": configurationString(rule.QuerystringParameterValues),
+		"redirect_url":           redirectURL,
+	}
+}
+
+var proxyRuleFieldNames = []string{"delta", "last", "metric_system_name", "position", "querystring_parameters", "redirect_url"}
+
+// proxyRuleOptionalFields holds the value of the optional fields of proxy rules when not set
+var proxyRuleOptionalFields = map[string]string{"querystring_parameters": "{}", "redirect_url": ""}
+
+func proxyRulesDiff(a, b []ProxyRule) []Change {
+	key := func(rule ProxyRule) string { return rule.HTTPMethod + " " + rule.Pattern }
+	currentKeys := diffKeys(len(a), func(idx int) string { return key(a[idx]) })
+	desiredKeys := diffKeys(len(b), func(idx int) string { return key(b[idx]) })
+
+	current := map[string]ProxyRule{}
+	for idx, k := range currentKeys {
+		current[k] = a[idx]
+	}
+	desired := map[string]ProxyRule{}
+	for idx, k := range desiredKeys {
+		desired[k] = b[idx]
+	}
+
+	changes := []Change{}
+	for _, k := range currentKeys {
+		if _, ok := desired[k]; !ok {
+			changes = append(changes, Change{Action: ChangeDelete, Kind: "proxy_rule", Name: k})
+		}
+	}
+	for _, k := range desiredKeys {
+		desiredFields := proxyRuleFields(desired[k])
+		previous, ok := current[k]
+		if !ok {
+			fields := []FieldDiff{}
+			for _, name := range proxyRuleFieldNames {
+				// optional fields are listed when set
+				if unset, ok := proxyRuleOptionalFields[name]; ok && desiredFields[name] == unset {
+					continue
+				}
+				fields = append(fields, FieldDiff{Field: name, Desired: desiredFields[name]})
+			}
+			changes = append(changes, Change{Action: ChangeCreate, Kind: "proxy_rule", Name: k, Fields: fields})
+			continue
+		}
+
+		currentFields := proxyRuleFields(previous)
+		fields := []FieldDiff{}
+		for _, name := range proxyRuleFieldNames {
+			if currentFields[name] != desiredFields[name] {
+				fields = append(fields, FieldDiff{Field: name, Current: currentFields[name], Desired: desiredFields[name]})
+			}
+		}
+		if len(fields) > 0 {
+			changes = append(changes, Change{Action: ChangeUpdate, Kind: "proxy_rule", Name: k, Fields: fields})
+		}
+	}
+	return changes
+}
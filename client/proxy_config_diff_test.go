@@ -0,0 +1,132 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestDiffProxyConfigs(t *testing.T) {
+	porta := newMockPorta(t)
+	porta.load("pets_proxy_config_versions_fixture.json")
+	c := porta.client()
+
+	production, err := c.GetLatestProxyConfig("10", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sandbox, err := c.GetLatestProxyConfig("10", "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := DiffProxyConfigs(production.ProxyConfig, sandbox.ProxyConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []Change{
+		{Action: ChangeUpdate, Kind: "proxy", Name: "pets", Fields: []FieldDiff{{Field: "error_status_no_match", Current: "0", Desired: "418"}}},
+		{Action: ChangeCreate, Kind: "host", Name: "pets-staging.example.com"},
+		{Action: ChangeDelete, Kind: "host", Name: "pets.example.com"},
+		{Action: ChangeCreate, Kind: "policy", Name: "cors", Fields: []FieldDiff{
+			{Field: "version", Desired: "builtin"},
+			{Field: "configuration", Desired: `{"allow_origin":"*"}`},
+		}},
+		{Action: ChangeDelete, Kind: "proxy_rule", Name: "POST /pets$"},
+		{Action: ChangeUpdate, Kind: "proxy_rule", Name: "GET /pets$", Fields: []FieldDiff{{Field: "delta", Current: "1", Desired: "5"}}},
+		{Action: ChangeCreate, Kind: "proxy_rule", Name: "DELETE /pets$", Fields: []FieldDiff{
+			{Field: "delta", Desired: "1"},
+			{Field: "last", Desired: "false"},
+			{Field: "metric_system_name", Desired: "hits"},
+			{Field: "position", Desired: "2"},
+		}},
+	}, diff.Changes)
+
+	equals(t, `production v1 -> sandbox v2
+~ proxy pets
+    error_status_no_match: "0" -> "418"
++ host pets-staging.example.com
+- host pets.example.com
++ policy cors
+    version: "builtin"
+    configuration: "{\"allow_origin\":\"*\"}"
+- proxy_rule POST /pets$
+~ proxy_rule GET /pets$
+    delta: "1" -> "5"
++ proxy_rule DELETE /pets$
+    delta: "1"
+    last: "false"
+    metric_system_name: "hits"
+    position: "2"
+`, diff.String())
+
+	same, err := DiffProxyConfigs(sandbox.ProxyConfig, sandbox.ProxyConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, true, same.IsEmpty())
+	equals(t, "sandbox v2 -> sandbox v2\nNo changes\n", same.String())
+}
+
+func TestDiffProxyConfigsPolicyChain(t *testing.T) {
+	config := func(chain ...PolicyChain) ProxyConfig {
+		return ProxyConfig{Version: 1, Environment: "sandbox", Content: Content{Proxy: ContentProxy{PolicyChain: chain}}}
+	}
+
+	diff, err := DiffProxyConfigs(
+		config(
+			PolicyChain{Name: "headers", Version: "builtin", ConfigurationValues: map[string]interface{}{"request": []interface{}{}}},
+			PolicyChain{Name: "apicast", Version: "builtin"},
+			PolicyChain{Name: "headers", Version: "builtin"},
+			PolicyChain{Name: "signer", Version: "0.1.0"},
+		),
+		config(
+			PolicyChain{Name: "apicast", Version: "builtin"},
+			PolicyChain{Name: "headers", Version: "builtin"},
+			PolicyChain{Name: "signer", Version: "0.2.0"},
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []Change{
+		{Action: ChangeDelete, Kind: "policy", Name: "headers#2"},
+		{Action: ChangeUpdate, Kind: "policy", Name: "headers", Fields: []FieldDiff{{Field: "configuration", Current: `{"request":[]}`, Desired: "{}"}}},
+		{Action: ChangeUpdate, Kind: "policy", Name: "signer", Fields: []FieldDiff{{Field: "version", Current: "0.1.0", Desired: "0.2.0"}}},
+		{Action: ChangeUpdate, Kind: "policy_chain", Name: "order", Fields: []FieldDiff{{Field: "order", Current: "headers,apicast,signer", Desired: "apicast,headers,signer"}}},
+	}, diff.Changes)
+}
+
+func TestDiffProxyConfigsProxyRuleOptionalFields(t *testing.T) {
+	config := func(rules ...ProxyRule) ProxyConfig {
+		return ProxyConfig{Version: 1, Environment: "sandbox", Content: Content{Proxy: ContentProxy{ProxyRules: rules}}}
+	}
+	rule := ProxyRule{HTTPMethod: "GET", Pattern: "/pets$", MetricSystemName: "hits", Delta: 1}
+	redirected := rule
+	redirected.QuerystringParameterValues = map[string]interface{}{"kind": "cat"}
+	redirected.RedirectURL = "https://cats.example.com"
+
+	diff, err := DiffProxyConfigs(config(rule), config(redirected))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []Change{
+		{Action: ChangeUpdate, Kind: "proxy_rule", Name: "GET /pets$", Fields: []FieldDiff{
+			{Field: "querystring_parameters", Current: "{}", Desired: `{"kind":"cat"}`},
+			{Field: "redirect_url", Current: "", Desired: "https://cats.example.com"},
+		}},
+	}, diff.Changes)
+
+	diff, err = DiffProxyConfigs(config(), config(redirected))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []Change{
+		{Action: ChangeCreate, Kind: "proxy_rule", Name: "GET /pets$", Fields: []FieldDiff{
+			{Field: "delta", Desired: "1"},
+			{Field: "last", Desired: "false"},
+			{Field: "metric_system_name", Desired: "hits"},
+			{Field: "position", Desired: "0"},
+			{Field: "querystring_parameters", Desired: `{"kind":"cat"}`},
+			{Field: "redirect_url", Desired: "https://cats.example.com"},
+		}},
+	}, diff.Changes)
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const defaultProxyConfigWatchInterval = 30 * time.Second
+
+// NewProxyConfigWatcher returns a watcher of the latest proxy config versions of the environment,
+// for the given products or the whole account
+func NewProxyConfigWatcher(c *ThreeScaleClient, opts ProxyConfigWatchOptions) *ProxyConfigWatcher {
+	if opts.Environment == "" {
+		opts.Environment = "production"
+	}
+	return &ProxyConfigWatcher{client: c, opts: opts, seen: map[int64]ProxyConfig{}}
+}
+
+// Poll reads the latest proxy configs once and returns an event per product whose version changed since the
+// previous poll, sorted by product ID. The first poll only records the versions unless opts.EmitInitial is set.
+// Products without proxy config in the environment are skipped, and those which had one at the previous poll,
+// e.g. deleted products, get a Removed event. The versions are only recorded when the poll succeeds, so a
+// failed poll loses no event. Poll must not be called concurrently
+func (w *ProxyConfigWatcher) Poll() ([]ProxyConfigEvent, error) {
+	configs, err := w.latest()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]ProxyConfig, len(configs))
+	events := []ProxyConfigEvent{}
+	for _, config := range configs {
+		// the content of a proxy config is its product
+		productID := config.Content.ID
+		seen[productID] = config
+		previous, ok := w.seen[productID]
+		if ok && previous.Version == config.Version {
+			continue
+		}
+		if !w.polled && !w.opts.EmitInitial {
+			continue
+		}
+
+		event := ProxyConfigEvent{ProductID: productID, Environment: w.opts.Environment, Current: config}
+		from := ProxyConfig{Environment: w.opts.Environment}
+		if ok {
+			event.Previous = &previous
+			from = previous
+		}
+		event.Diff, err = DiffProxyConfigs(from, config)
+		if err != nil {
+			return nil, fmt.Errorf("product %d: %w", productID, err)
+		}
+		events = append(events, event)
+	}
+
+	for productID, previous := range w.seen {
+		if _, ok := seen[productID]; ok {
+			continue
+		}
+		previous := previous
+		event := ProxyConfigEvent{
+			ProductID:   productID,
+			Environment: w.opts.Environment,
+			Previous:    &previous,
+			Current:     ProxyConfig{Environment: w.opts.Environment},
+			Removed:     true,
+		}
+		event.Diff, err = DiffProxyConfigs(previous, event.Current)
+		if err != nil {
+			return nil, fmt.Errorf("product %d: %w", productID, err)
+		}
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ProductID < events[j].ProductID })
+
+	w.seen = seen
+	w.polled = true
+	return events, nil
+}
+
+// latest returns the latest proxy configs of the watched products, sorted by product ID
+func (w *ProxyConfigWatcher) latest() ([]ProxyConfig, error) {
+	configs := []ProxyConfig{}
+
+	if len(w.opts.ProductIDs) == 0 {
+		list, err := w.client.ListAccountProxyConfigs(w.opts.Environment, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s proxy configs: %w", w.opts.Environment, err)
+		}
+		for _, element := range list.ProxyConfigs {
+			configs = append(configs, element.ProxyConfig)
+		}
+	}
+
+	for _, productID := range w.opts.ProductIDs {
+		config, err := w.client.latestProxyConfig(strconv.FormatInt(productID, 10), w.opts.Environment)
+		if err != nil {
+			return nil, fmt.Errorf("product %d: %s proxy config: %w", productID, w.opts.Environment, err)
+		}
+		if config == nil {
+			continue
+		}
+		configs = append(configs, *config)
+	}
+
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Content.ID < configs[j].Content.ID })
+	return configs, nil
+}
+
+// Watch polls every opts.Interval until the context is done, and sends the events of each poll to the
+// returned channel, which is closed once the context is done. Errors of the polls go to opts.OnError
+func (w *ProxyConfigWatcher) Watch(ctx context.Context) <-chan ProxyConfigEvent {
+	interval := w.opts.Interval
+	if interval <= 0 {
+		interval = defaultProxyConfigWatchInterval
+	}
+
+	events := make(chan ProxyConfigEvent)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			polled, err := w.Poll()
+			if err != nil && w.opts.OnError != nil {
+				w.opts.OnError(err)
+			}
+			for _, event := range polled {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
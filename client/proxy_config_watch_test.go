@@ -0,0 +1,202 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// catsProxyConfig returns the pets proxy config as the one of the cats product, 60
+func catsProxyConfig(config ProxyConfig) ProxyConfig {
+	config.Content.ID = 60
+	config.Content.SystemName = "cats"
+	config.Content.Proxy.ServiceID = 60
+	return config
+}
+
+// serveSandboxProxyConfigs makes the given proxy configs the latest sandbox ones of their products,
+// read by product or listed for the whole account
+func serveSandboxProxyConfigs(porta *mockPorta, configs ...ProxyConfig) {
+	list := ProxyConfigList{ProxyConfigs: []ProxyConfigElement{}}
+	for _, config := range configs {
+		config.Environment = "sandbox"
+		porta.reply(http.MethodGet, fmt.Sprintf("/admin/api/services/%d/proxy/configs/sandbox/latest.json", config.Content.ID), http.StatusOK, ProxyConfigElement{config})
+		list.ProxyConfigs = append(list.ProxyConfigs, ProxyConfigElement{config})
+	}
+	porta.reply(http.MethodGet, "/admin/api/account/proxy_configs/sandbox.json", http.StatusOK, list)
+}
+
+func TestProxyConfigWatcherPoll(t *testing.T) {
+	v1, v2 := petsProxyConfigVersions(t)
+	porta := newMockPorta(t)
+	c := porta.client()
+	expected, err := DiffProxyConfigs(v1, v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []ProxyConfigWatchOptions{
+		{Environment: "sandbox"},
+		{Environment: "sandbox", ProductIDs: []int64{60, 10}},
+	} {
+		serveSandboxProxyConfigs(porta, v1, catsProxyConfig(v1))
+		watcher := NewProxyConfigWatcher(c, opts)
+
+		// the first poll records the versions
+		events, err := watcher.Poll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		equals(t, []ProxyConfigEvent{}, events)
+
+		serveSandboxProxyConfigs(porta, v2, catsProxyConfig(v1))
+		events, err = watcher.Poll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		equals(t, 1, len(events))
+		event := events[0]
+		equals(t, int64(10), event.ProductID)
+		equals(t, "sandbox", event.Environment)
+		equals(t, 2, event.Current.Version)
+		equals(t, 1, event.Previous.Version)
+		equals(t, expected.Changes, event.Diff.Changes)
+
+		// nothing new
+		events, err = watcher.Poll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		equals(t, 0, len(events))
+	}
+}
+
+func TestProxyConfigWatcherEmitInitial(t *testing.T) {
+	v1, _ := petsProxyConfigVersions(t)
+	porta := newMockPorta(t)
+	serveSandboxProxyConfigs(porta, v1)
+	c := porta.client()
+
+	watcher := NewProxyConfigWatcher(c, ProxyConfigWatchOptions{Environment: "sandbox", ProductIDs: []int64{10, 60}, EmitInitial: true})
+	events, err := watcher.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cats has no proxy config yet
+	equals(t, 1, len(events))
+	equals(t, int64(10), events[0].ProductID)
+	equals(t, (*ProxyConfig)(nil), events[0].Previous)
+	equals(t, 0, events[0].Diff.FromVersion)
+	equals(t, false, events[0].Diff.IsEmpty())
+}
+
+func TestProxyConfigWatcherWatch(t *testing.T) {
+	v1, v2 := petsProxyConfigVersions(t)
+	porta := newMockPorta(t)
+	c := porta.client()
+
+	// fail the second poll, then serve a new version
+	polls := 0
+	porta.handle(http.MethodGet, "/admin/api/services/10/proxy/configs/sandbox/latest.json", func(req *http.Request) *http.Response {
+		polls++
+		switch polls {
+		case 1:
+			return helperJSONResponse(t, http.StatusOK, ProxyConfigElement{v1})
+		case 2:
+			return helperJSONResponse(t, http.StatusServiceUnavailable, map[string]string{"error": "down"})
+		}
+		return helperJSONResponse(t, http.StatusOK, ProxyConfigElement{v2})
+	})
+
+	errs := make(chan error, 10)
+	watcher := NewProxyConfigWatcher(c, ProxyConfigWatchOptions{
+		Environment: "sandbox",
+		ProductIDs:  []int64{10},
+		Interval:    time.Millisecond,
+		OnError:     func(err error) { errs <- err },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := watcher.Watch(ctx)
+
+	select {
+	case event := <-events:
+		equals(t, 2, event.Current.Version)
+		equals(t, 1, event.Previous.Version)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	equals(t, 1, len(errs))
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestProxyConfigWatcherRemoved(t *testing.T) {
+	v1, _ := petsProxyConfigVersions(t)
+	porta := newMockPorta(t)
+	serveSandboxProxyConfigs(porta, v1, catsProxyConfig(v1))
+	c := porta.client()
+
+	watcher := NewProxyConfigWatcher(c, ProxyConfigWatchOptions{Environment: "sandbox"})
+	if _, err := watcher.Poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// cats is deleted
+	serveSandboxProxyConfigs(porta, v1)
+	events, err := watcher.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 1, len(events))
+	equals(t, int64(60), events[0].ProductID)
+	equals(t, true, events[0].Removed)
+	equals(t, 1, events[0].Previous.Version)
+	equals(t, 0, events[0].Current.Version)
+	equals(t, false, events[0].Diff.IsEmpty())
+
+	// reported once
+	events, err = watcher.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 0, len(events))
+}
+
+func TestProxyConfigWatcherFailedPoll(t *testing.T) {
+	v1, v2 := petsProxyConfigVersions(t)
+	porta := newMockPorta(t)
+	serveSandboxProxyConfigs(porta, v1, catsProxyConfig(v1))
+	c := porta.client()
+
+	watcher := NewProxyConfigWatcher(c, ProxyConfigWatchOptions{Environment: "sandbox"})
+	if _, err := watcher.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	serveSandboxProxyConfigs(porta, v2, catsProxyConfig(v2))
+
+	// the previous cats version cannot be diffed
+	valid := watcher.seen[60]
+	invalid := valid
+	invalid.Content.Proxy.PolicyChain = []PolicyChain{{Name: "apicast", ConfigurationValues: map[string]interface{}{"ttl": math.Inf(1)}}}
+	watcher.seen[60] = invalid
+	if _, err := watcher.Poll(); err == nil {
+		t.Fatal("expected diff error")
+	}
+	equals(t, 1, watcher.seen[10].Version)
+
+	watcher.seen[60] = valid
+	events, err := watcher.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 2, len(events))
+	equals(t, int64(10), events[0].ProductID)
+	equals(t, 2, events[0].Current.Version)
+	equals(t, int64(60), events[1].ProductID)
+}
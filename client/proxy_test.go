@@ -231,3 +231,33 @@ func TestListAccountProxyConfigsPerPage(t *testing.T) {
 		}
 	})
 }
+
+func TestProxyConfigConfigurationValues(t *testing.T) {
+	porta := newMockPorta(t)
+	porta.reply(http.MethodGet, "/admin/api/services/10/proxy/configs/sandbox/latest.json", http.StatusOK, `{"proxy_config": {"id": 63, "version": 1, "environment": "sandbox", "content": {"proxy": {
+		"policy_chain": [{"name": "cors", "version": "builtin", "configuration": {"allow_credentials": true}}],
+		"proxy_rules": [{"http_method": "GET", "pattern": "/pets", "querystring_parameters": {"kind": "cat"}}]
+	}}}}`)
+
+	config, err := porta.client().GetLatestProxyConfig("10", "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := config.ProxyConfig.Content.Proxy.PolicyChain[0]
+	equals(t, Configuration{}, policy.Configuration)
+	equals(t, map[string]interface{}{"allow_credentials": true}, policy.ConfigurationValues)
+	rule := config.ProxyConfig.Content.Proxy.ProxyRules[0]
+	equals(t, map[string]interface{}{"kind": "cat"}, rule.QuerystringParameterValues)
+
+	raw, err := json.Marshal([]interface{}{policy, rule, PolicyChain{Name: "apicast"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := []map[string]interface{}{}
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, map[string]interface{}{"allow_credentials": true}, encoded[0]["configuration"])
+	equals(t, map[string]interface{}{"kind": "cat"}, encoded[1]["querystring_parameters"])
+	equals(t, map[string]interface{}{}, encoded[2]["configuration"])
+}
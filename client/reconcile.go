@@ -0,0 +1,1568 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PlanProduct compares the desired product bundle with the live tenant and returns the ordered
+// list of changes needed to converge, without modifying anything.
+// Creations and updates come first in dependency order, deletions (only with opts.Prune) last,
+// in reverse dependency order. opts.DryRun is ignored.
+func (c *ThreeScaleClient) PlanProduct(bundle *ProductBundle, opts ReconcileOptions) (*ChangePlan, error) {
+	if bundle == nil {
+		return nil, errors.New("PlanProduct needs not nil pointer")
+	}
+
+	if err := validateProductBundle(bundle); err != nil {
+		return nil, err
+	}
+
+	p := &productPlanner{
+		c:    c,
+		opts: opts,
+		plan: &ChangePlan{Changes: []Change{}, state: newReconcileState(c)},
+	}
+
+	for idx := range bundle.Backends {
+		if err := p.planBackend(&bundle.Backends[idx]); err != nil {
+			return nil, fmt.Errorf("backend %s: %w", bundle.Backends[idx].SystemName, err)
+		}
+	}
+
+	if err := p.planProduct(&bundle.Product); err != nil {
+		return nil, fmt.Errorf("product %s: %w", bundle.Product.SystemName, err)
+	}
+
+	p.plan.Changes = append(p.plan.Changes, p.deletes...)
+
+	return p.plan, nil
+}
+
+// ReconcileProduct computes the change plan for the bundle and applies it, unless opts.DryRun is set.
+// The returned plan lists the changes applied or, on dry run, the changes that would be applied
+func (c *ThreeScaleClient) ReconcileProduct(bundle *ProductBundle, opts ReconcileOptions) (*ChangePlan, error) {
+	plan, err := c.PlanProduct(bundle, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		return plan, nil
+	}
+
+	return plan, plan.Apply()
+}
+
+// Apply executes the changes in order. It stops at the first failing change
+func (p *ChangePlan) Apply() error {
+	for idx := range p.Changes {
+		change := &p.Changes[idx]
+		if change.apply == nil {
+			continue
+		}
+		if err := change.apply(); err != nil {
+			return fmt.Errorf("%s %s %s: %w", change.Action, change.Kind, change.Name, err)
+		}
+	}
+	return nil
+}
+
+// IsEmpty returns true when live and desired state already match
+func (p *ChangePlan) IsEmpty() bool {
+	return len(p.Changes) == 0
+}
+
+// String renders the plan for human review
+func (p *ChangePlan) String() string {
+	if p.IsEmpty() {
+		return "No changes\n"
+	}
+
+	var b strings.Builder
+	for _, change := range p.Changes {
+		b.WriteString(change.String())
+	}
+	return b.String()
+}
+
+// String renders the change for human review
+func (c Change) String() string {
+	symbol := map[ChangeAction]string{ChangeCreate: "+", ChangeUpdate: "~", ChangeDelete: "-"}[c.Action]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\n", symbol, c.Kind, c.Name)
+	for _, field := range c.Fields {
+		switch {
+		case c.Action == ChangeCreate:
+			fmt.Fprintf(&b, "    %s: %s\n", field.Field, printableValue(field.Desired))
+		case isLongValue(field.Current) || isLongValue(field.Desired):
+			fmt.Fprintf(&b, "    %s: changed (%d -> %d bytes)\n", field.Field, len(field.Current), len(field.Desired))
+		default:
+			fmt.Fprintf(&b, "    %s: %q -> %q\n", field.Field, field.Current, field.Desired)
+		}
+	}
+	return b.String()
+}
+
+func isLongValue(s string) bool {
+	return len(s) > 80 || strings.Contains(s, "\n")
+}
+
+func printableValue(s string) string {
+	if isLongValue(s) {
+		return fmt.Sprintf("(%d bytes)", len(s))
+	}
+	return strconv.Quote(s)
+}
+
+// fieldDiffs lists desired params that differ from current ones, sorted by field name
+func fieldDiffs(desired, current Params) []FieldDiff {
+	diffs := []FieldDiff{}
+	for k, v := range changedParams(desired, current) {
+		diffs = append(diffs, FieldDiff{Field: k, Current: current[k], Desired: v})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+// reconcileState holds the IDs of the resources handled by a plan.
+// IDs of existing resources are known when planning, the rest are filled in as changes are applied
+type reconcileState struct {
+	c      *ThreeScaleClient
+	result *ProductImportResult
+	// hits metric ID per owner, where "" is the product and any other key a backend system_name
+	hits map[string]int64
+}
+
+func newReconcileState(c *ThreeScaleClient) *reconcileState {
+	return &reconcileState{
+		c: c,
+		result: &ProductImportResult{
+			MetricIDs:          map[string]int64{},
+			BackendIDs:         map[string]int64{},
+			BackendMetricIDs:   map[string]map[string]int64{},
+			ApplicationPlanIDs: map[string]int64{},
+			ActiveDocIDs:       map[string]int64{},
+		},
+		hits: map[string]int64{},
+	}
+}
+
+func (s *reconcileState) owner(backend string) metricOwner {
+	if backend == "" {
+		return productMetricOwner(s.c, s.result.ProductID)
+	}
+	return backendMetricOwner(s.c, s.result.BackendIDs[backend])
+}
+
+func (s *reconcileState) metricIDs(backend string) map[string]int64 {
+	if backend == "" {
+		return s.result.MetricIDs
+	}
+	if _, ok := s.result.BackendMetricIDs[backend]; !ok {
+		s.result.BackendMetricIDs[backend] = map[string]int64{}
+	}
+	return s.result.BackendMetricIDs[backend]
+}
+
+func (s *reconcileState) hitsID(backend string) (int64, error) {
+	if id, ok := s.hits[backend]; ok {
+		return id, nil
+	}
+
+	owner := s.owner(backend)
+	list, err := owner.listMetrics()
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := owner.hitsID(list)
+	if err != nil {
+		return 0, err
+	}
+	s.hits[backend] = id
+	return id, nil
+}
+
+// loadHits registers the hits metric 3scale creates along with products and backends
+func (s *reconcileState) loadHits(backend string) error {
+	id, err := s.hitsID(backend)
+	if err != nil {
+		return err
+	}
+	s.metricIDs(backend)["hits"] = id
+	return nil
+}
+
+func (s *reconcileState) metricID(ref MetricRefSpec) (int64, error) {
+	id, ok := s.metricIDs(ref.BackendSystemName)[ref.MetricSystemName]
+	if !ok {
+		if ref.BackendSystemName != "" {
+			return 0, fmt.Errorf("metric %s not found in backend %s", ref.MetricSystemName, ref.BackendSystemName)
+		}
+		return 0, fmt.Errorf("metric %s not found", ref.MetricSystemName)
+	}
+	return id, nil
+}
+
+type productPlanner struct {
+	c    *ThreeScaleClient
+	opts ReconcileOptions
+	plan *ChangePlan
+	// deletes are collected apart and appended to the plan in reverse dependency order
+	deletes []Change
+
+	// remote backends indexed by system_name, loaded on first use
+	backends map[string]BackendApiItem
+	// remote metrics of backends not present in the bundle, loaded on first use
+	loadedBackends map[string]bool
+	// system names of the backends used by the remote product
+	usedBackends []string
+}
+
+func (p *productPlanner) add(change Change) {
+	p.plan.Changes = append(p.plan.Changes, change)
+}
+
+// addDeletes queues deletes ahead of the ones queued before,
+// as resources are planned after the resources they depend on
+func (p *productPlanner) addDeletes(changes ...Change) {
+	p.deletes = append(changes, p.deletes...)
+}
+
+func (p *productPlanner) remoteBackends() (map[string]BackendApiItem, error) {
+	if p.backends != nil {
+		return p.backends, nil
+	}
+
+	list, err := p.c.ListBackendApis()
+	if err != nil {
+		return nil, err
+	}
+
+	p.backends = map[string]BackendApiItem{}
+	for _, backend := range list.Backends {
+		p.backends[backend.Element.SystemName] = backend.Element
+	}
+	return p.backends, nil
+}
+
+func (p *productPlanner) planBackend(spec *BackendApiSpec) error {
+	backends, err := p.remoteBackends()
+	if err != nil {
+		return err
+	}
+
+	state := p.plan.state
+	systemName := spec.SystemName
+	desired := Params{
+		"name":             spec.Name,
+		"description":      spec.Description,
+		"private_endpoint": spec.PrivateEndpoint,
+	}
+
+	current, exists := backends[systemName]
+	if !exists {
+		params := Params{"system_name": systemName}
+		for k, v := range desired {
+			params[k] = v
+		}
+		p.add(Change{
+			Action: ChangeCreate,
+			Kind:   "backend_api",
+			Name:   systemName,
+			Fields: fieldDiffs(params, Params{}),
+			apply: func() error {
+				created, err := p.c.CreateBackendApi(params)
+				if err != nil {
+					return err
+				}
+				state.result.BackendIDs[systemName] = created.Element.ID
+				return state.loadHits(systemName)
+			},
+		})
+	} else {
+		state.result.BackendIDs[systemName] = current.ID
+		if params := changedParams(desired, jsonParams(current)); len(params) > 0 {
+			backendID := current.ID
+			p.add(Change{
+				Action: ChangeUpdate,
+				Kind:   "backend_api",
+				Name:   systemName,
+				Fields: fieldDiffs(desired, jsonParams(current)),
+				apply: func() error {
+					_, err := p.c.UpdateBackendApi(backendID, params)
+					return err
+				},
+			})
+		}
+	}
+
+	return p.planMetrics(systemName, exists, spec.Metrics, spec.Methods, spec.MappingRules)
+}
+
+// planMetrics plans metrics, methods and mapping rules of the product (backend == "")
+// or of the given backend
+func (p *productPlanner) planMetrics(backend string, exists bool, metrics []MetricSpec, methods []MethodSpec, rules []MappingRuleSpec) error {
+	state := p.plan.state
+	ids := state.metricIDs(backend)
+	owner := state.owner(backend)
+	prefix := backend
+	if backend == "" {
+		prefix = "product"
+	}
+
+	remoteMetrics := map[string]MetricItem{}
+	remoteMethods := map[string]MethodItem{}
+	remoteRules := []MappingRuleItem{}
+	if exists {
+		metricList, err := owner.listMetrics()
+		if err != nil {
+			return fmt.Errorf("metrics: %w", err)
+		}
+
+		hitsID, err := owner.hitsID(metricList)
+		if err != nil {
+			return err
+		}
+		state.hits[backend] = hitsID
+
+		// metric list includes methods as well
+		for _, metric := range metricList.Metrics {
+			systemName := owner.systemName(metric.Element.SystemName)
+			remoteMetrics[systemName] = metric.Element
+			ids[systemName] = metric.Element.ID
+		}
+
+		methodList, err := owner.listMethods(hitsID)
+		if err != nil {
+			return fmt.Errorf("methods: %w", err)
+		}
+
+		for _, method := range methodList.Methods {
+			systemName := owner.systemName(method.Element.SystemName)
+			remoteMethods[systemName] = method.Element
+			delete(remoteMetrics, systemName)
+		}
+
+		ruleList, err := owner.listMappingRules()
+		if err != nil {
+			return fmt.Errorf("mapping rules: %w", err)
+		}
+
+		for _, rule := range ruleList.MappingRules {
+			remoteRules = append(remoteRules, rule.Element)
+		}
+	}
+
+	// every metric known by system name, remote or about to be created
+	known := map[string]bool{"hits": true}
+	for systemName := range ids {
+		known[systemName] = true
+	}
+
+	desiredMetrics := map[string]bool{"hits": true}
+	for _, spec := range metrics {
+		spec := spec
+		desiredMetrics[spec.SystemName] = true
+		known[spec.SystemName] = true
+		desired := Params{"friendly_name": spec.Name, "unit": spec.Unit, "description": spec.Description}
+		name := prefix + "/" + spec.SystemName
+
+		current, ok := remoteMetrics[spec.SystemName]
+		if !ok {
+			params := Params{"system_name": spec.SystemName}
+			for k, v := range desired {
+				params[k] = v
+			}
+			p.add(Change{
+				Action: ChangeCreate,
+				Kind:   "metric",
+				Name:   name,
+				Fields: fieldDiffs(params, Params{}),
+				apply: func() error {
+					created, err := state.owner(backend).createMetric(params)
+					if err != nil {
+						return err
+					}
+					state.metricIDs(backend)[spec.SystemName] = created.Element.ID
+					return nil
+				},
+			})
+			continue
+		}
+
+		if params := changedParams(desired, jsonParams(current)); len(params) > 0 {
+			metricID := current.ID
+			p.add(Change{
+				Action: ChangeUpdate,
+				Kind:   "metric",
+				Name:   name,
+				Fields: fieldDiffs(desired, jsonParams(current)),
+				apply: func() error {
+					_, err := state.owner(backend).updateMetric(metricID, params)
+					return err
+				},
+			})
+		}
+	}
+
+	desiredMethods := map[string]bool{}
+	for _, spec := range methods {
+		spec := spec
+		desiredMethods[spec.SystemName] = true
+		known[spec.SystemName] = true
+		desired := Params{"friendly_name": spec.Name, "description": spec.Description}
+		name := prefix + "/" + spec.SystemName
+
+		current, ok := remoteMethods[spec.SystemName]
+		if !ok {
+			params := Params{"system_name": spec.SystemName}
+			for k, v := range desired {
+				params[k] = v
+			}
+			p.add(Change{
+				Action: ChangeCreate,
+				Kind:   "method",
+				Name:   name,
+				Fields: fieldDiffs(params, Params{}),
+				apply: func() error {
+					hitsID, err := state.hitsID(backend)
+					if err != nil {
+						return err
+					}
+					created, err := state.owner(backend).createMethod(hitsID, params)
+					if err != nil {
+						return err
+					}
+					state.metricIDs(backend)[spec.SystemName] = created.Element.ID
+					return nil
+				},
+			})
+			continue
+		}
+
+		if params := changedParams(desired, jsonParams(current)); len(params) > 0 {
+			methodID := current.ID
+			p.add(Change{
+				Action: ChangeUpdate,
+				Kind:   "method",
+				Name:   name,
+				Fields: fieldDiffs(desired, jsonParams(current)),
+				apply: func() error {
+					hitsID, err := state.hitsID(backend)
+					if err != nil {
+						return err
+					}
+					_, err = state.owner(backend).updateMethod(hitsID, methodID, params)
+					return err
+				},
+			})
+		}
+	}
+
+	// reverse index to show metric system names in mapping rule diffs
+	metricNames := map[int64]string{}
+	for systemName, id := range ids {
+		metricNames[id] = systemName
+	}
+
+	remoteRulesByKey := map[string]MappingRuleItem{}
+	for _, rule := range remoteRules {
+		remoteRulesByKey[mappingRuleKey(rule.HTTPMethod, rule.Pattern)] = rule
+	}
+
+	desiredRules := map[string]bool{}
+	for _, spec := range rules {
+		spec := spec
+		if !known[spec.MetricRef] {
+			return fmt.Errorf("mapping rule %s %s: metric %s not found", spec.HTTPMethod, spec.Pattern, spec.MetricRef)
+		}
+
+		key := mappingRuleKey(spec.HTTPMethod, spec.Pattern)
+		desiredRules[key] = true
+		desired := mappingRuleFields(spec)
+		name := prefix + "/" + key
+
+		current, ok := remoteRulesByKey[key]
+		if !ok {
+			p.add(Change{
+				Action: ChangeCreate,
+				Kind:   "mapping_rule",
+				Name:   name,
+				Fields: fieldDiffs(desired, Params{}),
+				apply: func() error {
+					params, err := mappingRuleParams(spec, state.metricIDs(backend))
+					if err != nil {
+						return err
+					}
+					_, err = state.owner(backend).createMappingRule(params)
+					return err
+				},
+			})
+			continue
+		}
+
+		currentFields := jsonParams(current)
+		currentFields["metric"] = metricNames[current.MetricID]
+		if spec.Position == 0 {
+			delete(currentFields, "position")
+		}
+		if diffs := fieldDiffs(desired, currentFields); len(diffs) > 0 {
+			ruleID := current.ID
+			p.add(Change{
+				Action: ChangeUpdate,
+				Kind:   "mapping_rule",
+				Name:   name,
+				Fields: diffs,
+				apply: func() error {
+					params, err := mappingRuleParams(spec, state.metricIDs(backend))
+					if err != nil {
+						return err
+					}
+					_, err = state.owner(backend).updateMappingRule(ruleID, changedParams(params, jsonParams(current)))
+					return err
+				},
+			})
+		}
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+
+	// mapping rules go before the metrics they reference, methods before their parent metric
+	deletes := []Change{}
+	for _, rule := range remoteRules {
+		key := mappingRuleKey(rule.HTTPMethod, rule.Pattern)
+		if desiredRules[key] {
+			continue
+		}
+		ruleID := rule.ID
+		deletes = append(deletes, Change{
+			Action: ChangeDelete,
+			Kind:   "mapping_rule",
+			Name:   prefix + "/" + key,
+			apply: func() error {
+				return state.owner(backend).deleteMappingRule(ruleID)
+			},
+		})
+	}
+
+	for _, systemName := range sortedNames(remoteMethods) {
+		if desiredMethods[systemName] {
+			continue
+		}
+		methodID := remoteMethods[systemName].ID
+		deletes = append(deletes, Change{
+			Action: ChangeDelete,
+			Kind:   "method",
+			Name:   prefix + "/" + systemName,
+			apply: func() error {
+				hitsID, err := state.hitsID(backend)
+				if err != nil {
+					return err
+				}
+				return state.owner(backend).deleteMethod(hitsID, methodID)
+			},
+		})
+	}
+
+	for _, systemName := range sortedNames(remoteMetrics) {
+		if desiredMetrics[systemName] {
+			continue
+		}
+		metricID := remoteMetrics[systemName].ID
+		deletes = append(deletes, Change{
+			Action: ChangeDelete,
+			Kind:   "metric",
+			Name:   prefix + "/" + systemName,
+			apply: func() error {
+				return state.owner(backend).deleteMetric(metricID)
+			},
+		})
+	}
+
+	p.addDeletes(deletes...)
+	return nil
+}
+
+// sortedNames returns the keys of a map indexed by system name, sorted
+func sortedNames(m interface{}) []string {
+	names := []string{}
+	switch typed := m.(type) {
+	case map[string]MetricItem:
+		for name := range typed {
+			names = append(names, name)
+		}
+	case map[string]MethodItem:
+		for name := range typed {
+			names = append(names, name)
+		}
+	case map[string]ApplicationPlanItem:
+		for name := range typed {
+			names = append(names, name)
+		}
+	case map[string]ActiveDocItem:
+		for name := range typed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func mappingRuleKey(httpMethod, pattern string) string {
+	return httpMethod + " " + pattern
+}
+
+// mappingRuleFields describes a mapping rule for diffing, referencing the metric by system name
+func mappingRuleFields(spec MappingRuleSpec) Params {
+	fields := Params{
+		"http_method": spec.HTTPMethod,
+		"pattern":     spec.Pattern,
+		"metric":      spec.MetricRef,
+		"delta":       strconv.Itoa(spec.Delta),
+		"last":        strconv.FormatBool(spec.Last),
+	}
+	if spec.Position != 0 {
+		fields["position"] = strconv.Itoa(spec.Position)
+	}
+	return fields
+}
+
+func mappingRuleParams(spec MappingRuleSpec, metricIDs map[string]int64) (Params, error) {
+	metricID, ok := metricIDs[spec.MetricRef]
+	if !ok {
+		return nil, fmt.Errorf("metric %s not found", spec.MetricRef)
+	}
+
+	params := mappingRuleFields(spec)
+	delete(params, "metric")
+	params["metric_id"] = strconv.FormatInt(metricID, 10)
+	return params, nil
+}
+
+func (p *productPlanner) planProduct(spec *ProductSpec) error {
+	list, err := p.c.ListProducts()
+	if err != nil {
+		return err
+	}
+
+	state := p.plan.state
+	var current *ProductItem
+	for idx := range list.Products {
+		if list.Products[idx].Element.SystemName == spec.SystemName {
+			current = &list.Products[idx].Element
+			break
+		}
+	}
+
+	desired := Params{"name": spec.Name, "description": spec.Description}
+	if spec.DeploymentOption != "" {
+		desired["deployment_option"] = spec.DeploymentOption
+	}
+	if spec.BackendVersion != "" {
+		desired["backend_version"] = spec.BackendVersion
+	}
+
+	exists := current != nil
+	if !exists {
+		params := Params{"system_name": spec.SystemName}
+		for k, v := range desired {
+			params[k] = v
+		}
+		p.add(Change{
+			Action: ChangeCreate,
+			Kind:   "product",
+			Name:   spec.SystemName,
+			Fields: fieldDiffs(params, Params{}),
+			apply: func() error {
+				// CreateProduct sets the name on its own
+				createParams := Params{}
+				for k, v := range params {
+					if k != "name" {
+						createParams[k] = v
+					}
+				}
+				created, err := p.c.CreateProduct(spec.Name, createParams)
+				if err != nil {
+					return err
+				}
+				state.result.ProductID = created.Element.ID
+				return state.loadHits("")
+			},
+		})
+	} else {
+		state.result.ProductID = current.ID
+		if params := changedParams(desired, jsonParams(current)); len(params) > 0 {
+			productID := current.ID
+			p.add(Change{
+				Action: ChangeUpdate,
+				Kind:   "product",
+				Name:   spec.SystemName,
+				Fields: fieldDiffs(desired, jsonParams(current)),
+				apply: func() error {
+					_, err := p.c.UpdateProduct(productID, params)
+					return err
+				},
+			})
+		}
+	}
+
+	if err := p.planMetrics("", exists, spec.Metrics, spec.Methods, spec.MappingRules); err != nil {
+		return err
+	}
+
+	if err := p.planBackendUsages(spec.SystemName, exists, spec.BackendUsages); err != nil {
+		return fmt.Errorf("backend usages: %w", err)
+	}
+
+	if err := p.planProxy(spec.SystemName, exists, spec.Proxy); err != nil {
+		return fmt.Errorf("proxy: %w", err)
+	}
+
+	if err := p.planPolicies(spec.SystemName, exists, spec.Policies); err != nil {
+		return fmt.Errorf("policies: %w", err)
+	}
+
+	if err := p.planOIDC(spec.SystemName, exists, spec.OIDC); err != nil {
+		return fmt.Errorf("oidc configuration: %w", err)
+	}
+
+	if err := p.planApplicationPlans(spec.SystemName, exists, spec.ApplicationPlans); err != nil {
+		return err
+	}
+
+	return p.planActiveDocs(spec.SystemName, exists, spec.ActiveDocs)
+}
+
+func (p *productPlanner) planBackendUsages(product string, exists bool, usages []BackendUsageSpec) error {
+	state := p.plan.state
+	if len(usages) == 0 && !p.opts.Prune {
+		return nil
+	}
+
+	backends, err := p.remoteBackends()
+	if err != nil {
+		return err
+	}
+
+	remoteUsages := map[int64]BackendAPIUsageItem{}
+	if exists {
+		list, err := p.c.ListBackendapiUsages(state.result.ProductID)
+		if err != nil {
+			return err
+		}
+		for _, usage := range list {
+			remoteUsages[usage.Element.BackendAPIID] = usage.Element
+		}
+
+		for systemName, backend := range backends {
+			if _, ok := remoteUsages[backend.ID]; ok {
+				p.usedBackends = append(p.usedBackends, systemName)
+			}
+		}
+		sort.Strings(p.usedBackends)
+	}
+
+	desiredBackendIDs := map[int64]bool{}
+	for _, spec := range usages {
+		spec := spec
+		name := product + "/" + spec.BackendSystemName
+
+		backendID, ok := state.result.BackendIDs[spec.BackendSystemName]
+		if !ok {
+			if _, ok := backends[spec.BackendSystemName]; !ok {
+				// neither remote nor in the bundle
+				if !p.backendInBundle(spec.BackendSystemName) {
+					return fmt.Errorf("backend %s not found", spec.BackendSystemName)
+				}
+			} else {
+				backendID = backends[spec.BackendSystemName].ID
+				state.result.BackendIDs[spec.BackendSystemName] = backendID
+			}
+		}
+		desiredBackendIDs[backendID] = true
+
+		current, ok := remoteUsages[backendID]
+		if !ok || backendID == 0 {
+			p.add(Change{
+				Action: ChangeCreate,
+				Kind:   "backend_usage",
+				Name:   name,
+				Fields: fieldDiffs(Params{"path": spec.Path}, Params{}),
+				apply: func() error {
+					params := Params{
+						"backend_api_id": strconv.FormatInt(state.result.BackendIDs[spec.BackendSystemName], 10),
+						"path":           spec.Path,
+					}
+					_, err := p.c.CreateBackendapiUsage(state.result.ProductID, params)
+					return err
+				},
+			})
+			continue
+		}
+
+		if current.Path != spec.Path {
+			usageID := current.ID
+			p.add(Change{
+				Action: ChangeUpdate,
+				Kind:   "backend_usage",
+				Name:   name,
+				Fields: []FieldDiff{{Field: "path", Current: current.Path, Desired: spec.Path}},
+				apply: func() error {
+					_, err := p.c.UpdateBackendapiUsage(state.result.ProductID, usageID, Params{"path": spec.Path})
+					return err
+				},
+			})
+		}
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+
+	deletes := []Change{}
+	for _, systemName := range p.usedBackends {
+		backendID := backends[systemName].ID
+		if desiredBackendIDs[backendID] {
+			continue
+		}
+		usageID := remoteUsages[backendID].ID
+		deletes = append(deletes, Change{
+			Action: ChangeDelete,
+			Kind:   "backend_usage",
+			Name:   product + "/" + systemName,
+			apply: func() error {
+				return p.c.DeleteBackendapiUsage(state.result.ProductID, usageID)
+			},
+		})
+	}
+
+	p.addDeletes(deletes...)
+	return nil
+}
+
+func (p *productPlanner) backendInBundle(systemName string) bool {
+	_, ok := p.plan.state.result.BackendMetricIDs[systemName]
+	return ok
+}
+
+func (p *productPlanner) planProxy(product string, exists bool, desired Params) error {
+	if len(desired) == 0 {
+		return nil
+	}
+
+	state := p.plan.state
+	current := Params{}
+	if exists {
+		proxy, err := p.c.ProductProxy(state.result.ProductID)
+		if err != nil {
+			return err
+		}
+		current = jsonParams(proxy.Element)
+	}
+
+	params := changedParams(desired, current)
+	if len(params) == 0 {
+		return nil
+	}
+
+	p.add(Change{
+		Action: ChangeUpdate,
+		Kind:   "proxy",
+		Name:   product,
+		Fields: fieldDiffs(desired, current),
+		apply: func() error {
+			_, err := p.c.UpdateProductProxy(state.result.ProductID, params)
+			return err
+		},
+	})
+	return nil
+}
+
+func (p *productPlanner) planPolicies(product string, exists bool, policies []PolicyConfig) error {
+	// a nil chain leaves the remote chain untouched
+	if policies == nil {
+		return nil
+	}
+
+	state := p.plan.state
+	desired := &PoliciesConfigList{Policies: policies}
+	current := &PoliciesConfigList{Policies: []PolicyConfig{}}
+	if exists {
+		var err error
+		current, err = p.c.Policies(state.result.ProductID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if equalJSON(current.Policies, desired.Policies) {
+		return nil
+	}
+
+	currentJSON, _ := json.Marshal(current.Policies)
+	desiredJSON, _ := json.Marshal(desired.Policies)
+	p.add(Change{
+		Action: ChangeUpdate,
+		Kind:   "policy_chain",
+		Name:   product,
+		Fields: []FieldDiff{{Field: "policies", Current: string(currentJSON), Desired: string(desiredJSON)}},
+		apply: func() error {
+			_, err := p.c.UpdatePolicies(state.result.ProductID, desired)
+			return err
+		},
+	})
+	return nil
+}
+
+func (p *productPlanner) planOIDC(product string, exists bool, desired *OIDCConfigurationItem) error {
+	if desired == nil {
+		return nil
+	}
+
+	state := p.plan.state
+	current := Params{}
+	if exists {
+		conf, err := p.c.OIDCConfiguration(state.result.ProductID)
+		if err != nil {
+			return err
+		}
+		current = jsonParams(conf.Element)
+	}
+
+	desiredFields := jsonParams(desired)
+	delete(desiredFields, "id")
+	diffs := fieldDiffs(desiredFields, current)
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	p.add(Change{
+		Action: ChangeUpdate,
+		Kind:   "oidc_configuration",
+		Name:   product,
+		Fields: diffs,
+		apply: func() error {
+			conf := &OIDCConfiguration{Element: *desired}
+			conf.Element.ID = 0
+			_, err := p.c.UpdateOIDCConfiguration(state.result.ProductID, conf)
+			return err
+		},
+	})
+	return nil
+}
+
+func applicationPlanFields(spec *ApplicationPlanSpec) Params {
+	state := "hidden"
+	if spec.Published {
+		state = "published"
+	}
+	return Params{
+		"name":                spec.Name,
+		"state":               state,
+		"approval_required":   strconv.FormatBool(spec.ApprovalRequired),
+		"setup_fee":           strconv.FormatFloat(spec.SetupFee, 'f', -1, 64),
+		"cost_per_month":      strconv.FormatFloat(spec.CostPerMonth, 'f', -1, 64),
+		"trial_period_days":   strconv.Itoa(spec.TrialPeriodDays),
+		"cancellation_period": strconv.Itoa(spec.CancellationPeriod),
+	}
+}
+
+// applicationPlanParams converts plan fields into endpoint params, where state is set through state events
+func applicationPlanParams(fields Params) Params {
+	params := Params{}
+	for k, v := range fields {
+		params[k] = v
+	}
+	switch params["state"] {
+	case "published":
+		params["state_event"] = "publish"
+	case "hidden":
+		params["state_event"] = "hide"
+	}
+	delete(params, "state")
+	return params
+}
+
+func (p *productPlanner) planApplicationPlans(product string, exists bool, plans []ApplicationPlanSpec) error {
+	state := p.plan.state
+	if len(plans) == 0 && !p.opts.Prune {
+		return nil
+	}
+
+	remotePlans := map[string]ApplicationPlanItem{}
+	if exists {
+		list, err := p.c.ListApplicationPlansByProduct(state.result.ProductID)
+		if err != nil {
+			return fmt.Errorf("application plans: %w", err)
+		}
+		for _, plan := range list.Plans {
+			remotePlans[plan.Element.SystemName] = plan.Element
+		}
+	}
+
+	desiredPlans := map[string]bool{}
+	for idx := range plans {
+		spec := &plans[idx]
+		desiredPlans[spec.SystemName] = true
+		systemName := spec.SystemName
+		name := product + "/" + systemName
+		desired := applicationPlanFields(spec)
+
+		current, planExists := remotePlans[systemName]
+		if !planExists {
+			fields := Params{"system_name": systemName}
+			for k, v := range desired {
+				fields[k] = v
+			}
+			p.add(Change{
+				Action: ChangeCreate,
+				Kind:   "application_plan",
+				Name:   name,
+				Fields: fieldDiffs(fields, Params{}),
+				apply: func() error {
+					params := applicationPlanParams(fields)
+					if params["state_event"] == "hide" {
+						// plans are created hidden
+						delete(params, "state_event")
+					}
+					created, err := p.c.CreateApplicationPlan(state.result.ProductID, params)
+					if err != nil {
+						return err
+					}
+					state.result.ApplicationPlanIDs[systemName] = created.Element.ID
+					return nil
+				},
+			})
+		} else {
+			state.result.ApplicationPlanIDs[systemName] = current.ID
+			if fields := changedParams(desired, jsonParams(current)); len(fields) > 0 {
+				planID := current.ID
+				p.add(Change{
+					Action: ChangeUpdate,
+					Kind:   "application_plan",
+					Name:   name,
+					Fields: fieldDiffs(desired, jsonParams(current)),
+					apply: func() error {
+						_, err := p.c.UpdateApplicationPlan(state.result.ProductID, planID, applicationPlanParams(fields))
+						return err
+					},
+				})
+			}
+		}
+
+		if err := p.planLimits(name, systemName, planExists, spec.Limits); err != nil {
+			return fmt.Errorf("application plan %s: %w", systemName, err)
+		}
+
+		if err := p.planPricingRules(name, systemName, planExists, spec.PricingRules); err != nil {
+			return fmt.Errorf("application plan %s: %w", systemName, err)
+		}
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+
+	deletes := []Change{}
+	for _, systemName := range sortedNames(remotePlans) {
+		if desiredPlans[systemName] {
+			continue
+		}
+		planID := remotePlans[systemName].ID
+		deletes = append(deletes, Change{
+			Action: ChangeDelete,
+			Kind:   "application_plan",
+			Name:   product + "/" + systemName,
+			apply: func() error {
+				return p.c.DeleteApplicationPlan(state.result.ProductID, planID)
+			},
+		})
+	}
+
+	p.addDeletes(deletes...)
+	return nil
+}
+
+// knownMetricID resolves a metric reference with the IDs known at plan time.
+// Returns false when the metric is about to be created
+func (p *productPlanner) knownMetricID(ref MetricRefSpec) (int64, bool, error) {
+	state := p.plan.state
+	if ref.BackendSystemName != "" {
+		if err := p.loadBackendMetrics(ref.BackendSystemName); err != nil {
+			return 0, false, err
+		}
+	}
+
+	if id, ok := state.metricIDs(ref.BackendSystemName)[ref.MetricSystemName]; ok {
+		return id, true, nil
+	}
+
+	// hits comes along with new products and backends
+	if ref.MetricSystemName != "hits" && !p.metricPlanned(ref) {
+		_, err := state.metricID(ref)
+		return 0, false, err
+	}
+	return 0, false, nil
+}
+
+// metricPlanned returns true when the plan creates the referenced metric
+func (p *productPlanner) metricPlanned(ref MetricRefSpec) bool {
+	owner := ref.BackendSystemName
+	if owner == "" {
+		owner = "product"
+	}
+	name := owner + "/" + ref.MetricSystemName
+	for _, change := range p.plan.Changes {
+		if change.Action == ChangeCreate && (change.Kind == "metric" || change.Kind == "method") && change.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadBackendMetrics fetches metric IDs of backends referenced but not present in the bundle
+func (p *productPlanner) loadBackendMetrics(systemName string) error {
+	if p.backendInBundle(systemName) || p.loadedBackends[systemName] {
+		return nil
+	}
+
+	backends, err := p.remoteBackends()
+	if err != nil {
+		return err
+	}
+
+	backend, ok := backends[systemName]
+	if !ok {
+		return fmt.Errorf("backend %s not found", systemName)
+	}
+
+	owner := backendMetricOwner(p.c, backend.ID)
+	list, err := owner.listMetrics()
+	if err != nil {
+		return err
+	}
+
+	state := p.plan.state
+	state.result.BackendIDs[systemName] = backend.ID
+	ids := state.metricIDs(systemName)
+	for _, metric := range list.Metrics {
+		ids[owner.systemName(metric.Element.SystemName)] = metric.Element.ID
+	}
+
+	if p.loadedBackends == nil {
+		p.loadedBackends = map[string]bool{}
+	}
+	p.loadedBackends[systemName] = true
+	return nil
+}
+
+func metricRefName(ref MetricRefSpec) string {
+	if ref.BackendSystemName != "" {
+		return ref.BackendSystemName + "." + ref.MetricSystemName
+	}
+	return ref.MetricSystemName
+}
+
+// metricRefNameByID names a remote metric of the product or of the backends it uses
+// the way limits and pricing rules reference it
+func (p *productPlanner) metricRefNameByID(id int64) (string, error) {
+	if name, ok := p.findMetricRefName(id); ok {
+		return name, nil
+	}
+
+	// metrics of backends the bundle does not reference are not loaded yet
+	for _, systemName := range p.usedBackends {
+		if err := p.loadBackendMetrics(systemName); err != nil {
+			return "", err
+		}
+	}
+
+	if name, ok := p.findMetricRefName(id); ok {
+		return name, nil
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+func (p *productPlanner) findMetricRefName(id int64) (string, bool) {
+	state := p.plan.state
+	for systemName, metricID := range state.result.MetricIDs {
+		if metricID == id {
+			return systemName, true
+		}
+	}
+
+	for backend, ids := range state.result.BackendMetricIDs {
+		for systemName, metricID := range ids {
+			if metricID == id {
+				return metricRefName(MetricRefSpec{MetricSystemName: systemName, BackendSystemName: backend}), true
+			}
+		}
+	}
+	return "", false
+}
+
+func (p *productPlanner) planLimits(planName, planSystemName string, planExists bool, limits []LimitSpec) error {
+	state := p.plan.state
+	remoteLimits := map[string]ApplicationPlanLimitItem{}
+	if planExists && (len(limits) > 0 || p.opts.Prune) {
+		list, err := p.c.ListApplicationPlansLimits(state.result.ApplicationPlanIDs[planSystemName])
+		if err != nil {
+			return fmt.Errorf("limits: %w", err)
+		}
+		for _, limit := range list.Limits {
+			remoteLimits[limitKey(limit.Element.MetricID, limit.Element.Period)] = limit.Element
+		}
+	}
+
+	desiredLimits := map[string]bool{}
+	for _, spec := range limits {
+		spec := spec
+		name := planName + "/" + metricRefName(spec.MetricRefSpec) + "/" + spec.Period
+
+		metricID, known, err := p.knownMetricID(spec.MetricRefSpec)
+		if err != nil {
+			return fmt.Errorf("limit: %w", err)
+		}
+
+		current, ok := remoteLimits[limitKey(metricID, spec.Period)]
+		if known {
+			desiredLimits[limitKey(metricID, spec.Period)] = true
+		}
+
+		if !known || !ok {
+			p.add(Change{
+				Action: ChangeCreate,
+				Kind:   "limit",
+				Name:   name,
+				Fields: []FieldDiff{{Field: "value", Desired: strconv.Itoa(spec.Value)}},
+				apply: func() error {
+					metricID, err := state.metricID(spec.MetricRefSpec)
+					if err != nil {
+						return err
+					}
+					params := Params{"period": spec.Period, "value": strconv.Itoa(spec.Value)}
+					_, err = p.c.CreateApplicationPlanLimit(state.result.ApplicationPlanIDs[planSystemName], metricID, params)
+					return err
+				},
+			})
+			continue
+		}
+
+		if current.Value != spec.Value {
+			limitID := current.ID
+			p.add(Change{
+				Action: ChangeUpdate,
+				Kind:   "limit",
+				Name:   name,
+				Fields: []FieldDiff{{Field: "value", Current: strconv.Itoa(current.Value), Desired: strconv.Itoa(spec.Value)}},
+				apply: func() error {
+					params := Params{"value": strconv.Itoa(spec.Value)}
+					_, err := p.c.UpdateApplicationPlanLimit(state.result.ApplicationPlanIDs[planSystemName], metricID, limitID, params)
+					return err
+				},
+			})
+		}
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+
+	deletes := []Change{}
+	for key, limit := range remoteLimits {
+		if desiredLimits[key] {
+			continue
+		}
+		metric, err := p.metricRefNameByID(limit.MetricID)
+		if err != nil {
+			return fmt.Errorf("limit: %w", err)
+		}
+		limit := limit
+		deletes = append(deletes, Change{
+			Action: ChangeDelete,
+			Kind:   "limit",
+			Name:   planName + "/" + metric + "/" + limit.Period,
+			apply: func() error {
+				return p.c.DeleteApplicationPlanLimit(state.result.ApplicationPlanIDs[planSystemName], limit.MetricID, limit.ID)
+			},
+		})
+	}
+
+	p.addDeletes(sortedChanges(deletes)...)
+	return nil
+}
+
+// sortedChanges sorts changes by name
+func sortedChanges(changes []Change) []Change {
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+func limitKey(metricID int64, period string) string {
+	return fmt.Sprintf("%d/%s", metricID, period)
+}
+
+func (p *productPlanner) planPricingRules(planName, planSystemName string, planExists bool, rules []PricingRuleSpec) error {
+	state := p.plan.state
+	remoteRules := map[string]ApplicationPlanPricingRuleItem{}
+	if planExists && (len(rules) > 0 || p.opts.Prune) {
+		list, err := p.c.ListApplicationPlansPricingRules(state.result.ApplicationPlanIDs[planSystemName])
+		if err != nil {
+			return fmt.Errorf("pricing rules: %w", err)
+		}
+		for _, rule := range list.Rules {
+			remoteRules[pricingRuleKey(rule.Element.MetricID, rule.Element.Min, rule.Element.Max)] = rule.Element
+		}
+	}
+
+	desiredRules := map[string]bool{}
+	for _, spec := range rules {
+		spec := spec
+		name := fmt.Sprintf("%s/%s/%d-%d", planName, metricRefName(spec.MetricRefSpec), spec.Min, spec.Max)
+
+		metricID, known, err := p.knownMetricID(spec.MetricRefSpec)
+		if err != nil {
+			return fmt.Errorf("pricing rule: %w", err)
+		}
+
+		key := pricingRuleKey(metricID, spec.Min, spec.Max)
+		current, ok := remoteRules[key]
+		if known {
+			desiredRules[key] = true
+		}
+
+		create := func() error {
+			metricID, err := state.metricID(spec.MetricRefSpec)
+			if err != nil {
+				return err
+			}
+			params := Params{
+				"cost_per_unit": spec.CostPerUnit,
+				"min":           strconv.Itoa(spec.Min),
+				"max":           strconv.Itoa(spec.Max),
+			}
+			_, err = p.c.CreateApplicationPlanPricingRule(state.result.ApplicationPlanIDs[planSystemName], metricID, params)
+			return err
+		}
+
+		if !known || !ok {
+			p.add(Change{
+				Action: ChangeCreate,
+				Kind:   "pricing_rule",
+				Name:   name,
+				Fields: []FieldDiff{{Field: "cost_per_unit", Desired: spec.CostPerUnit}},
+				apply:  create,
+			})
+			continue
+		}
+
+		if equalCost(current.CostPerUnit, spec.CostPerUnit) {
+			continue
+		}
+
+		// pricing rules cannot be updated, they are replaced instead
+		ruleID := current.ID
+		p.add(Change{
+			Action: ChangeUpdate,
+			Kind:   "pricing_rule",
+			Name:   name,
+			Fields: []FieldDiff{{Field: "cost_per_unit", Current: current.CostPerUnit, Desired: spec.CostPerUnit}},
+			apply: func() error {
+				if err := p.c.DeleteApplicationPlanPricingRule(state.result.ApplicationPlanIDs[planSystemName], metricID, ruleID); err != nil {
+					return err
+				}
+				return create()
+			},
+		})
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+
+	deletes := []Change{}
+	for key, rule := range remoteRules {
+		if desiredRules[key] {
+			continue
+		}
+		metric, err := p.metricRefNameByID(rule.MetricID)
+		if err != nil {
+			return fmt.Errorf("pricing rule: %w", err)
+		}
+		rule := rule
+		deletes = append(deletes, Change{
+			Action: ChangeDelete,
+			Kind:   "pricing_rule",
+			Name:   fmt.Sprintf("%s/%s/%d-%d", planName, metric, rule.Min, rule.Max),
+			apply: func() error {
+				return p.c.DeleteApplicationPlanPricingRule(state.result.ApplicationPlanIDs[planSystemName], rule.MetricID, rule.ID)
+			},
+		})
+	}
+
+	p.addDeletes(sortedChanges(deletes)...)
+	return nil
+}
+
+func pricingRuleKey(metricID int64, min, max int) string {
+	return fmt.Sprintf("%d/%d/%d", metricID, min, max)
+}
+
+// equalCost compares cost values numerically, 3scale returns them with four decimals
+func equalCost(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return fa == fb
+}
+
+func activeDocFields(doc *ActiveDocItem) Params {
+	return Params{
+		"name":        stringValue(doc.Name),
+		"description": stringValue(doc.Description),
+		"published":   strconv.FormatBool(boolValue(doc.Published)),
+		"body":        stringValue(doc.Body),
+	}
+}
+
+func (p *productPlanner) planActiveDocs(product string, exists bool, docs []ActiveDocSpec) error {
+	state := p.plan.state
+	if len(docs) == 0 && (!p.opts.Prune || !exists) {
+		return nil
+	}
+
+	list, err := p.c.ListActiveDocs()
+	if err != nil {
+		return fmt.Errorf("activedocs: %w", err)
+	}
+
+	remoteDocs := map[string]ActiveDocItem{}
+	for _, doc := range list.ActiveDocs {
+		if doc.Element.SystemName != nil {
+			remoteDocs[*doc.Element.SystemName] = doc.Element
+		}
+	}
+
+	desiredDocs := map[string]bool{}
+	for idx := range docs {
+		spec := docs[idx]
+		desiredDocs[spec.SystemName] = true
+		name := product + "/" + spec.SystemName
+		desired := ActiveDocItem{
+			Name:        &spec.Name,
+			Description: &spec.Description,
+			Published:   &spec.Published,
+			Body:        &spec.Body,
+		}
+
+		current, ok := remoteDocs[spec.SystemName]
+		if !ok {
+			fields := activeDocFields(&desired)
+			fields["system_name"] = spec.SystemName
+			p.add(Change{
+				Action: ChangeCreate,
+				Kind:   "activedoc",
+				Name:   name,
+				Fields: fieldDiffs(fields, Params{}),
+				apply: func() error {
+					item := desired
+					item.SystemName = &spec.SystemName
+					item.SkipSwaggerValidations = &spec.SkipSwaggerValidations
+					item.ServiceID = &state.result.ProductID
+					created, err := p.c.CreateActiveDoc(&ActiveDoc{Element: item})
+					if err != nil {
+						return err
+					}
+					state.result.ActiveDocIDs[spec.SystemName] = *created.Element.ID
+					return nil
+				},
+			})
+			continue
+		}
+
+		if current.ServiceID != nil && (!exists || *current.ServiceID != state.result.ProductID) && !p.opts.RebindActiveDocs {
+			return fmt.Errorf("activedoc %s belongs to product %d", spec.SystemName, *current.ServiceID)
+		}
+
+		state.result.ActiveDocIDs[spec.SystemName] = *current.ID
+		currentFields := activeDocFields(&current)
+		desiredFields := activeDocFields(&desired)
+		if current.ServiceID == nil || !exists || *current.ServiceID != state.result.ProductID {
+			currentFields["service"] = ""
+			if current.ServiceID != nil {
+				currentFields["service"] = strconv.FormatInt(*current.ServiceID, 10)
+			}
+			desiredFields["service"] = product
+		}
+
+		if diffs := fieldDiffs(desiredFields, currentFields); len(diffs) > 0 {
+			docID := current.ID
+			p.add(Change{
+				Action: ChangeUpdate,
+				Kind:   "activedoc",
+				Name:   name,
+				Fields: diffs,
+				apply: func() error {
+					item := desired
+					item.ID = docID
+					item.SkipSwaggerValidations = &spec.SkipSwaggerValidations
+					item.ServiceID = &state.result.ProductID
+					_, err := p.c.UpdateActiveDoc(&ActiveDoc{Element: item})
+					return err
+				},
+			})
+		}
+	}
+
+	if !p.opts.Prune || !exists {
+		return nil
+	}
+
+	deletes := []Change{}
+	for _, systemName := range sortedNames(remoteDocs) {
+		doc := remoteDocs[systemName]
+		if desiredDocs[systemName] || doc.ServiceID == nil || *doc.ServiceID != state.result.ProductID {
+			continue
+		}
+		docID := *doc.ID
+		deletes = append(deletes, Change{
+			Action: ChangeDelete,
+			Kind:   "activedoc",
+			Name:   product + "/" + systemName,
+			apply: func() error {
+				return p.c.DeleteActiveDoc(docID)
+			},
+		})
+	}
+
+	p.addDeletes(deletes...)
+	return nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
+
+func equalJSON(a, b interface{}) bool {
+	rawA, errA := json.Marshal(a)
+	rawB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(rawA) == string(rawB)
+}
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPlanProductDryRun(t *testing.T) {
+	porta := petsPorta(t, false)
+
+	plan, err := porta.client().ReconcileProduct(testProductBundle(), ReconcileOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equals(t, []string{}, porta.writes())
+
+	// dependencies come first
+	order := map[string]int{}
+	for idx, change := range plan.Changes {
+		// settings of the new product are updated right after creating it
+		if change.Action != ChangeCreate && change.Kind != "proxy" && change.Kind != "policy_chain" {
+			t.Fatalf("unexpected %s %s %s", change.Action, change.Kind, change.Name)
+		}
+		if _, ok := order[change.Kind]; !ok {
+			order[change.Kind] = idx
+		}
+	}
+	for _, pair := range [][2]string{
+		{"backend_api", "metric"},
+		{"metric", "mapping_rule"},
+		{"mapping_rule", "product"},
+		{"product", "backend_usage"},
+		{"application_plan", "limit"},
+		{"limit", "activedoc"},
+	} {
+		if order[pair[0]] > order[pair[1]] {
+			t.Fatalf("expected %s changes before %s changes: %v", pair[0], pair[1], plan.Changes)
+		}
+	}
+}
+
+func TestPlanProductNoChanges(t *testing.T) {
+	porta := petsPorta(t, true)
+
+	plan, err := porta.client().PlanProduct(testProductBundle(), ReconcileOptions{Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !plan.IsEmpty() {
+		t.Fatalf("expected empty plan; got\n%s", plan)
+	}
+	equals(t, "No changes\n", plan.String())
+	equals(t, []string{}, porta.writes())
+}
+
+func TestPlanProductFieldDiffs(t *testing.T) {
+	porta := petsPorta(t, true)
+
+	bundle := testProductBundle()
+	bundle.Product.Description = "new description"
+	bundle.Backends[0].MappingRules[0].MetricRef = "storage"
+
+	plan, err := porta.client().PlanProduct(bundle, ReconcileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Change{
+		{
+			Action: ChangeUpdate,
+			Kind:   "mapping_rule",
+			Name:   "pets_backend/GET /pets$",
+			Fields: []FieldDiff{{Field: "metric", Current: "list_pets", Desired: "storage"}},
+		},
+		{
+			Action: ChangeUpdate,
+			Kind:   "product",
+			Name:   "pets",
+			Fields: []FieldDiff{{Field: "description", Current: "all about pets", Desired: "new description"}},
+		},
+	}
+	if len(plan.Changes) != len(expected) {
+		t.Fatalf("unexpected plan:\n%s", plan)
+	}
+	for idx := range expected {
+		plan.Changes[idx].apply = nil
+		equals(t, expected[idx], plan.Changes[idx])
+	}
+
+	equals(t, `~ mapping_rule pets_backend/GET /pets$
+    metric: "list_pets" -> "storage"
+~ product pets
+    description: "all about pets" -> "new description"
+`, plan.String())
+}
+
+func TestReconcileProductPrune(t *testing.T) {
+	porta := petsPorta(t, true)
+	c := porta.client()
+	for _, path := range []string{
+		"/admin/api/active_docs/50.json",
+		"/admin/api/services/10/application_plans/40.json",
+		"/admin/api/backend_apis/20/mapping_rules/25.json",
+		"/admin/api/backend_apis/20/metrics/22.json",
+	} {
+		porta.reply(http.MethodDelete, path, http.StatusOK, nil)
+	}
+
+	bundle := testProductBundle()
+	bundle.Backends[0].MappingRules = bundle.Backends[0].MappingRules[:1]
+	bundle.Backends[0].Metrics = nil
+	bundle.Product.ApplicationPlans = nil
+	bundle.Product.ActiveDocs = nil
+
+	// without prune, missing resources are kept
+	plan, err := c.PlanProduct(bundle, ReconcileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.IsEmpty() {
+		t.Fatalf("expected empty plan; got\n%s", plan)
+	}
+
+	plan, err = c.ReconcileProduct(bundle, ReconcileOptions{Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, change := range plan.Changes {
+		equals(t, ChangeDelete, change.Action)
+	}
+
+	// mapping rule goes before the metric it references
+	equals(t, []string{
+		"DELETE /admin/api/active_docs/50.json",
+		"DELETE /admin/api/services/10/application_plans/40.json",
+		"DELETE /admin/api/backend_apis/20/mapping_rules/25.json",
+		"DELETE /admin/api/backend_apis/20/metrics/22.json",
+	}, porta.writes())
+}
+
+func TestPlanProductPruneOrder(t *testing.T) {
+	porta := petsPorta(t, true)
+	porta.reply(http.MethodGet, "/admin/api/services/10/metrics.json", http.StatusOK, MetricJSONList{Metrics: []MetricJSON{
+		{MetricItem{ID: 11, SystemName: "hits"}},
+		{MetricItem{ID: 12, SystemName: "adopt"}},
+		{MetricItem{ID: 16, SystemName: "storage_v2"}},
+		{MetricItem{ID: 14, SystemName: "bandwidth"}},
+	}})
+	porta.reply(http.MethodGet, "/admin/api/services/10/proxy/mapping_rules.json", http.StatusOK, MappingRuleJSONList{MappingRules: []MappingRuleJSON{
+		{MappingRuleItem{ID: 17, MetricID: 14, HTTPMethod: "GET", Pattern: "/bandwidth", Position: 1}},
+		{MappingRuleItem{ID: 13, MetricID: 12, HTTPMethod: "POST", Pattern: "/adopt", Position: 2}},
+	}})
+
+	// pets_backend stays in use without being declared in the bundle
+	bundle := testProductBundle()
+	bundle.Backends = nil
+	bundle.Product.Methods = nil
+	bundle.Product.MappingRules = nil
+	bundle.Product.ApplicationPlans[0].Limits = nil
+	bundle.Product.ApplicationPlans[0].PricingRules = nil
+
+	plan, err := porta.client().PlanProduct(bundle, ReconcileOptions{Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// limits and pricing rules reference metrics by system name,
+	// mapping rules go before metrics and methods
+	equals(t, `- pricing_rule pets/basic/hits/1-100
+- limit pets/basic/adopt/day
+- limit pets/basic/pets_backend.storage/month
+- mapping_rule product/GET /bandwidth
+- mapping_rule product/POST /adopt
+- method product/adopt
+- metric product/bandwidth
+- metric product/storage_v2
+`, plan.String())
+	equals(t, []string{}, porta.writes())
+}
+
+func TestReconcileProductRebindActiveDocs(t *testing.T) {
+	porta := petsPorta(t, true)
+	c := porta.client()
+	porta.reply(http.MethodPost, "/admin/api/services.json", http.StatusCreated, Product{ProductItem{ID: 60, Name: "Pets API", SystemName: "cats"}})
+	porta.reply(http.MethodGet, "/admin/api/services/60/metrics.json", http.StatusOK, MetricJSONList{Metrics: []MetricJSON{{MetricItem{ID: 61, SystemName: "hits"}}}})
+	porta.reply(http.MethodPost, "/admin/api/services/60/metrics/61/methods.json", http.StatusCreated, Method{MethodItem{ID: 62, SystemName: "adopt", ParentID: 61}})
+	porta.reply(http.MethodPut, "/admin/api/services/60/proxy.json", http.StatusOK, ProxyJSON{ProxyItem{ServiceID: 60}})
+	porta.reply(http.MethodPut, "/admin/api/services/60/proxy/policies.json", http.StatusOK, PoliciesConfigList{})
+
+	// another product declaring the activedoc of pets
+	bundle := testProductBundle()
+	bundle.Backends = nil
+	bundle.Product.SystemName = "cats"
+	bundle.Product.BackendUsages = nil
+	bundle.Product.MappingRules = nil
+	bundle.Product.ApplicationPlans = nil
+
+	if _, err := c.PlanProduct(bundle, ReconcileOptions{}); err == nil || !strings.Contains(err.Error(), "activedoc pets_doc belongs to product 10") {
+		t.Fatalf("expected activedoc ownership error, got %v", err)
+	}
+	equals(t, []string{}, porta.writes())
+
+	cats, err := c.ReconcileProduct(bundle, ReconcileOptions{RebindActiveDocs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, int64(60), cats.state.result.ProductID)
+
+	doc := ActiveDocItem{}
+	if err := json.Unmarshal(porta.calls(http.MethodPut, "/admin/api/active_docs/50.json")[0].Body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, int64(60), *doc.ServiceID)
+}
+
+func TestChangePlanApplyStopsOnError(t *testing.T) {
+	porta := petsPorta(t, false)
+	porta.reply(http.MethodPost, "/admin/api/services/10/application_plans.json", http.StatusForbidden, map[string]string{"error": "forbidden"})
+
+	plan, err := porta.client().ReconcileProduct(testProductBundle(), ReconcileOptions{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if !strings.HasPrefix(err.Error(), "create application_plan pets/basic") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan == nil {
+		t.Fatal("expected plan on apply error")
+	}
+
+	writes := porta.writes()
+	equals(t, "POST /admin/api/services/10/application_plans.json", writes[len(writes)-1])
+}
+
+func TestChangeStringTruncatesLongValues(t *testing.T) {
+	change := Change{
+		Action: ChangeUpdate,
+		Kind:   "activedoc",
+		Name:   "pets/pets_doc",
+		Fields: []FieldDiff{{Field: "body", Current: "{\n}", Desired: "{\n \"a\": 1\n}"}},
+	}
+	equals(t, "~ activedoc pets/pets_doc\n    body: changed (3 -> 11 bytes)\n", change.String())
+
+	change = Change{
+		Action: ChangeCreate,
+		Kind:   "activedoc",
+		Name:   "pets/pets_doc",
+		Fields: []FieldDiff{{Field: "body", Desired: "{\n}"}, {Field: "name", Desired: "Pets"}},
+	}
+	equals(t, "+ activedoc pets/pets_doc\n    body: (3 bytes)\n    name: \"Pets\"\n", change.String())
+}
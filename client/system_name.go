@@ -0,0 +1,258 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ProductBySystemName returns the product with the given system name.
+// The error satisfies IsNotFound when there is no such product
+func (c *ThreeScaleClient) ProductBySystemName(systemName string) (*Product, error) {
+	list, err := c.ListProducts()
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range list.Products {
+		if list.Products[idx].Element.SystemName == systemName {
+			return &list.Products[idx], nil
+		}
+	}
+	return nil, &SystemNameNotFoundErr{Kind: "product", SystemName: systemName}
+}
+
+// BackendApiBySystemName returns the backend api with the given system name.
+// The error satisfies IsNotFound when there is no such backend api
+func (c *ThreeScaleClient) BackendApiBySystemName(systemName string) (*BackendApi, error) {
+	list, err := c.ListBackendApis()
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range list.Backends {
+		if list.Backends[idx].Element.SystemName == systemName {
+			return &list.Backends[idx], nil
+		}
+	}
+	return nil, &SystemNameNotFoundErr{Kind: "backend api", SystemName: systemName}
+}
+
+// ProductMetricBySystemName returns the metric or method of the product with the given system name
+func (c *ThreeScaleClient) ProductMetricBySystemName(productID int64, systemName string) (*MetricJSON, error) {
+	return productMetricOwner(c, productID).metricBySystemName(systemName)
+}
+
+// BackendApiMetricBySystemName returns the metric or method of the backend api with the given system name.
+// The system name may be given with or without the ".<backendapiID>" suffix 3scale appends to backend metrics
+func (c *ThreeScaleClient) BackendApiMetricBySystemName(backendapiID int64, systemName string) (*MetricJSON, error) {
+	return backendMetricOwner(c, backendapiID).metricBySystemName(systemName)
+}
+
+// ProductMethodBySystemName returns the method of the product with the given system name
+func (c *ThreeScaleClient) ProductMethodBySystemName(productID int64, systemName string) (*Method, error) {
+	return productMetricOwner(c, productID).methodBySystemName(systemName)
+}
+
+// BackendApiMethodBySystemName returns the method of the backend api with the given system name.
+// The system name may be given with or without the ".<backendapiID>" suffix 3scale appends to backend methods
+func (c *ThreeScaleClient) BackendApiMethodBySystemName(backendapiID int64, systemName string) (*Method, error) {
+	return backendMetricOwner(c, backendapiID).methodBySystemName(systemName)
+}
+
+// ApplicationPlanBySystemName returns the application plan of the product with the given system name
+func (c *ThreeScaleClient) ApplicationPlanBySystemName(productID int64, systemName string) (*ApplicationPlan, error) {
+	list, err := c.ListApplicationPlansByProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range list.Plans {
+		if list.Plans[idx].Element.SystemName == systemName {
+			return &list.Plans[idx], nil
+		}
+	}
+	return nil, &SystemNameNotFoundErr{Kind: "application plan", SystemName: systemName}
+}
+
+func (o metricOwner) metricBySystemName(systemName string) (*MetricJSON, error) {
+	list, err := o.listMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range list.Metrics {
+		if o.matchesSystemName(list.Metrics[idx].Element.SystemName, systemName) {
+			return &list.Metrics[idx], nil
+		}
+	}
+	return nil, &SystemNameNotFoundErr{Kind: o.String() + " metric", SystemName: systemName}
+}
+
+func (o metricOwner) methodBySystemName(systemName string) (*Method, error) {
+	metrics, err := o.listMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	hitsID, err := o.hitsID(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := o.listMethods(hitsID)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range list.Methods {
+		if o.matchesSystemName(list.Methods[idx].Element.SystemName, systemName) {
+			return &list.Methods[idx], nil
+		}
+	}
+	return nil, &SystemNameNotFoundErr{Kind: o.String() + " method", SystemName: systemName}
+}
+
+func (o metricOwner) matchesSystemName(remote, systemName string) bool {
+	return remote == systemName || o.systemName(remote) == systemName
+}
+
+// SystemNameCache resolves system names to IDs. Each index is loaded on first use and kept in memory;
+// a lookup missing the index reloads it once, so resources created after loading are found.
+// Safe for concurrent use
+type SystemNameCache struct {
+	c  *ThreeScaleClient
+	mu sync.Mutex
+
+	products map[string]int64
+	backends map[string]int64
+	// metric and method IDs indexed by owner and system name
+	metrics map[metricOwner]map[string]int64
+	// application plan IDs indexed by product ID and system name
+	plans map[int64]map[string]int64
+}
+
+// NewSystemNameCache returns an empty cache that reads from the given client
+func NewSystemNameCache(c *ThreeScaleClient) *SystemNameCache {
+	cache := &SystemNameCache{c: c}
+	cache.Invalidate()
+	return cache
+}
+
+// Invalidate drops every index. They are loaded again on next use
+func (s *SystemNameCache) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.products = nil
+	s.backends = nil
+	s.metrics = map[metricOwner]map[string]int64{}
+	s.plans = map[int64]map[string]int64{}
+}
+
+// ProductID returns the ID of the product with the given system name
+func (s *SystemNameCache) ProductID(systemName string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lookup(&s.products, "product", systemName, func() (map[string]int64, error) {
+		list, err := s.c.ListProducts()
+		if err != nil {
+			return nil, fmt.Errorf("products: %w", err)
+		}
+		index := map[string]int64{}
+		for _, product := range list.Products {
+			index[product.Element.SystemName] = product.Element.ID
+		}
+		return index, nil
+	})
+}
+
+// BackendApiID returns the ID of the backend api with the given system name
+func (s *SystemNameCache) BackendApiID(systemName string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lookup(&s.backends, "backend api", systemName, func() (map[string]int64, error) {
+		list, err := s.c.ListBackendApis()
+		if err != nil {
+			return nil, fmt.Errorf("backend apis: %w", err)
+		}
+		index := map[string]int64{}
+		for _, backend := range list.Backends {
+			index[backend.Element.SystemName] = backend.Element.ID
+		}
+		return index, nil
+	})
+}
+
+// ProductMetricID returns the ID of the metric or method of the product with the given system name
+func (s *SystemNameCache) ProductMetricID(productID int64, systemName string) (int64, error) {
+	return s.metricID(productMetricOwner(s.c, productID), systemName)
+}
+
+// BackendApiMetricID returns the ID of the metric or method of the backend api with the given system name,
+// given with or without the ".<backendapiID>" suffix
+func (s *SystemNameCache) BackendApiMetricID(backendapiID int64, systemName string) (int64, error) {
+	return s.metricID(backendMetricOwner(s.c, backendapiID), systemName)
+}
+
+// ApplicationPlanID returns the ID of the application plan of the product with the given system name
+func (s *SystemNameCache) ApplicationPlanID(productID int64, systemName string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.plans[productID]
+	id, err := s.lookup(&index, "application plan", systemName, func() (map[string]int64, error) {
+		list, err := s.c.ListApplicationPlansByProduct(productID)
+		if err != nil {
+			return nil, fmt.Errorf("product %d: application plans: %w", productID, err)
+		}
+		index := map[string]int64{}
+		for _, plan := range list.Plans {
+			index[plan.Element.SystemName] = plan.Element.ID
+		}
+		return index, nil
+	})
+	s.plans[productID] = index
+	return id, err
+}
+
+func (s *SystemNameCache) metricID(owner metricOwner, systemName string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.metrics[owner]
+	id, err := s.lookup(&index, owner.String()+" metric", systemName, func() (map[string]int64, error) {
+		list, err := owner.listMetrics()
+		if err != nil {
+			return nil, fmt.Errorf("%s: metrics: %w", owner, err)
+		}
+		index := map[string]int64{}
+		for _, metric := range list.Metrics {
+			index[metric.Element.SystemName] = metric.Element.ID
+			index[owner.systemName(metric.Element.SystemName)] = metric.Element.ID
+		}
+		return index, nil
+	})
+	s.metrics[owner] = index
+	return id, err
+}
+
+// lookup finds the system name in the index, loading the index when it is missing or stale.
+// Must be called with the lock held
+func (s *SystemNameCache) lookup(index *map[string]int64, kind, systemName string, load func() (map[string]int64, error)) (int64, error) {
+	if id, ok := (*index)[systemName]; ok {
+		return id, nil
+	}
+
+	loaded, err := load()
+	if err != nil {
+		return 0, err
+	}
+	*index = loaded
+
+	if id, ok := loaded[systemName]; ok {
+		return id, nil
+	}
+	return 0, &SystemNameNotFoundErr{Kind: kind, SystemName: systemName}
+}
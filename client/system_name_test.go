@@ -0,0 +1,149 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestLookupBySystemName(t *testing.T) {
+	c := petsPorta(t, true).client()
+	var productID, backendID int64 = 10, 20
+
+	product, err := c.ProductBySystemName("pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, productID, product.Element.ID)
+
+	backend, err := c.BackendApiBySystemName("pets_backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, backendID, backend.Element.ID)
+
+	metric, err := c.ProductMetricBySystemName(productID, "hits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, int64(11), metric.Element.ID)
+
+	// with and without the backend suffix
+	for _, systemName := range []string{"storage", fmt.Sprintf("storage.%d", backendID)} {
+		metric, err = c.BackendApiMetricBySystemName(backendID, systemName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		equals(t, int64(22), metric.Element.ID)
+	}
+
+	method, err := c.ProductMethodBySystemName(productID, "adopt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, int64(12), method.Element.ID)
+
+	method, err = c.BackendApiMethodBySystemName(backendID, "list_pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, int64(23), method.Element.ID)
+
+	plan, err := c.ApplicationPlanBySystemName(productID, "basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, int64(40), plan.Element.ID)
+}
+
+func TestLookupBySystemNameNotFound(t *testing.T) {
+	c := petsPorta(t, true).client()
+	var productID int64 = 10
+
+	_, err := c.ProductBySystemName("unknown")
+	if !IsNotFound(err) {
+		t.Fatalf("expected not found error; got %v", err)
+	}
+	equals(t, `product with system_name "unknown" not found`, err.Error())
+
+	if _, err := c.BackendApiBySystemName("unknown"); !IsNotFound(err) {
+		t.Fatalf("expected not found error; got %v", err)
+	}
+	// methods are not metrics
+	if _, err := c.ProductMethodBySystemName(productID, "hits"); !IsNotFound(err) {
+		t.Fatalf("expected not found error; got %v", err)
+	}
+	if _, err := c.ApplicationPlanBySystemName(productID, "unknown"); !IsNotFound(err) {
+		t.Fatalf("expected not found error; got %v", err)
+	}
+
+	// wrapped errors
+	if !IsNotFound(fmt.Errorf("lookup: %w", err)) {
+		t.Fatal("expected wrapped error to be not found")
+	}
+	if !IsForbidden(fmt.Errorf("lookup: %w", ApiErr{code: http.StatusForbidden})) {
+		t.Fatal("expected wrapped api error to be forbidden")
+	}
+}
+
+func TestSystemNameCache(t *testing.T) {
+	porta := petsPorta(t, true)
+	cache := NewSystemNameCache(porta.client())
+	var productID, backendID int64 = 10, 20
+
+	for i := 0; i < 2; i++ {
+		id, err := cache.ProductID("pets")
+		if err != nil {
+			t.Fatal(err)
+		}
+		equals(t, productID, id)
+
+		id, err = cache.BackendApiID("pets_backend")
+		if err != nil {
+			t.Fatal(err)
+		}
+		equals(t, backendID, id)
+
+		id, err = cache.ProductMetricID(productID, "adopt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		equals(t, int64(12), id)
+
+		id, err = cache.BackendApiMetricID(backendID, "list_pets")
+		if err != nil {
+			t.Fatal(err)
+		}
+		equals(t, int64(23), id)
+
+		id, err = cache.ApplicationPlanID(productID, "basic")
+		if err != nil {
+			t.Fatal(err)
+		}
+		equals(t, int64(40), id)
+	}
+	// one list request per index
+	equals(t, 5, len(porta.served(http.MethodGet)))
+
+	// a miss reloads the index
+	porta.reply(http.MethodGet, "/admin/api/services.json", http.StatusOK, ProductList{Products: []Product{
+		{Element: ProductItem{ID: productID, SystemName: "pets"}},
+		{Element: ProductItem{ID: 60, SystemName: "cats"}},
+	}})
+	id, err := cache.ProductID("cats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, int64(60), id)
+
+	if _, err := cache.ProductID("dogs"); !IsNotFound(err) {
+		t.Fatalf("expected not found error; got %v", err)
+	}
+
+	cache.Invalidate()
+	porta.reset()
+	if _, err := cache.ProductID("pets"); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 1, len(porta.served(http.MethodGet)))
+}
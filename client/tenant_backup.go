@@ -0,0 +1,622 @@
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tenantBackupVersion  = 1
+	tenantBackupManifest = "manifest.json"
+)
+
+// BackupTenant reads the custom APIcast policies, backends, products (with their proxy config history),
+// activedocs, developer accounts, users and applications of the tenant.
+// Products are stored as ProductSpec, so they are described by system names instead of IDs.
+// Applications on custom plans are kept, but can not be restored.
+func (c *ThreeScaleClient) BackupTenant() (*TenantBackup, error) {
+	backup := &TenantBackup{
+		Policies:   []APIcastPolicyItem{},
+		Backends:   []BackendApiSpec{},
+		Products:   []ProductBackup{},
+		ActiveDocs: []ActiveDocSpec{},
+		Accounts:   []AccountBackup{},
+	}
+
+	policies, err := c.ListAPIcastPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("apicast policies: %w", err)
+	}
+	for _, policy := range policies.Items {
+		backup.Policies = append(backup.Policies, policy.Element)
+	}
+
+	e := newProductExporter(c)
+	backends, err := c.ListBackendApis()
+	if err != nil {
+		return nil, fmt.Errorf("backend apis: %w", err)
+	}
+	for _, backend := range backends.Backends {
+		spec, err := e.exportBackend(backend.Element.ID)
+		if err != nil {
+			return nil, fmt.Errorf("backend %s: %w", backend.Element.SystemName, err)
+		}
+		backup.Backends = append(backup.Backends, *spec)
+	}
+
+	products, err := c.ListProducts()
+	if err != nil {
+		return nil, fmt.Errorf("products: %w", err)
+	}
+
+	// product and plan system names indexed by product and plan ID
+	productNames := map[int64]string{}
+	planNames := map[int64]string{}
+	for _, product := range products.Products {
+		productID := product.Element.ID
+		productExporter := newProductExporter(c)
+		productExporter.refs = e.refs
+		productExporter.backends = e.backends
+
+		bundle, err := productExporter.export(productID)
+		if err != nil {
+			return nil, fmt.Errorf("product %s: %w", product.Element.SystemName, err)
+		}
+
+		productNames[productID] = bundle.Product.SystemName
+		for systemName, planID := range productExporter.state.result.ApplicationPlanIDs {
+			planNames[planID] = systemName
+		}
+
+		productBackup := ProductBackup{Product: bundle.Product, ProxyConfigs: []ProxyConfig{}}
+		for _, env := range []string{"sandbox", "production"} {
+			configs, err := c.ListProxyConfig(strconv.FormatInt(productID, 10), env)
+			if err != nil {
+				return nil, fmt.Errorf("product %s: %s proxy configs: %w", bundle.Product.SystemName, env, err)
+			}
+			for _, config := range configs.ProxyConfigs {
+				productBackup.ProxyConfigs = append(productBackup.ProxyConfigs, config.ProxyConfig)
+			}
+		}
+		backup.Products = append(backup.Products, productBackup)
+	}
+
+	// activedocs of products were exported along with them
+	docs, err := c.ListActiveDocs()
+	if err != nil {
+		return nil, fmt.Errorf("activedocs: %w", err)
+	}
+	for _, doc := range docs.ActiveDocs {
+		item := doc.Element
+		if item.ServiceID != nil && productNames[*item.ServiceID] != "" {
+			continue
+		}
+		if item.SystemName == nil {
+			return nil, fmt.Errorf("activedoc %d: system_name not found", *item.ID)
+		}
+		backup.ActiveDocs = append(backup.ActiveDocs, activeDocSpec(item))
+	}
+
+	accounts, err := c.ListDeveloperAccounts()
+	if err != nil {
+		return nil, fmt.Errorf("developer accounts: %w", err)
+	}
+	for _, account := range accounts.Items {
+		accountID := *account.Element.ID
+		accountBackup := AccountBackup{
+			Account:      account.Element,
+			Users:        []DeveloperUserItem{},
+			Applications: []ApplicationBackup{},
+		}
+
+		users, err := c.ListDeveloperUsers(accountID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("account %d: users: %w", accountID, err)
+		}
+		for _, user := range users.Items {
+			accountBackup.Users = append(accountBackup.Users, user.Element)
+		}
+
+		apps, err := c.ListApplications(accountID)
+		if err != nil {
+			return nil, fmt.Errorf("account %d: applications: %w", accountID, err)
+		}
+		for _, app := range apps.Applications {
+			appBackup := ApplicationBackup{
+				Application:       app.Application,
+				ProductSystemName: productNames[app.Application.ServiceID],
+				PlanSystemName:    planNames[app.Application.PlanID],
+			}
+			if app.Application.ApplicationId != "" {
+				keys, err := c.ApplicationKeys(accountID, app.Application.ID)
+				if err != nil {
+					return nil, fmt.Errorf("account %d: application %d: keys: %w", accountID, app.Application.ID, err)
+				}
+				for _, key := range keys {
+					appBackup.Keys = append(appBackup.Keys, key.Value)
+				}
+			}
+			accountBackup.Applications = append(accountBackup.Applications, appBackup)
+		}
+
+		backup.Accounts = append(backup.Accounts, accountBackup)
+	}
+
+	return backup, nil
+}
+
+// RestoreTenant replays the backup into the tenant, which is expected to be empty: it fails when any of
+// the backed up products or backends already exists. Restore is best effort in some areas:
+//   - the proxy config history is not replayed. Products with sandbox configs are deployed once and
+//     products with production configs get the new sandbox config promoted
+//   - user passwords are not part of the backup. Restored users have to reset them
+//   - applications on custom plans or on unknown plans are skipped and reported as warnings
+//   - accounts without admin user can not be signed up. They are skipped and reported as warnings
+func (c *ThreeScaleClient) RestoreTenant(backup *TenantBackup) (*TenantRestoreResult, error) {
+	if backup == nil {
+		return nil, errors.New("RestoreTenant needs not nil backup")
+	}
+
+	if err := c.checkTenantRestorable(backup); err != nil {
+		return nil, err
+	}
+
+	result := &TenantRestoreResult{
+		PolicyIDs:      map[string]int64{},
+		BackendIDs:     map[string]int64{},
+		ProductIDs:     map[string]int64{},
+		ActiveDocIDs:   map[string]int64{},
+		AccountIDs:     map[int64]int64{},
+		ApplicationIDs: map[int64]int64{},
+		Warnings:       []string{},
+	}
+
+	for _, item := range backup.Policies {
+		policy := &APIcastPolicy{Element: item}
+		policy.Element.ID, policy.Element.CreatedAt, policy.Element.UpdatedAt = nil, nil, nil
+		created, err := c.CreateAPIcastPolicy(policy)
+		if err != nil {
+			return result, fmt.Errorf("apicast policy %s: %w", policyArchiveName(item), err)
+		}
+		result.PolicyIDs[policyArchiveName(item)] = *created.Element.ID
+	}
+
+	for idx := range backup.Backends {
+		spec := backup.Backends[idx]
+		ids, err := importBackendApi(c, &spec)
+		if err != nil {
+			return result, err
+		}
+		result.BackendIDs[spec.SystemName] = ids.BackendIDs[spec.SystemName]
+	}
+
+	// plan IDs indexed by product and plan system names
+	planIDs := map[string]map[string]int64{}
+	for _, productBackup := range backup.Products {
+		systemName := productBackup.Product.SystemName
+		ids, err := c.ImportProduct(&ProductBundle{Product: productBackup.Product})
+		if ids != nil && ids.ProductID != 0 {
+			result.ProductIDs[systemName] = ids.ProductID
+		}
+		if err != nil {
+			return result, fmt.Errorf("product %s: %w", systemName, err)
+		}
+		planIDs[systemName] = ids.ApplicationPlanIDs
+
+		if err := c.restoreProxyConfigs(ids.ProductID, productBackup.ProxyConfigs); err != nil {
+			return result, fmt.Errorf("product %s: %w", systemName, err)
+		}
+	}
+
+	for idx := range backup.ActiveDocs {
+		spec := backup.ActiveDocs[idx]
+		created, err := c.CreateActiveDoc(&ActiveDoc{Element: ActiveDocItem{
+			SystemName:             &spec.SystemName,
+			Name:                   &spec.Name,
+			Description:            &spec.Description,
+			Published:              &spec.Published,
+			SkipSwaggerValidations: &spec.SkipSwaggerValidations,
+			Body:                   &spec.Body,
+		}})
+		if err != nil {
+			return result, fmt.Errorf("activedoc %s: %w", spec.SystemName, err)
+		}
+		result.ActiveDocIDs[spec.SystemName] = *created.Element.ID
+	}
+
+	for _, accountBackup := range backup.Accounts {
+		if err := c.restoreAccount(accountBackup, planIDs, result); err != nil {
+			return result, fmt.Errorf("account %s: %w", stringValue(accountBackup.Account.OrgName), err)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *ThreeScaleClient) checkTenantRestorable(backup *TenantBackup) error {
+	backends, err := c.ListBackendApis()
+	if err != nil {
+		return fmt.Errorf("backend apis: %w", err)
+	}
+	existing := map[string]bool{}
+	for _, backend := range backends.Backends {
+		existing[backend.Element.SystemName] = true
+	}
+	for _, backend := range backup.Backends {
+		if existing[backend.SystemName] {
+			return fmt.Errorf("backend %s already exists", backend.SystemName)
+		}
+	}
+
+	products, err := c.ListProducts()
+	if err != nil {
+		return fmt.Errorf("products: %w", err)
+	}
+	existing = map[string]bool{}
+	for _, product := range products.Products {
+		existing[product.Element.SystemName] = true
+	}
+	for _, product := range backup.Products {
+		if existing[product.Product.SystemName] {
+			return fmt.Errorf("product %s already exists", product.Product.SystemName)
+		}
+	}
+
+	if len(backup.ActiveDocs) == 0 {
+		return nil
+	}
+	docs, err := c.ListActiveDocs()
+	if err != nil {
+		return fmt.Errorf("activedocs: %w", err)
+	}
+	existing = map[string]bool{}
+	for _, doc := range docs.ActiveDocs {
+		existing[stringValue(doc.Element.SystemName)] = true
+	}
+	for _, doc := range backup.ActiveDocs {
+		if existing[doc.SystemName] {
+			return fmt.Errorf("activedoc %s already exists", doc.SystemName)
+		}
+	}
+
+	return nil
+}
+
+func (c *ThreeScaleClient) restoreProxyConfigs(productID int64, configs []ProxyConfig) error {
+	envs := map[string]bool{}
+	for _, config := range configs {
+		envs[config.Environment] = true
+	}
+
+	if !envs["sandbox"] && !envs["production"] {
+		return nil
+	}
+
+	if _, err := c.DeployProductProxy(productID); err != nil {
+		return fmt.Errorf("deploy proxy: %w", err)
+	}
+
+	if !envs["production"] {
+		return nil
+	}
+
+	productIDStr := strconv.FormatInt(productID, 10)
+	latest, err := c.GetLatestProxyConfig(productIDStr, "sandbox")
+	if err != nil {
+		return fmt.Errorf("sandbox proxy config: %w", err)
+	}
+	_, err = c.PromoteProxyConfig(productIDStr, "sandbox", strconv.Itoa(latest.ProxyConfig.Version), "production")
+	if err != nil {
+		return fmt.Errorf("promote proxy config: %w", err)
+	}
+	return nil
+}
+
+func (c *ThreeScaleClient) restoreAccount(backup AccountBackup, planIDs map[string]map[string]int64, result *TenantRestoreResult) error {
+	var admin *DeveloperUserItem
+	for idx := range backup.Users {
+		if stringValue(backup.Users[idx].Role) == "admin" {
+			admin = &backup.Users[idx]
+			break
+		}
+	}
+	if admin == nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"account %s skipped along with its %d applications: no admin user",
+			stringValue(backup.Account.OrgName), len(backup.Applications)))
+		return nil
+	}
+
+	account, err := c.Signup(Params{
+		"org_name": stringValue(backup.Account.OrgName),
+		"username": stringValue(admin.Username),
+		"email":    stringValue(admin.Email),
+	})
+	if err != nil {
+		return fmt.Errorf("signup: %w", err)
+	}
+	accountID := *account.Element.ID
+	if backup.Account.ID != nil {
+		result.AccountIDs[*backup.Account.ID] = accountID
+	}
+
+	update := &DeveloperAccount{Element: backup.Account}
+	update.Element.ID = &accountID
+	update.Element.State, update.Element.CreatedAt, update.Element.UpdatedAt = nil, nil, nil
+	update.Element.CreditCardStored = nil
+	if _, err := c.UpdateDeveloperAccount(update); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	for _, item := range backup.Users {
+		if stringValue(item.Username) == stringValue(admin.Username) {
+			continue
+		}
+		if err := c.restoreUser(accountID, item); err != nil {
+			return fmt.Errorf("user %s: %w", stringValue(item.Username), err)
+		}
+	}
+
+	for _, app := range backup.Applications {
+		planID, ok := planIDs[app.ProductSystemName][app.PlanSystemName]
+		if !ok || app.PlanSystemName == "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"application %d (%s) of account %s skipped: plan not restored",
+				app.Application.ID, app.Application.AppName, stringValue(backup.Account.OrgName)))
+			continue
+		}
+
+		appID, err := c.restoreApplication(accountID, planID, app)
+		if appID != 0 {
+			result.ApplicationIDs[app.Application.ID] = appID
+		}
+		if err != nil {
+			return fmt.Errorf("application %s: %w", app.Application.AppName, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *ThreeScaleClient) restoreUser(accountID int64, item DeveloperUserItem) error {
+	user := &DeveloperUser{Element: DeveloperUserItem{
+		Username:    item.Username,
+		Email:       item.Email,
+		Annotations: item.Annotations,
+	}}
+	created, err := c.CreateDeveloperUser(accountID, user)
+	if err != nil {
+		return err
+	}
+	userID := *created.Element.ID
+
+	if stringValue(item.Role) == "admin" {
+		if _, err := c.ChangeRoleToAdminDeveloperUser(accountID, userID); err != nil {
+			return fmt.Errorf("role: %w", err)
+		}
+	}
+
+	switch stringValue(item.State) {
+	case "active":
+		_, err = c.ActivateDeveloperUser(accountID, userID)
+	case "suspended":
+		if _, err = c.ActivateDeveloperUser(accountID, userID); err == nil {
+			_, err = c.SuspendDeveloperUser(accountID, userID)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("state: %w", err)
+	}
+	return nil
+}
+
+// restoreApplication returns the ID of the new application, also when setting its keys or state fails
+func (c *ThreeScaleClient) restoreApplication(accountID, planID int64, backup ApplicationBackup) (int64, error) {
+	params := Params{"description": backup.Application.Description}
+	if backup.Application.UserKey != "" {
+		params["user_key"] = backup.Application.UserKey
+	}
+	if backup.Application.ApplicationId != "" {
+		params["application_id"] = backup.Application.ApplicationId
+	}
+
+	app, err := c.CreateApplication(accountID, planID, backup.Application.AppName, params)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range backup.Keys {
+		if _, err := c.CreateApplicationKey(accountID, app.ID, key); err != nil {
+			return app.ID, fmt.Errorf("key: %w", err)
+		}
+	}
+
+	if backup.Application.State == "suspended" {
+		if _, err := c.ApplicationSuspend(accountID, app.ID); err != nil {
+			return app.ID, fmt.Errorf("suspend: %w", err)
+		}
+	}
+
+	return app.ID, nil
+}
+
+// Save writes the backup as a gzipped tar archive to the given path
+func (b *TenantBackup) Save(filePath string) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := b.WriteArchive(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteArchive writes the backup as a gzipped tar archive with one JSON file per resource:
+// manifest.json, policies/<name>-<version>.json, backends/<system_name>.json,
+// products/<system_name>.json, activedocs/<system_name>.json and accounts/<id>.json
+func (b *TenantBackup) WriteArchive(w io.Writer) error {
+	files := map[string]interface{}{
+		tenantBackupManifest: map[string]int{"version": tenantBackupVersion},
+	}
+	for _, policy := range b.Policies {
+		files["policies/"+policyArchiveName(policy)+".json"] = policy
+	}
+	for _, backend := range b.Backends {
+		files["backends/"+backend.SystemName+".json"] = backend
+	}
+	for _, product := range b.Products {
+		files["products/"+product.Product.SystemName+".json"] = product
+	}
+	for _, doc := range b.ActiveDocs {
+		files["activedocs/"+doc.SystemName+".json"] = doc
+	}
+	for idx, account := range b.Accounts {
+		name := strconv.Itoa(idx)
+		if account.Account.ID != nil {
+			name = strconv.FormatInt(*account.Account.ID, 10)
+		}
+		files["accounts/"+name+".json"] = account
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, name := range names {
+		data, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: now}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// LoadTenantBackup reads a backup archive written by Save
+func LoadTenantBackup(filePath string) (*TenantBackup, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadTenantBackup(f)
+}
+
+// ReadTenantBackup reads a backup archive written by WriteArchive
+func ReadTenantBackup(r io.Reader) (*TenantBackup, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	backup := &TenantBackup{
+		Policies:   []APIcastPolicyItem{},
+		Backends:   []BackendApiSpec{},
+		Products:   []ProductBackup{},
+		ActiveDocs: []ActiveDocSpec{},
+		Accounts:   []AccountBackup{},
+	}
+	manifest := false
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", header.Name, err)
+		}
+
+		switch dir, _ := path.Split(header.Name); strings.TrimSuffix(dir, "/") {
+		case "":
+			if header.Name != tenantBackupManifest {
+				continue
+			}
+			version := struct {
+				Version int `json:"version"`
+			}{}
+			err = json.Unmarshal(data, &version)
+			if err == nil && version.Version != tenantBackupVersion {
+				return nil, fmt.Errorf("unsupported backup version %d", version.Version)
+			}
+			manifest = true
+		case "policies":
+			item := APIcastPolicyItem{}
+			if err = json.Unmarshal(data, &item); err == nil {
+				backup.Policies = append(backup.Policies, item)
+			}
+		case "backends":
+			item := BackendApiSpec{}
+			if err = json.Unmarshal(data, &item); err == nil {
+				backup.Backends = append(backup.Backends, item)
+			}
+		case "products":
+			item := ProductBackup{}
+			if err = json.Unmarshal(data, &item); err == nil {
+				backup.Products = append(backup.Products, item)
+			}
+		case "activedocs":
+			item := ActiveDocSpec{}
+			if err = json.Unmarshal(data, &item); err == nil {
+				backup.ActiveDocs = append(backup.ActiveDocs, item)
+			}
+		case "accounts":
+			item := AccountBackup{}
+			if err = json.Unmarshal(data, &item); err == nil {
+				backup.Accounts = append(backup.Accounts, item)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", header.Name, err)
+		}
+	}
+
+	if !manifest {
+		return nil, errors.New("not a tenant backup: " + tenantBackupManifest + " not found")
+	}
+	return backup, nil
+}
+
+func policyArchiveName(policy APIcastPolicyItem) string {
+	return stringValue(policy.Name) + "-" + stringValue(policy.Version)
+}
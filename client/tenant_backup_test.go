@@ -0,0 +1,265 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// backupPorta serves the tenant holding the imported product of testProductBundle, a custom policy,
+// an activedoc not bound to any product, the deployed and promoted proxy config and
+// the Acme developer account with two users and two applications
+func backupPorta(t *testing.T) *mockPorta {
+	porta := petsPorta(t, true)
+	porta.load("tenant_backup_fixture.json")
+	return porta
+}
+
+func testTenantBackup(t *testing.T) *TenantBackup {
+	backup, err := backupPorta(t).client().BackupTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backup
+}
+
+// restorePorta serves an empty tenant where the backup of backupPorta is restored with IDs shifted by 100.
+// Backends show up in the list once created, as products reference them by system name
+func restorePorta(t *testing.T) (*mockPorta, *ThreeScaleClient) {
+	porta, c := copyPorta(t, false)
+	porta.load("tenant_restore_fixture.json")
+
+	backends := "/admin/api/backend_apis.json"
+	porta.handle(http.MethodGet, backends, func(req *http.Request) *http.Response {
+		list := BackendApiList{Backends: []BackendApi{}}
+		if len(porta.calls(http.MethodPost, backends)) > 0 {
+			list.Backends = append(list.Backends, BackendApi{Element: BackendApiItem{
+				ID: 120, Name: "Pets Backend", SystemName: "pets_backend", PrivateEndpoint: "https://pets.internal:443",
+			}})
+		}
+		return helperJSONResponse(t, http.StatusOK, list)
+	})
+
+	docs := "/admin/api/active_docs.json"
+	porta.handle(http.MethodPost, docs, func(req *http.Request) *http.Response {
+		doc := ActiveDocItem{}
+		if err := json.NewDecoder(req.Body).Decode(&doc); err != nil {
+			t.Fatal(err)
+		}
+		id := int64(150)
+		if *doc.SystemName == "shared_doc" {
+			id = 151
+		}
+		doc.ID = &id
+		return helperJSONResponse(t, http.StatusCreated, ActiveDoc{Element: doc})
+	})
+
+	apps := "/admin/api/accounts/170/applications.json"
+	porta.handle(http.MethodPost, apps, func(req *http.Request) *http.Response {
+		app := Application{ID: 181, State: "live", UserAccountID: 170, ServiceID: 110, PlanID: 140}
+		if err := req.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		app.AppName, app.UserKey, app.ApplicationId = req.Form.Get("name"), req.Form.Get("user_key"), req.Form.Get("application_id")
+		if app.ApplicationId != "" {
+			app.ID = 182
+		}
+		return helperJSONResponse(t, http.StatusCreated, ApplicationElem{Application: app})
+	})
+
+	return porta, c
+}
+
+func TestBackupTenant(t *testing.T) {
+	porta := backupPorta(t)
+
+	backup, err := porta.client().BackupTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{}, porta.writes())
+
+	equals(t, 1, len(backup.Policies))
+	equals(t, "my-policy", *backup.Policies[0].Name)
+	equals(t, 1, len(backup.Backends))
+	equals(t, "pets_backend", backup.Backends[0].SystemName)
+
+	equals(t, 1, len(backup.Products))
+	product := backup.Products[0]
+	equals(t, "pets", product.Product.SystemName)
+	equals(t, "basic", product.Product.ApplicationPlans[0].SystemName)
+	equals(t, 2, len(product.ProxyConfigs))
+	equals(t, "sandbox", product.ProxyConfigs[0].Environment)
+	equals(t, "production", product.ProxyConfigs[1].Environment)
+	equals(t, 1, len(product.Product.ActiveDocs))
+
+	// the activedoc of the product is not repeated
+	equals(t, 1, len(backup.ActiveDocs))
+	equals(t, "shared_doc", backup.ActiveDocs[0].SystemName)
+	equals(t, `{"openapi":"3.0.0"}`, backup.ActiveDocs[0].Body)
+
+	equals(t, 1, len(backup.Accounts))
+	account := backup.Accounts[0]
+	equals(t, "Acme", *account.Account.OrgName)
+	equals(t, 2, len(account.Users))
+	equals(t, 2, len(account.Applications))
+	for _, app := range account.Applications {
+		equals(t, int64(10), app.Application.ServiceID)
+		equals(t, "pets", app.ProductSystemName)
+		equals(t, "basic", app.PlanSystemName)
+	}
+	equals(t, []string(nil), account.Applications[0].Keys)
+	equals(t, []string{"app-key"}, account.Applications[1].Keys)
+
+	// keys are only read for applications identified by application_id
+	equals(t, 0, len(porta.calls(http.MethodGet, "/admin/api/accounts/70/applications/81/keys.json")))
+}
+
+func TestTenantBackupArchive(t *testing.T) {
+	backup := testTenantBackup(t)
+
+	dir, err := ioutil.TempDir("", "tenant-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "backup.tar.gz")
+	if err := backup.Save(archive); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadTenantBackup(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// compare the JSON documents, empty lists are omitted in the archive
+	expected, _ := json.Marshal(backup)
+	actual, _ := json.Marshal(loaded)
+	equals(t, string(expected), string(actual))
+
+	if _, err := ReadTenantBackup(bytes.NewReader([]byte("not an archive"))); err == nil {
+		t.Fatal("expected error reading an invalid archive")
+	}
+
+	empty := &bytes.Buffer{}
+	if err := (&TenantBackup{}).WriteArchive(empty); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = ReadTenantBackup(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 0, len(loaded.Products))
+}
+
+func TestRestoreTenant(t *testing.T) {
+	backup := testTenantBackup(t)
+	target, c := restorePorta(t)
+
+	result, err := c.RestoreTenant(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &TenantRestoreResult{
+		PolicyIDs:      map[string]int64{policyArchiveName(backup.Policies[0]): 160},
+		BackendIDs:     map[string]int64{"pets_backend": 120},
+		ProductIDs:     map[string]int64{"pets": 110},
+		ActiveDocIDs:   map[string]int64{"shared_doc": 151},
+		AccountIDs:     map[int64]int64{70: 170},
+		ApplicationIDs: map[int64]int64{81: 181, 82: 182},
+		Warnings:       []string{},
+	}
+	equals(t, expected, result)
+
+	policy := APIcastPolicyItem{}
+	if err := json.Unmarshal(target.calls(http.MethodPost, "/admin/api/registry/policies.json")[0].Body, &policy); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "my-policy", *policy.Name)
+	equals(t, (*int64)(nil), policy.ID)
+
+	// the sandbox config is deployed and promoted once
+	equals(t, 1, len(target.calls(http.MethodPost, "/admin/api/services/110/proxy/deploy.json")))
+	promote := target.calls(http.MethodPost, "/admin/api/services/110/proxy/configs/sandbox/1/promote.json")
+	equals(t, 1, len(promote))
+	equals(t, "production", promote[0].Params.Get("to"))
+
+	shared := ActiveDocItem{}
+	if err := json.Unmarshal(target.calls(http.MethodPost, "/admin/api/active_docs.json")[1].Body, &shared); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "shared_doc", *shared.SystemName)
+	equals(t, "Shared", *shared.Name)
+	equals(t, (*int64)(nil), shared.ServiceID)
+
+	signup := target.calls(http.MethodPost, "/admin/api/signup.json")[0].Params
+	equals(t, "Acme", signup.Get("org_name"))
+	equals(t, "john", signup.Get("username"))
+	equals(t, "john@example.com", signup.Get("email"))
+
+	// the admin user comes with the signup, the member is created and activated
+	user := DeveloperUserItem{}
+	if err := json.Unmarshal(target.calls(http.MethodPost, "/admin/api/accounts/170/users.json")[0].Body, &user); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "jane", *user.Username)
+	equals(t, 1, len(target.calls(http.MethodPut, "/admin/api/accounts/170/users/172/activate.json")))
+	equals(t, 0, len(target.calls(http.MethodPut, "/admin/api/accounts/170/users/172/admin.json")))
+
+	apps := target.calls(http.MethodPost, "/admin/api/accounts/170/applications.json")
+	equals(t, 2, len(apps))
+	equals(t, "user key app", apps[0].Params.Get("name"))
+	equals(t, "secret", apps[0].Params.Get("user_key"))
+	equals(t, "140", apps[0].Params.Get("plan_id"))
+	equals(t, "app id app", apps[1].Params.Get("name"))
+	equals(t, "app-id", apps[1].Params.Get("application_id"))
+	equals(t, "app-key", target.calls(http.MethodPost, "/admin/api/accounts/170/applications/182/keys.json")[0].Params.Get("key"))
+	equals(t, 1, len(target.calls(http.MethodPut, "/admin/api/accounts/170/applications/182/suspend.json")))
+	equals(t, 0, len(target.calls(http.MethodPut, "/admin/api/accounts/170/applications/181/suspend.json")))
+
+	// the tenant is not empty
+	_, restored := copyPorta(t, true)
+	if _, err := restored.RestoreTenant(backup); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected already exists error; got %v", err)
+	}
+}
+
+func TestRestoreTenantSkipsCustomPlans(t *testing.T) {
+	backup := testTenantBackup(t)
+	backup.Accounts[0].Applications[0].PlanSystemName = ""
+
+	target, c := restorePorta(t)
+	result, err := c.RestoreTenant(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"application 81 (user key app) of account Acme skipped: plan not restored"}, result.Warnings)
+	equals(t, map[int64]int64{82: 182}, result.ApplicationIDs)
+	equals(t, 1, len(target.calls(http.MethodPost, "/admin/api/accounts/170/applications.json")))
+}
+
+func TestRestoreTenantSkipsAccountsWithoutAdmin(t *testing.T) {
+	backup := testTenantBackup(t)
+
+	orgName := "Orphan"
+	orphan := backup.Accounts[0]
+	orphan.Account.OrgName = &orgName
+	orphan.Users = []DeveloperUserItem{}
+	backup.Accounts = append([]AccountBackup{orphan}, backup.Accounts...)
+
+	target, c := restorePorta(t)
+	result, err := c.RestoreTenant(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, []string{"account Orphan skipped along with its 2 applications: no admin user"}, result.Warnings)
+	equals(t, 1, len(target.calls(http.MethodPost, "/admin/api/signup.json")))
+	equals(t, 2, len(result.ApplicationIDs))
+}
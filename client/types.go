@@ -218,6 +218,8 @@ type ProxyConfig struct {
 	Version     int     `json:"version"`
 	Environment string  `json:"environment"`
 	Content     Content `json:"content"`
+	// Raw holds the proxy config as read from Porta, fields missing from Content included
+	Raw json.RawMessage `json:"-"`
 }
 
 type ProxyConfigList struct {
@@ -326,6 +328,8 @@ type PolicyChain struct {
 	Name          string        `json:"name"`
 	Version       string        `json:"version"`
 	Configuration Configuration `json:"configuration"`
+	// ConfigurationValues holds the configuration of the policy, which Configuration does not keep
+	ConfigurationValues map[string]interface{} `json:"-"`
 }
 
 type Configuration struct{}
@@ -346,6 +350,8 @@ type ProxyRule struct {
 	QuerystringParameters Configuration `json:"querystring_parameters"`
 	Position              int           `json:"position,omitempty"`
 	Last                  bool          `json:"last,omitempty"`
+	// QuerystringParameterValues holds the querystring parameters, which QuerystringParameters does not keep
+	QuerystringParameterValues map[string]interface{} `json:"-"`
 }
 
 type Params map[string]string
@@ -791,3 +797,786 @@ type DeveloperUser struct {
 type DeveloperUserList struct {
 	Items []DeveloperUser `json:"users"`
 }
+
+// ProductBundle - Declarative description of a product and the resources it depends on.
+// Resources reference each other by system_name, numeric IDs are resolved on import.
+type ProductBundle struct {
+	Product  ProductSpec      `json:"product"`
+	Backends []BackendApiSpec `json:"backends,omitempty"`
+}
+
+// ProductSpec - Desired state of a product
+type ProductSpec struct {
+	SystemName       string `json:"system_name"`
+	Name             string `json:"name"`
+	Description      string `json:"description,omitempty"`
+	DeploymentOption string `json:"deployment_option,omitempty"`
+	BackendVersion   string `json:"backend_version,omitempty"`
+
+	// Proxy holds proxy settings as accepted by UpdateProductProxy
+	Proxy Params `json:"proxy,omitempty"`
+
+	Metrics          []MetricSpec          `json:"metrics,omitempty"`
+	Methods          []MethodSpec          `json:"methods,omitempty"`
+	MappingRules     []MappingRuleSpec     `json:"mapping_rules,omitempty"`
+	BackendUsages    []BackendUsageSpec    `json:"backend_usages,omitempty"`
+	ApplicationPlans []ApplicationPlanSpec `json:"application_plans,omitempty"`
+	ActiveDocs       []ActiveDocSpec       `json:"activedocs,omitempty"`
+
+	// Policies is the full policy chain. When nil, the remote policy chain is left untouched
+	Policies []PolicyConfig `json:"policies,omitempty"`
+
+	// OIDC holds the OpenID Connect flows. When nil, the remote configuration is left untouched
+	OIDC *OIDCConfigurationItem `json:"oidc,omitempty"`
+}
+
+// BackendApiSpec - Desired state of a backend api
+type BackendApiSpec struct {
+	SystemName      string            `json:"system_name"`
+	Name            string            `json:"name"`
+	Description     string            `json:"description,omitempty"`
+	PrivateEndpoint string            `json:"private_endpoint"`
+	Metrics         []MetricSpec      `json:"metrics,omitempty"`
+	Methods         []MethodSpec      `json:"methods,omitempty"`
+	MappingRules    []MappingRuleSpec `json:"mapping_rules,omitempty"`
+}
+
+// MetricSpec - Desired state of a metric
+type MetricSpec struct {
+	SystemName  string `json:"system_name"`
+	Name        string `json:"friendly_name"`
+	Unit        string `json:"unit"`
+	Description string `json:"description,omitempty"`
+}
+
+// MethodSpec - Desired state of a method. Methods always belong to the hits metric
+type MethodSpec struct {
+	SystemName  string `json:"system_name"`
+	Name        string `json:"friendly_name"`
+	Description string `json:"description,omitempty"`
+}
+
+// MappingRuleSpec - Desired state of a mapping rule.
+// MetricRef is the system_name of the metric or method increased by the rule
+type MappingRuleSpec struct {
+	HTTPMethod string `json:"http_method"`
+	Pattern    string `json:"pattern"`
+	MetricRef  string `json:"metric_ref"`
+	Delta      int    `json:"delta"`
+	Last       bool   `json:"last,omitempty"`
+	Position   int    `json:"position,omitempty"`
+}
+
+// BackendUsageSpec - Desired backend usage of a product
+type BackendUsageSpec struct {
+	BackendSystemName string `json:"backend_system_name"`
+	Path              string `json:"path"`
+}
+
+// MetricRefSpec - Reference to a metric or method by system_name.
+// When BackendSystemName is empty, the metric belongs to the product
+type MetricRefSpec struct {
+	MetricSystemName  string `json:"metric_system_name"`
+	BackendSystemName string `json:"backend_system_name,omitempty"`
+}
+
+// ApplicationPlanSpec - Desired state of an application plan
+type ApplicationPlanSpec struct {
+	SystemName         string            `json:"system_name"`
+	Name               string            `json:"name"`
+	Published          bool              `json:"published,omitempty"`
+	ApprovalRequired   bool              `json:"approval_required,omitempty"`
+	SetupFee           float64           `json:"setup_fee,omitempty"`
+	CostPerMonth       float64           `json:"cost_per_month,omitempty"`
+	TrialPeriodDays    int               `json:"trial_period_days,omitempty"`
+	CancellationPeriod int               `json:"cancellation_period,omitempty"`
+	Limits             []LimitSpec       `json:"limits,omitempty"`
+	PricingRules       []PricingRuleSpec `json:"pricing_rules,omitempty"`
+}
+
+// LimitSpec - Desired application plan limit
+type LimitSpec struct {
+	MetricRefSpec
+	Period string `json:"period"`
+	Value  int    `json:"value"`
+}
+
+// PricingRuleSpec - Desired application plan pricing rule
+type PricingRuleSpec struct {
+	MetricRefSpec
+	CostPerUnit string `json:"cost_per_unit"`
+	Min         int    `json:"min"`
+	Max         int    `json:"max"`
+}
+
+// ActiveDocSpec - Desired state of an activedoc bound to the product
+type ActiveDocSpec struct {
+	SystemName             string `json:"system_name"`
+	Name                   string `json:"name"`
+	Description            string `json:"description,omitempty"`
+	Published              bool   `json:"published,omitempty"`
+	SkipSwaggerValidations bool   `json:"skip_swagger_validations,omitempty"`
+	Body                   string `json:"body"`
+}
+
+// ProductImportResult - IDs of the resources handled by ImportProduct, keyed by system_name
+type ProductImportResult struct {
+	ProductID int64
+	// MetricIDs holds product metric and method IDs
+	MetricIDs  map[string]int64
+	BackendIDs map[string]int64
+	// BackendMetricIDs holds backend metric and method IDs per backend system_name
+	BackendMetricIDs   map[string]map[string]int64
+	ApplicationPlanIDs map[string]int64
+	ActiveDocIDs       map[string]int64
+}
+
+// CopyProductOptions - Tunes how CopyProduct names the copied resources
+type CopyProductOptions struct {
+	// SystemName of the copy. Defaults to the source product system name
+	SystemName string
+	// Name of the copy. Defaults to the source product name
+	Name string
+	// ReuseBackends uses backends already present in the destination tenant as they are,
+	// matched by system name after renaming. Missing backends are copied
+	ReuseBackends bool
+	// BackendSystemNames renames copied backends, keyed by source system name
+	BackendSystemNames map[string]string
+	// ActiveDocSystemNames renames copied activedocs, keyed by source system name
+	ActiveDocSystemNames map[string]string
+}
+
+// ProductCopyResult - Maps the IDs of the source resources to the IDs of their copies
+type ProductCopyResult struct {
+	ProductID          int64
+	BackendIDs         map[int64]int64
+	MetricIDs          map[int64]int64
+	ApplicationPlanIDs map[int64]int64
+	ActiveDocIDs       map[int64]int64
+}
+
+// BackendApiCopyResult - Maps the IDs of the source backend api resources to the IDs of their copies
+type BackendApiCopyResult struct {
+	BackendApiID int64
+	// MetricIDs holds both metrics and methods
+	MetricIDs      map[int64]int64
+	MappingRuleIDs map[int64]int64
+}
+
+// MetricTree - Holds the metrics and methods of a product or a backend api as a hierarchy.
+// Methods are the children of the hits metric
+type MetricTree struct {
+	owner   metricOwner
+	hits    *MetricNode
+	metrics []*MetricNode
+	byID    map[int64]*MetricNode
+	// nodes indexed by the system name known by the user, without the backend api suffix
+	bySystemName map[string]*MetricNode
+}
+
+// MetricNode - Holds a metric or a method of a MetricTree
+type MetricNode struct {
+	Item MetricItem
+	// SystemName is the system name without the ".<backendapiID>" suffix of backend metrics
+	SystemName string
+	Parent     *MetricNode
+	Children   []*MetricNode
+}
+
+// MappingRuleSimulator - Evaluates requests against mapping rules the way APIcast does
+type MappingRuleSimulator struct {
+	rules []simulatedMappingRule
+}
+
+// MatchedMappingRule - Holds a mapping rule matched by a MappingRuleSimulator
+type MatchedMappingRule struct {
+	MappingRuleItem
+	// MetricSystemName is only known for rules read from a proxy config
+	MetricSystemName string
+	// PathPrefix is the backend usage path the pattern was prefixed with
+	PathPrefix string
+}
+
+// MappingRuleMatch - Holds the mapping rules matching a request and the resulting usage
+type MappingRuleMatch struct {
+	Rules []MatchedMappingRule
+	// Deltas holds the sum of the deltas of the matched rules by metric ID
+	Deltas map[int64]int
+}
+
+// MappingRuleSet - Holds the mapping rules of a product, or of a backend api used by a product at PathPrefix
+type MappingRuleSet struct {
+	// Name identifies the owner of the rules in lint reports
+	Name       string
+	PathPrefix string
+	Rules      []MappingRuleItem
+	// MetricIDs lists the metrics and methods the rules may reference. Not checked when nil
+	MetricIDs []int64
+}
+
+// MappingRuleIssue - Holds a problem found by LintMappingRules
+type MappingRuleIssue struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	// Set is the name of the MappingRuleSet the rule belongs to
+	Set           string `json:"set"`
+	MappingRuleID int64  `json:"mapping_rule_id,omitempty"`
+	HTTPMethod    string `json:"http_method,omitempty"`
+	Pattern       string `json:"pattern,omitempty"`
+	Message       string `json:"message"`
+}
+
+// MappingRuleLintReport - Holds the issues found by LintMappingRules
+type MappingRuleLintReport struct {
+	Issues []MappingRuleIssue `json:"issues"`
+}
+
+// MappingRulesReplaceResult - Holds the mapping rules created, updated and deleted to replace or reorder
+// the mapping rules of a product or backend api
+type MappingRulesReplaceResult struct {
+	Created []MappingRuleItem
+	Updated []MappingRuleItem
+	Deleted []MappingRuleItem
+	// RolledBack is set when an operation failed and the previous ones were reverted
+	RolledBack bool
+}
+
+// OpenAPIOperation - Holds an operation of an OpenAPI document with the method and the mapping rule
+// generated for it
+type OpenAPIOperation struct {
+	HTTPMethod  string
+	Path        string
+	OperationID string
+	// Method system name and friendly name
+	SystemName   string
+	FriendlyName string
+	Description  string
+	// Mapping rule pattern: the path prefixed with the public base path
+	Pattern string
+}
+
+// OpenAPIImportOptions - Holds the options to generate methods and mapping rules from an OpenAPI document
+type OpenAPIImportOptions struct {
+	// PublicBasePath replaces the base path of the document (basePath or the path of the first server)
+	PublicBasePath string
+	// PrefixMatching generates patterns without the $ anchor, matching any path starting with them
+	PrefixMatching bool
+}
+
+// OpenAPIImportResult - Holds the methods and mapping rules generated from an OpenAPI document
+type OpenAPIImportResult struct {
+	Operations []OpenAPIOperation
+	// Method IDs by system name
+	MethodIDs map[string]int64
+	// Mapping rule IDs by "<HTTP method> <pattern>"
+	MappingRuleIDs map[string]int64
+	// Number of methods and mapping rules created or updated
+	Changed int
+}
+
+// OpenAPIProductOptions - Holds the options to bootstrap a product from an OpenAPI document
+type OpenAPIProductOptions struct {
+	OpenAPIImportOptions
+	// SystemName of the product and of the activedoc, derived from info.title when empty.
+	// The backend system name gets the _backend suffix
+	SystemName string
+	// PrivateEndpoint replaces the API location of the document (host or the first server)
+	PrivateEndpoint string
+	// OIDCIssuerEndpoint is set on the proxy when the document is secured with OAuth2 or OpenID Connect
+	OIDCIssuerEndpoint string
+	// ApplicationPlan is the system name of the default application plan, "default" when empty
+	ApplicationPlan string
+	// PublishActiveDoc makes the activedoc visible in the developer portal
+	PublishActiveDoc bool
+}
+
+// ValidationError - Holds a problem found in a document, located by a JSON pointer (RFC 6901)
+type ValidationError struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// PolicyCatalog - Holds the built-in and custom policies a tenant can add to policy chains
+type PolicyCatalog struct {
+	Policies []PolicyCatalogItem `json:"policies"`
+}
+
+// PolicyCatalogItem - Holds a policy version of the catalog with its manifest.
+// RegistryID is only set for custom policies of the registry
+type PolicyCatalogItem struct {
+	Name       string               `json:"name"`
+	Version    string               `json:"version"`
+	Builtin    bool                 `json:"builtin"`
+	RegistryID *int64               `json:"registry_id,omitempty"`
+	Schema     *APIcastPolicySchema `json:"schema,omitempty"`
+}
+
+// PolicyValidator - Holds the configuration JSON schemas of policies by name and version,
+// used to validate policy chains before they are persisted
+type PolicyValidator struct {
+	schemas map[string]json.RawMessage
+}
+
+// ActiveDocNormalizeOptions - Holds the rewrites applied to an activedoc body by NormalizeActiveDocBody
+type ActiveDocNormalizeOptions struct {
+	// UserKey is the name of the user key parameter added to every operation. None is added when empty
+	UserKey string
+	// UserKeyLocation is the proxy credentials location of the user key: "query" (default) or "headers"
+	UserKeyLocation string
+	// ServerURL replaces the servers of OpenAPI 3 documents or the host and schemes of OpenAPI 2 documents
+	ServerURL string
+}
+
+// ActiveDocSyncOptions - Holds the options to sync activedocs with the spec files of a directory
+type ActiveDocSyncOptions struct {
+	// ServiceID binds the activedocs to the product. Only activedocs of the product are deleted when set
+	ServiceID int64
+	// ServiceIDs binds activedocs, by system name, to other products than ServiceID
+	ServiceIDs map[string]int64
+	// Published makes the activedocs visible in the developer portal
+	Published bool
+	// Delete removes the activedocs without spec file. It needs ServiceID or WholeTenant
+	Delete bool
+	// WholeTenant lets Delete without ServiceID remove the activedocs of every product and unbound ones
+	WholeTenant bool
+	// Normalize holds the rewrites applied to the spec files
+	Normalize ActiveDocNormalizeOptions
+}
+
+// ActiveDocSyncResult - Holds the system names of the activedocs handled by SyncActiveDocs
+type ActiveDocSyncResult struct {
+	Created   []string
+	Updated   []string
+	Deleted   []string
+	Unchanged []string
+	// IDs of the activedocs by system name, deleted ones excluded
+	IDs map[string]int64
+}
+
+// PolicyPublishOptions - Holds the options to publish custom policies to the registry
+type PolicyPublishOptions struct {
+	// Delete removes the registry policies, by name and version, without local manifest
+	Delete bool
+}
+
+// PolicyPublishResult - Holds the policies handled by PublishPolicies, as name@version
+type PolicyPublishResult struct {
+	Created   []string
+	Updated   []string
+	Deleted   []string
+	Unchanged []string
+	// IDs of the registry policies by name@version, deleted ones excluded
+	IDs map[string]int64
+}
+
+// PolicyConfiguration - Implemented by the typed configurations of the built-in APIcast policies,
+// converted to PolicyConfig by NewPolicyConfig and back by PolicyConfig.Decode
+type PolicyConfiguration interface {
+	// PolicyName returns the name of the policy in the policy chain
+	PolicyName() string
+}
+
+// CORSPolicyConfig - Holds the configuration of the CORS request handling policy
+type CORSPolicyConfig struct {
+	AllowHeaders     []string `json:"allow_headers,omitempty"`
+	AllowMethods     []string `json:"allow_methods,omitempty"`
+	AllowOrigin      string   `json:"allow_origin,omitempty"`
+	AllowCredentials bool     `json:"allow_credentials,omitempty"`
+	MaxAge           int      `json:"max_age,omitempty"`
+}
+
+// HeadersPolicyConfig - Holds the configuration of the header modification policy
+type HeadersPolicyConfig struct {
+	Request  []HeaderPolicyCommand `json:"request,omitempty"`
+	Response []HeaderPolicyCommand `json:"response,omitempty"`
+}
+
+// HeaderPolicyCommand - Holds a header operation: "set", "add", "push" or "delete"
+type HeaderPolicyCommand struct {
+	Op     string `json:"op"`
+	Header string `json:"header"`
+	Value  string `json:"value,omitempty"`
+	// ValueType is "plain" (default) or "liquid"
+	ValueType string `json:"value_type,omitempty"`
+}
+
+// URLRewritingPolicyConfig - Holds the configuration of the URL rewriting policy
+type URLRewritingPolicyConfig struct {
+	Commands          []URLRewritingCommand `json:"commands,omitempty"`
+	QueryArgsCommands []QueryArgCommand     `json:"query_args_commands,omitempty"`
+}
+
+// URLRewritingCommand - Holds a path rewrite: "sub" replaces the first match of the regex, "gsub" all of them
+type URLRewritingCommand struct {
+	Op      string   `json:"op"`
+	Regex   string   `json:"regex"`
+	Replace string   `json:"replace"`
+	Options string   `json:"options,omitempty"`
+	Break   bool     `json:"break,omitempty"`
+	Methods []string `json:"methods,omitempty"`
+}
+
+// QueryArgCommand - Holds a query argument operation: "add", "set", "push" or "delete"
+type QueryArgCommand struct {
+	Op        string `json:"op"`
+	Arg       string `json:"arg"`
+	Value     string `json:"value,omitempty"`
+	ValueType string `json:"value_type,omitempty"`
+}
+
+// IPCheckPolicyConfig - Holds the configuration of the IP check policy
+type IPCheckPolicyConfig struct {
+	// CheckType is "whitelist" or "blacklist"
+	CheckType string   `json:"check_type"`
+	IPs       []string `json:"ips"`
+	ErrorMsg  string   `json:"error_msg,omitempty"`
+	// ClientIPSources are tried in order: "X-Forwarded-For", "last_caller" or "proxy_protocol_addr"
+	ClientIPSources []string `json:"client_ip_sources,omitempty"`
+}
+
+// RateLimitPolicyConfig - Holds the configuration of the edge limiting policy
+type RateLimitPolicyConfig struct {
+	ConnectionLimiters  []ConnectionLimiter     `json:"connection_limiters,omitempty"`
+	LeakyBucketLimiters []LeakyBucketLimiter    `json:"leaky_bucket_limiters,omitempty"`
+	FixedWindowLimiters []FixedWindowLimiter    `json:"fixed_window_limiters,omitempty"`
+	RedisURL            string                  `json:"redis_url,omitempty"`
+	LimitsExceededError *RateLimitErrorHandling `json:"limits_exceeded_error,omitempty"`
+	ConfigurationError  *RateLimitErrorHandling `json:"configuration_error,omitempty"`
+}
+
+// RateLimiterKey - Holds the key limits are counted by
+type RateLimiterKey struct {
+	Name string `json:"name"`
+	// NameType is "plain" (default) or "liquid"
+	NameType string `json:"name_type,omitempty"`
+	// Scope is "service" (default) or "global"
+	Scope string `json:"scope,omitempty"`
+}
+
+// ConnectionLimiter - Holds a limit of concurrent connections
+type ConnectionLimiter struct {
+	Key   RateLimiterKey `json:"key"`
+	Conn  int            `json:"conn"`
+	Burst int            `json:"burst"`
+	Delay float64        `json:"delay"`
+}
+
+// LeakyBucketLimiter - Holds a limit of requests per second
+type LeakyBucketLimiter struct {
+	Key   RateLimiterKey `json:"key"`
+	Rate  int            `json:"rate"`
+	Burst int            `json:"burst"`
+}
+
+// FixedWindowLimiter - Holds a limit of requests per time window, in seconds
+type FixedWindowLimiter struct {
+	Key    RateLimiterKey `json:"key"`
+	Count  int            `json:"count"`
+	Window int            `json:"window,omitempty"`
+}
+
+// RateLimitErrorHandling - Holds the response of the edge limiting policy on errors
+type RateLimitErrorHandling struct {
+	StatusCode int `json:"status_code,omitempty"`
+	// ErrorHandling is "exit" (default) or "log"
+	ErrorHandling string `json:"error_handling,omitempty"`
+}
+
+// CachingPolicyConfig - Holds the configuration of the 3scale auth caching policy
+type CachingPolicyConfig struct {
+	// CachingType is "resilient", "strict", "allow" or "none"
+	CachingType string `json:"caching_type"`
+}
+
+// RoutingPolicyConfig - Holds the configuration of the routing policy
+type RoutingPolicyConfig struct {
+	Rules []RoutingRule `json:"rules"`
+}
+
+// RoutingRule - Holds an upstream and the condition requests must meet to be routed to it
+type RoutingRule struct {
+	URL         string           `json:"url"`
+	HostHeader  string           `json:"host_header,omitempty"`
+	ReplacePath string           `json:"replace_path,omitempty"`
+	OwnerID     int64            `json:"owner_id,omitempty"`
+	OwnerType   string           `json:"owner_type,omitempty"`
+	Condition   RoutingCondition `json:"condition"`
+}
+
+// RoutingCondition - Holds the operations of a routing rule, combined with "and" (default) or "or"
+type RoutingCondition struct {
+	CombineOp  string             `json:"combine_op,omitempty"`
+	Operations []RoutingOperation `json:"operations"`
+}
+
+// RoutingOperation - Holds a comparison ("==", "!=" or "matches") of a request attribute:
+// "path", "header", "query_arg", "jwt_claim" or "liquid"
+type RoutingOperation struct {
+	Match        string `json:"match"`
+	Op           string `json:"op"`
+	Value        string `json:"value"`
+	ValueType    string `json:"value_type,omitempty"`
+	HeaderName   string `json:"header_name,omitempty"`
+	QueryArgName string `json:"query_arg_name,omitempty"`
+	JWTClaimName string `json:"jwt_claim_name,omitempty"`
+	LiquidValue  string `json:"liquid_value,omitempty"`
+}
+
+// JWTClaimCheckPolicyConfig - Holds the configuration of the JWT claim check policy
+type JWTClaimCheckPolicyConfig struct {
+	Rules        []JWTClaimCheckRule `json:"rules"`
+	ErrorMessage string              `json:"error_message,omitempty"`
+}
+
+// JWTClaimCheckRule - Holds the claims requests to the resource must have
+type JWTClaimCheckRule struct {
+	Resource string `json:"resource"`
+	// ResourceType is "plain" (default) or "liquid"
+	ResourceType string              `json:"resource_type,omitempty"`
+	Methods      []string            `json:"methods,omitempty"`
+	CombineOp    string              `json:"combine_op,omitempty"`
+	Operations   []JWTClaimOperation `json:"operations"`
+}
+
+// JWTClaimOperation - Holds a comparison ("==", "!=" or "matches") of a JWT claim
+type JWTClaimOperation struct {
+	Op           string `json:"op"`
+	JWTClaim     string `json:"jwt_claim"`
+	JWTClaimType string `json:"jwt_claim_type,omitempty"`
+	Value        string `json:"value"`
+	ValueType    string `json:"value_type,omitempty"`
+}
+
+// UpstreamPolicyConfig - Holds the configuration of the upstream policy
+type UpstreamPolicyConfig struct {
+	Rules []UpstreamRule `json:"rules"`
+}
+
+// UpstreamRule - Holds the upstream of the requests whose path matches the regex
+type UpstreamRule struct {
+	Regex string `json:"regex"`
+	URL   string `json:"url"`
+}
+
+// LoggingPolicyConfig - Holds the configuration of the logging policy
+type LoggingPolicyConfig struct {
+	// EnableAccessLogs defaults to true, set it to false to disable access logs
+	EnableAccessLogs *bool              `json:"enable_access_logs,omitempty"`
+	CustomLogging    string             `json:"custom_logging,omitempty"`
+	EnableJSONLogs   bool               `json:"enable_json_logs,omitempty"`
+	JSONObjectConfig []LoggingJSONField `json:"json_object_config,omitempty"`
+	Condition        *LoggingCondition  `json:"condition,omitempty"`
+}
+
+// LoggingJSONField - Holds a field of the JSON access logs
+type LoggingJSONField struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	ValueType string `json:"value_type,omitempty"`
+}
+
+// LoggingCondition - Holds the operations requests must meet to be logged, combined with "and" (default) or "or"
+type LoggingCondition struct {
+	CombineOp  string                      `json:"combine_op,omitempty"`
+	Operations []LoggingConditionOperation `json:"operations"`
+}
+
+// LoggingConditionOperation - Holds a comparison ("==", "!=" or "matches") of two values
+type LoggingConditionOperation struct {
+	Op        string `json:"op"`
+	Match     string `json:"match"`
+	MatchType string `json:"match_type,omitempty"`
+	Value     string `json:"value"`
+	ValueType string `json:"value_type,omitempty"`
+}
+
+// TenantBackup - Holds the content of a tenant that BackupTenant reads and RestoreTenant replays
+type TenantBackup struct {
+	Policies []APIcastPolicyItem `json:"policies"`
+	Backends []BackendApiSpec    `json:"backends"`
+	Products []ProductBackup     `json:"products"`
+	// ActiveDocs holds the activedocs not bound to a product. The others are part of their product
+	ActiveDocs []ActiveDocSpec `json:"activedocs"`
+	Accounts   []AccountBackup `json:"accounts"`
+}
+
+// ProductBackup - Holds a product and its proxy config history
+type ProductBackup struct {
+	Product      ProductSpec   `json:"product"`
+	ProxyConfigs []ProxyConfig `json:"proxy_configs,omitempty"`
+}
+
+// AccountBackup - Holds a developer account with its users and applications
+type AccountBackup struct {
+	Account      DeveloperAccountItem `json:"account"`
+	Users        []DeveloperUserItem  `json:"users"`
+	Applications []ApplicationBackup  `json:"applications"`
+}
+
+// ApplicationBackup - Holds an application along with the system names of its product and plan
+type ApplicationBackup struct {
+	Application       Application `json:"application"`
+	ProductSystemName string      `json:"product_system_name"`
+	// PlanSystemName is empty for applications on custom plans
+	PlanSystemName string   `json:"plan_system_name,omitempty"`
+	Keys           []string `json:"keys,omitempty"`
+}
+
+// TenantRestoreResult - Holds the IDs of the resources created by RestoreTenant
+type TenantRestoreResult struct {
+	PolicyIDs  map[string]int64
+	BackendIDs map[string]int64
+	ProductIDs map[string]int64
+	// ActiveDocIDs holds the activedocs not bound to a product
+	ActiveDocIDs map[string]int64
+	// AccountIDs and ApplicationIDs map the IDs in the backup to the IDs of the restored resources
+	AccountIDs     map[int64]int64
+	ApplicationIDs map[int64]int64
+	// Warnings lists the resources that could not be restored as they were
+	Warnings []string
+}
+
+// ChangeAction - Operation performed by a Change
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeUpdate ChangeAction = "update"
+	ChangeDelete ChangeAction = "delete"
+)
+
+// FieldDiff - Difference of a single field between live and desired state
+type FieldDiff struct {
+	Field   string `json:"field"`
+	Current string `json:"current"`
+	Desired string `json:"desired"`
+}
+
+// Change - Create, update or delete operation on a single resource
+type Change struct {
+	Action ChangeAction `json:"action"`
+	// Kind is the resource type, i.e. "product", "metric", "mapping_rule"
+	Kind string `json:"kind"`
+	// Name identifies the resource by system names, i.e. "pets/basic"
+	Name   string      `json:"name"`
+	Fields []FieldDiff `json:"fields,omitempty"`
+
+	apply func() error
+}
+
+// ChangePlan - Ordered list of changes that bring the live state to the desired state
+type ChangePlan struct {
+	Changes []Change `json:"changes"`
+
+	state *reconcileState
+}
+
+// ProxyConfigDiff - Holds the changes from one proxy config version to another, e.g. what a promotion changes
+type ProxyConfigDiff struct {
+	FromEnvironment string `json:"from_environment"`
+	FromVersion     int    `json:"from_version"`
+	ToEnvironment   string `json:"to_environment"`
+	ToVersion       int    `json:"to_version"`
+	// Changes of kind "proxy", "host", "backend", "policy", "policy_chain" and "proxy_rule"
+	Changes []Change `json:"changes"`
+}
+
+// PromoteOptions - Holds the options of the deploy and promote flow of Promote
+type PromoteOptions struct {
+	// Timeout bounds the wait for the deployed sandbox version, one minute when zero
+	Timeout time.Duration
+	// PollInterval is the delay between reads of the latest sandbox version, one second when zero
+	PollInterval time.Duration
+	// Review is given the changes the promotion brings to production. An error cancels the promotion
+	Review func(diff *ProxyConfigDiff) error
+	// Rollback rolls production back to its previous version when the promotion cannot be verified, see RollbackProduction
+	Rollback bool
+}
+
+// PromoteResult - Holds the proxy config versions handled by Promote
+type PromoteResult struct {
+	SandboxVersion int
+	// PreviousProductionVersion is zero when nothing was promoted before
+	PreviousProductionVersion int
+	ProductionVersion         int
+	// Diff holds the changes from the previous production version to the deployed sandbox version
+	Diff       *ProxyConfigDiff
+	Promoted   bool
+	RolledBack bool
+}
+
+// ProxyConfigWatchOptions - Holds what a ProxyConfigWatcher polls and how often
+type ProxyConfigWatchOptions struct {
+	// Environment is either "sandbox" or "production"
+	Environment string
+	// ProductIDs are polled one by one. The latest proxy configs of the whole account are polled when empty
+	ProductIDs []int64
+	// Interval between polls of Watch, 30 seconds when zero
+	Interval time.Duration
+	// EmitInitial emits the versions found by the first poll too, without previous version
+	EmitInitial bool
+	// OnError is invoked with the errors of the polls of Watch, which keeps polling
+	OnError func(err error)
+}
+
+// ProxyConfigEvent - Holds a new proxy config version found by a ProxyConfigWatcher
+type ProxyConfigEvent struct {
+	ProductID   int64
+	Environment string
+	// Previous is the version seen by the previous poll, nil for the versions of the first poll
+	Previous *ProxyConfig
+	Current  ProxyConfig
+	// Removed is true when the product has no proxy config in the environment anymore, e.g. it was deleted.
+	// Current is then empty
+	Removed bool
+	// Diff holds the changes from the previous version, or from an empty proxy config
+	Diff *ProxyConfigDiff
+}
+
+// ProxyConfigWatcher - Holds the latest proxy config versions seen by product, see NewProxyConfigWatcher
+type ProxyConfigWatcher struct {
+	client *ThreeScaleClient
+	opts   ProxyConfigWatchOptions
+	seen   map[int64]ProxyConfig
+	polled bool
+}
+
+// APIcastConfigOptions - Holds the environment and the filters of the proxy configs exported by APIcastConfig
+type APIcastConfigOptions struct {
+	// Environment is either "sandbox" or "production", the default
+	Environment string
+	// ProductIDs and ProductSystemNames keep the proxy configs of any of the products, all when both are empty
+	ProductIDs         []int64
+	ProductSystemNames []string
+	// Hosts keeps the proxy configs serving any of the hosts, all when empty
+	Hosts []string
+}
+
+// APIcastConfig - Holds an APIcast file based configuration, as loaded from THREESCALE_CONFIG_FILE
+type APIcastConfig struct {
+	// Services holds the content of the proxy configs as read from Porta, so no field is lost
+	Services []json.RawMessage `json:"services"`
+	// OIDC holds the OpenID Connect discovery of each service, in the same order. It is empty for the services
+	// without OpenID Connect issuer, and nil when no service has one
+	OIDC []APIcastOIDC `json:"oidc,omitempty"`
+}
+
+// APIcastOIDC - Holds the OpenID Connect discovery of the issuer of a service, as APIcast loads it
+type APIcastOIDC struct {
+	Issuer string                 `json:"issuer,omitempty"`
+	Config map[string]interface{} `json:"config,omitempty"`
+	// Keys holds the JSON web keys of the issuer by kid, with their PEM encoding when they are RSA keys
+	Keys map[string]map[string]interface{} `json:"keys,omitempty"`
+}
+
+// ReconcileOptions - Tunes the reconciliation of a ProductBundle
+type ReconcileOptions struct {
+	// DryRun computes the change plan without applying it
+	DryRun bool
+	// Prune deletes remote resources of the product and declared backends missing from the desired state.
+	// Backend apis are never deleted as they may be used by other products
+	Prune bool
+	// RebindActiveDocs moves desired activedocs bound to other products to the product.
+	// Otherwise planning fails when a desired activedoc belongs to another product
+	RebindActiveDocs bool
+}
@@ -1,3 +1,5 @@
 module github.com/3scale/3scale-porta-go-client
 
 go 1.13
+
+require gopkg.in/yaml.v2 v2.4.0